@@ -0,0 +1,215 @@
+// Package main - atomic.go
+//
+// This file implements the 'atomic' option of the batch tools create_multiple_products and
+// update_products (and so adjust_prices): the batch is applied in full or not at all.
+//
+// Key Responsibilities:
+//   - Snapshot the products a batch updates before writing
+//   - Apply the batch one product at a time, so the failing item is known
+//   - On the first failure, compensate every applied write in reverse order and report
+//     what was rolled back
+//
+// Compensation:
+//   - created product -> DELETE /products/{id}
+//   - updated product -> PUT the snapshot back
+//
+// delete_products refuses 'atomic': a deleted product can only be recreated with
+// POST /products, which assigns a new id, so a "rolled back" delete would not restore it.
+//
+// Without 'atomic' the batch endpoints (/products/create-multiple, /products/update) are
+// called once, as before; if they fail part-way the catalog is left
+// half-applied. Atomic batches make one backend call per product instead and hold the
+// product locks (see concurrency.go) for the whole batch, including the rollback. The
+// rollback is not itself atomic: a compensating write that fails is reported and the
+// error says the rollback is incomplete.
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// atomicParam returns the optional 'atomic' argument
+func atomicParam(params map[string]interface{}) (bool, error) {
+	v, present := params["atomic"]
+	if !present || v == nil {
+		return false, nil
+	}
+	atomic, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("invalid 'atomic' argument: must be a boolean")
+	}
+	return atomic, nil
+}
+
+// refuseAtomicDelete refuses 'atomic' on delete_products, whose deletes cannot be rolled back
+func refuseAtomicDelete(params map[string]interface{}) error {
+	atomic, err := atomicParam(params)
+	if err != nil {
+		return err
+	}
+	if atomic {
+		return fmt.Errorf("invalid 'atomic' argument: delete_products cannot be atomic, since a deleted product cannot be restored with its id")
+	}
+	return nil
+}
+
+// rollbackStep is a compensating write of a failed atomic batch
+type rollbackStep struct {
+	Action    string `json:"action"` // delete or restore
+	ProductID string `json:"product_id"`
+	Error     string `json:"error,omitempty"`
+}
+
+// rollbackError is returned when an atomic batch failed and its applied writes were
+// compensated
+type rollbackError struct {
+	Message          string         `json:"message"`
+	FailedItem       int            `json:"failed_item"`
+	Cause            string         `json:"cause"`
+	Applied          int            `json:"applied"`
+	RolledBack       []rollbackStep `json:"rolled_back"`
+	RollbackComplete bool           `json:"rollback_complete"`
+	err              error
+}
+
+func (e *rollbackError) Error() string {
+	if !e.RollbackComplete {
+		var failed []string
+		for _, step := range e.RolledBack {
+			if step.Error != "" {
+				failed = append(failed, fmt.Sprintf("%s %s: %s", step.Action, step.ProductID, step.Error))
+			}
+		}
+		return fmt.Sprintf("%s; rollback incomplete: %s", e.Message, strings.Join(failed, "; "))
+	}
+	return e.Message
+}
+
+func (e *rollbackError) Unwrap() error { return e.err }
+
+// appliedWrite is a write of an atomic batch that succeeded
+type appliedWrite struct {
+	action string // create or update
+	id     string
+	before map[string]interface{} // nil for creates
+}
+
+// atomicBatch tracks the applied writes of an atomic batch
+type atomicBatch struct {
+	tool    string
+	applied []appliedWrite
+}
+
+// fail compensates the applied writes in reverse order and returns the error reporting them
+func (b *atomicBatch) fail(ctx context.Context, item int, cause error) error {
+	// the rollback runs even if the caller went away
+	ctx = context.WithoutCancel(ctx)
+	steps := make([]rollbackStep, 0, len(b.applied))
+	complete := true
+	for i := len(b.applied) - 1; i >= 0; i-- {
+		w := b.applied[i]
+		url := productServiceBaseURL + "/products/" + w.id
+		var step rollbackStep
+		var err error
+		switch w.action {
+		case "create":
+			step = rollbackStep{Action: "delete", ProductID: w.id}
+			if w.id == "" {
+				err = fmt.Errorf("the product service did not return the id of the created product")
+			} else {
+				_, err = sendWrite(ctx, http.MethodDelete, "/products/{id}", url, nil)
+			}
+		case "update":
+			step = rollbackStep{Action: "restore", ProductID: w.id}
+			_, err = sendWrite(ctx, http.MethodPut, "/products/{id}", url, w.before)
+		}
+		if err != nil {
+			step.Error = err.Error()
+			complete = false
+		}
+		steps = append(steps, step)
+	}
+	return &rollbackError{
+		Message: fmt.Sprintf("%s failed at item %d (%v); %d applied change(s) were rolled back",
+			b.tool, item, cause, len(b.applied)),
+		FailedItem:       item,
+		Cause:            cause.Error(),
+		Applied:          len(b.applied),
+		RolledBack:       steps,
+		RollbackComplete: complete,
+		err:              cause,
+	}
+}
+
+// atomicSnapshot returns the current state of the products a batch updates and
+// refuses the batch if any of them does not exist
+func atomicSnapshot(ctx context.Context, tool string, ids []string) (map[string]map[string]interface{}, error) {
+	current, err := snapshotProducts(withFreshReads(ctx), ids)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, id := range ids {
+		if current[id] == nil {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%s: product(s) %s do not exist; nothing was written", tool, strings.Join(missing, ", "))
+	}
+	return current, nil
+}
+
+// createProductsAtomically creates the products one at a time and deletes the created ones
+// if any create fails
+func createProductsAtomically(ctx context.Context, items []map[string]interface{}) (interface{}, error) {
+	batch := &atomicBatch{tool: "create_multiple_products"}
+	created := make([]interface{}, 0, len(items))
+	for i, item := range items {
+		product, err := sendWrite(ctx, http.MethodPost, "/products", productServiceBaseURL+"/products", item)
+		if err != nil {
+			return nil, batch.fail(ctx, i, err)
+		}
+		id := ""
+		if product["id"] != nil {
+			id = fmt.Sprint(product["id"])
+		}
+		batch.applied = append(batch.applied, appliedWrite{action: "create", id: id})
+		created = append(created, product)
+	}
+	return created, nil
+}
+
+// updateProductsAtomically applies the updates one at a time and restores the updated
+// products if any update fails; the caller holds the product locks. before tracks the state
+// each update starts from, so the reverse-order rollback ends at the snapshot.
+func updateProductsAtomically(ctx context.Context, ids []string, updates []map[string]interface{}) (interface{}, error) {
+	before, err := atomicSnapshot(ctx, "update_products", ids)
+	if err != nil {
+		return nil, err
+	}
+	batch := &atomicBatch{tool: "update_products"}
+	updated := make([]map[string]interface{}, 0, len(updates))
+	for i, update := range updates {
+		// each PUT sends the whole product, so a product updated twice keeps both updates
+		id := ids[i]
+		body := copyParams(before[id])
+		for k, v := range update {
+			body[k] = v
+		}
+		product, err := sendWrite(ctx, http.MethodPut, "/products/{id}", productServiceBaseURL+"/products/"+id, body)
+		if err != nil {
+			return nil, batch.fail(ctx, i, err)
+		}
+		batch.applied = append(batch.applied, appliedWrite{action: "update", id: id, before: before[id]})
+		if product["id"] == nil {
+			product = body
+		}
+		before[id] = product
+		updated = append(updated, product)
+	}
+	return map[string]interface{}{"atomic": true, "updated": updated}, nil
+}
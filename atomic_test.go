@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAtomicBatchesRollBackOnFailure(t *testing.T) {
+	fake := newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Laptops", 999),
+		testProduct("2", "Laptop7", "Electronics", "Laptops", 1299),
+		testProduct("3", "Mouse", "Electronics", "Accessories", 25),
+	)
+	ctx := context.Background()
+
+	// the third create fails: the first two are deleted again
+	fake.failWrite(3)
+	_, err := executeToolCall(ctx, "create_multiple_products", map[string]interface{}{"atomic": true, "products": []interface{}{
+		map[string]interface{}{"name": "Desk", "category": "Furniture", "price": 199.0},
+		map[string]interface{}{"name": "Chair", "category": "Furniture", "price": 99.0},
+		map[string]interface{}{"name": "Lamp", "category": "Furniture", "price": 29.0},
+	}})
+	var rolledBack *rollbackError
+	if !errors.As(err, &rolledBack) || rolledBack.FailedItem != 2 || rolledBack.Applied != 2 || !rolledBack.RollbackComplete {
+		t.Fatalf("Expected the batch to be rolled back after item 2, got %v", err)
+	}
+	if toolErrorKind(err) != "rolled_back" {
+		t.Errorf("Expected error kind 'rolled_back', got %s", toolErrorKind(err))
+	}
+	if furniture := fake.matching("category", "Furniture"); len(furniture) != 0 {
+		t.Errorf("Expected the created products to be deleted, got %v", furniture)
+	}
+
+	// the second update fails: the first product gets its price back
+	fake.failWrite(2)
+	_, err = executeToolCall(ctx, "update_products", map[string]interface{}{"atomic": true, "products": []interface{}{
+		map[string]interface{}{"id": "1", "price": 899.0},
+		map[string]interface{}{"id": "2", "price": 1199.0},
+	}})
+	if !errors.As(err, &rolledBack) || len(rolledBack.RolledBack) != 1 || rolledBack.RolledBack[0].Action != "restore" {
+		t.Fatalf("Expected the first update to be restored, got %v", err)
+	}
+	if fake.get("1")["price"] != 999.0 || fake.get("2")["price"] != 1299.0 {
+		t.Errorf("Expected the prices to be unchanged, got %v", fake.sortedProducts())
+	}
+}
+
+func TestAtomicBatchesRefuseWhatTheyCannotRollBack(t *testing.T) {
+	fake := newFakeProductService(t, testProduct("1", "Laptop5", "Electronics", "Laptops", 999))
+	ctx := context.Background()
+
+	// a deleted product cannot be recreated with its id
+	for _, dryRun := range []bool{false, true} {
+		_, err := executeToolCall(ctx, "delete_products", map[string]interface{}{"atomic": true, "ids": []interface{}{"1"}, "dry_run": dryRun})
+		if err == nil || !strings.Contains(err.Error(), "cannot be atomic") {
+			t.Errorf("Expected an atomic delete to be refused (dry_run %v), got %v", dryRun, err)
+		}
+	}
+	// an update without an id is refused before any product is locked or written
+	_, err := executeToolCall(ctx, "update_products", map[string]interface{}{"atomic": true, "products": []interface{}{
+		map[string]interface{}{"id": "1", "price": 899.0},
+		map[string]interface{}{"price": 1199.0},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "missing or invalid product id") {
+		t.Errorf("Expected an update without an id to be refused, got %v", err)
+	}
+	if fake.writeCount() != 0 || fake.get("1")["price"] != 999.0 {
+		t.Errorf("Expected nothing to be written, got %d write(s)", fake.writeCount())
+	}
+}
+
+func TestAtomicBatchesApplyInFull(t *testing.T) {
+	fake := newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Laptops", 999),
+		testProduct("2", "Laptop7", "Electronics", "Laptops", 1299),
+	)
+	ctx := context.Background()
+
+	result, err := executeToolCall(ctx, "update_products", map[string]interface{}{"atomic": true, "products": []interface{}{
+		map[string]interface{}{"id": "1", "price": 899.0},
+		map[string]interface{}{"id": "1", "segment": "Gaming"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := fake.get("1"); p["price"] != 899.0 || p["segment"] != "Gaming" || p["name"] != "Laptop5" {
+		t.Errorf("Expected both updates of the same product to be kept, got %v", p)
+	}
+	if updated := result.(map[string]interface{})["updated"].([]map[string]interface{}); len(updated) != 2 {
+		t.Errorf("Expected the updated products in the result, got %v", result)
+	}
+
+	writes := fake.writeCount()
+	if _, err := executeToolCall(ctx, "update_products", map[string]interface{}{"atomic": true, "products": []interface{}{
+		map[string]interface{}{"id": "2", "price": 1199.0},
+		map[string]interface{}{"id": "9", "price": 5.0},
+	}}); err == nil || fake.writeCount() != writes {
+		t.Errorf("Expected a batch with an unknown product to be refused before writing, got %v", err)
+	}
+	if _, err := executeToolCall(ctx, "update_products", map[string]interface{}{"atomic": "yes", "products": []interface{}{
+		map[string]interface{}{"id": "2", "price": 1199.0},
+	}}); err == nil {
+		t.Error("Expected a non-boolean 'atomic' to be refused")
+	}
+
+	created, err := executeToolCall(ctx, "create_multiple_products", map[string]interface{}{"atomic": true, "products": []interface{}{
+		map[string]interface{}{"name": "Desk", "category": "Furniture", "price": 199.0},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if products := createdProducts("create_multiple_products", created); len(products) != 1 {
+		t.Errorf("Expected the created product in the result, got %v", created)
+	}
+}
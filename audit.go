@@ -0,0 +1,675 @@
+// Package main - audit.go
+//
+// This file records an append-only audit log of every mutating tool call and implements
+// the read-only get_audit_log tool.
+//
+// Key Responsibilities:
+//   - Capture the state of the affected products before and after each mutation
+//   - Record who made the call (API key name), in which MCP session, with which arguments,
+//     and what the product service answered
+//   - Write records to a pluggable sink: a rotating JSONL file, or memory when no file is set
+//   - Filter and return records for get_audit_log, newest first
+//
+// Record format (one JSON object per line in the file sink):
+//
+//	{
+//	  "id": "chg_5f0c...", "time": "2026-10-18T09:12:03Z",
+//	  "caller": "copilot-agent", "session": "a1b2...",
+//	  "tool": "delete_product", "arguments": {"id": "12345"},
+//	  "changes": [{"action": "delete", "product_id": "12345", "before": {...}}],
+//	  "backend": [{"method": "DELETE", "route": "/products/{id}", "status": 200}],
+//	  "outcome": "ok", "duration_ms": 41
+//	}
+//
+//	outcome is "ok" or the error kind of the call (see toolErrorKind in metrics.go);
+//	a write the product service answered with 4xx/5xx is "backend_4xx"/"backend_5xx".
+//	Dry runs and calls declined at confirmation are not recorded. An argument larger
+//	than 4 KiB as JSON is stored as {"omitted": true, "bytes": n, "sha256": "..."}.
+//
+// Before/after states are read from the product service, bypassing the read cache. The
+// before state comes from the plan of the call (see plannedChanges in dryrun.go), which is
+// made once and shared with confirmation, and is read again once the tool holds the
+// product locks (auditLockedProducts), so it includes writes that landed in between. A batch touching more than one product is
+// snapshotted with a single GET /products; deleted products are not read back after a
+// successful call.
+//
+// File rotation:
+//
+//	When the file would exceed audit.max_size_mb it is renamed to <file>.1 (older files
+//	shift to .2, .3, ...) and a new file is started; files beyond audit.max_backups are
+//	removed. get_audit_log reads the backups and the current file. A line longer than
+//	16 MiB (e.g. an import of many products) is skipped with a warning when the file is
+//	read, so one oversized record cannot make the rest of the log unreadable.
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// auditMemoryEntries is the number of records kept by the in-memory sink
+	auditMemoryEntries = 10000
+
+	// maxAuditArgumentBytes is the largest argument stored verbatim in a record
+	maxAuditArgumentBytes = 4 << 10
+
+	// maxAuditRecordBytes is the longest line the file sink reads back
+	maxAuditRecordBytes = 16 << 20
+
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+// auditChange is the state of one product before and after a recorded mutation
+type auditChange struct {
+	Action    string                 `json:"action"` // create, update or delete
+	ProductID string                 `json:"product_id,omitempty"`
+	Before    map[string]interface{} `json:"before,omitempty"`
+	After     map[string]interface{} `json:"after,omitempty"`
+}
+
+// auditBackendCall is one write request sent to the product service
+type auditBackendCall struct {
+	Method string `json:"method"`
+	Route  string `json:"route"`
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// auditRecord is one entry of the audit log
+type auditRecord struct {
+	ID         string                 `json:"id"`
+	Time       time.Time              `json:"time"`
+	Caller     string                 `json:"caller"`
+	Session    string                 `json:"session,omitempty"`
+	Tool       string                 `json:"tool"`
+	Arguments  map[string]interface{} `json:"arguments"`
+	Changes    []auditChange          `json:"changes,omitempty"`
+	Backend    []auditBackendCall     `json:"backend,omitempty"`
+	Outcome    string                 `json:"outcome"`
+	Error      string                 `json:"error,omitempty"`
+	DurationMs int64                  `json:"duration_ms"`
+
+	mu sync.Mutex
+}
+
+// auditSink stores audit records; records returns them oldest first
+type auditSink interface {
+	append(rec *auditRecord) error
+	records() ([]*auditRecord, error)
+}
+
+// auditLog is the active sink, or nil when auditing is disabled; set by configureAudit
+var auditLog auditSink = newMemoryAuditSink(auditMemoryEntries)
+
+var errAuditDisabled = errors.New("the audit log is disabled on this server")
+
+// configureAudit selects the sink from the server config
+func configureAudit(cfg AuditConfig) error {
+	switch {
+	case !cfg.Enabled:
+		auditLog = nil
+	case cfg.File == "":
+		auditLog = newMemoryAuditSink(auditMemoryEntries)
+	default:
+		sink, err := newFileAuditSink(cfg.File, int64(cfg.MaxSizeMB)<<20, cfg.MaxBackups)
+		if err != nil {
+			return err
+		}
+		auditLog = sink
+	}
+	return nil
+}
+
+type auditRecordContextKey struct{}
+
+// auditRecordFromContext returns the record of the mutation being executed, if any
+func auditRecordFromContext(ctx context.Context) *auditRecord {
+	rec, _ := ctx.Value(auditRecordContextKey{}).(*auditRecord)
+	return rec
+}
+
+// auditLockedProducts re-reads the products an audited call is about to write, once it holds
+// their locks, as the before state of the record: the plan was read before the locks were
+// taken, and a write that landed in between must not be undone by undo_change
+func auditLockedProducts(ctx context.Context, ids []string) {
+	rec := auditRecordFromContext(ctx)
+	if rec == nil || len(ids) == 0 {
+		return
+	}
+	current, err := snapshotProducts(withFreshReads(ctx), ids)
+	if err != nil {
+		slog.Warn("Failed to read products before mutation", "tool", rec.Tool, "error", err)
+		return
+	}
+	auditLockedSnapshot(ctx, ids, current)
+}
+
+// auditLockedSnapshot is auditLockedProducts for a tool that already read the products
+// under their locks
+func auditLockedSnapshot(ctx context.Context, ids []string, current map[string]map[string]interface{}) {
+	rec := auditRecordFromContext(ctx)
+	if rec == nil {
+		return
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	for i, c := range rec.Changes {
+		if slices.Contains(ids, c.ProductID) {
+			rec.Changes[i].Before = current[c.ProductID]
+		}
+	}
+}
+
+// recordBackendWrite adds a write request to the audit record of the current call, and
+// tells an idempotent call whether it failed (see idempotency.go)
+func recordBackendWrite(ctx context.Context, method, route string, resp *backendResponse, err error) {
+	noteIdempotentWrite(ctx, resp, err)
+	rec := auditRecordFromContext(ctx)
+	if rec == nil {
+		return
+	}
+	call := auditBackendCall{Method: method, Route: route}
+	if err != nil {
+		call.Error = err.Error()
+	} else {
+		call.Status = resp.StatusCode
+	}
+	rec.mu.Lock()
+	rec.Backend = append(rec.Backend, call)
+	rec.mu.Unlock()
+}
+
+// auditMutation runs a mutating tool call and records it in the audit log
+func auditMutation(ctx context.Context, toolName string, params map[string]interface{}, run toolFunc) (interface{}, error) {
+	sink := auditLog
+	if sink == nil {
+		return run(ctx, toolName, params)
+	}
+
+	// records are timed by the history's clock, since the history is built from them
+	rec := &auditRecord{
+		ID:        newChangeID(),
+		Time:      catalogHistory.now().UTC(),
+		Caller:    callerFromContext(ctx),
+		Tool:      toolName,
+		Arguments: auditArguments(params),
+	}
+	if sess := sessionFromContext(ctx); sess != nil {
+		rec.Session = sess.ID
+	}
+
+	ctx = withPlanMemo(ctx)
+	fresh := withFreshReads(ctx)
+	rec.Changes = auditTargets(ctx, toolName, params)
+	result, err := run(context.WithValue(ctx, auditRecordContextKey{}, rec), toolName, params)
+	rec.Outcome = auditOutcome(rec, err)
+	rec.Changes = completeAuditChanges(fresh, rec.Changes, createdProducts(toolName, result), rec.Outcome == "ok")
+	rec.DurationMs = catalogHistory.now().Sub(rec.Time).Milliseconds()
+	if err != nil {
+		rec.Error = err.Error()
+	}
+
+	if werr := sink.append(rec); werr != nil {
+		slog.Error("Failed to write audit record", "record", rec.ID, "tool", toolName, "error", werr)
+	}
+	catalogHistory.recordChange(rec)
+	return result, err
+}
+
+// auditTargets returns the products a mutation is about to change, with their current
+// state; the plan is the one confirmation used, if the call was confirmed
+func auditTargets(ctx context.Context, toolName string, params map[string]interface{}) []auditChange {
+	planned, err := plannedChanges(ctx, toolName, params)
+	if err != nil {
+		return nil
+	}
+	changes := make([]auditChange, 0, len(planned))
+	var ids []string
+	for _, p := range planned {
+		// created products are taken from the tool result afterwards
+		if p.Action == "create" {
+			continue
+		}
+		// adjust_prices (and import_products with upsert_by_name) plans every matched
+		// product; only the changed ones are written
+		if p.ID == nil || ((toolName == "adjust_prices" || toolName == "import_products") && len(p.Fields) == 0) {
+			continue
+		}
+		id := fmt.Sprint(p.ID)
+		changes = append(changes, auditChange{Action: p.Action, ProductID: id, Before: p.Before})
+		if p.Before == nil {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > 0 {
+		current, err := snapshotProducts(withFreshReads(ctx), ids)
+		if err != nil {
+			slog.Warn("Failed to read products before mutation", "tool", toolName, "error", err)
+		}
+		for i := range changes {
+			if changes[i].Before == nil && changes[i].ProductID != "" {
+				changes[i].Before = current[changes[i].ProductID]
+			}
+		}
+	}
+	return changes
+}
+
+// completeAuditChanges fills in the state after the mutation: created products come from
+// the tool result, updated ones are read back from the product service, and deleted ones
+// too unless every write succeeded
+func completeAuditChanges(ctx context.Context, changes []auditChange, created []map[string]interface{}, succeeded bool) []auditChange {
+	completed := changes
+	var ids []string
+	for _, c := range changes {
+		if c.Action == "delete" && succeeded {
+			continue
+		}
+		ids = append(ids, c.ProductID)
+	}
+	if len(ids) > 0 {
+		current, err := snapshotProducts(ctx, ids)
+		if err != nil {
+			slog.Warn("Failed to read products after mutation", "error", err)
+		}
+		for i := range completed {
+			completed[i].After = current[completed[i].ProductID]
+		}
+	}
+	for _, p := range created {
+		completed = append(completed, auditChange{Action: "create", ProductID: fmt.Sprint(p["id"]), After: p})
+	}
+	return completed
+}
+
+// createdProducts extracts the products created by a tool call from its result
+func createdProducts(toolName string, result interface{}) []map[string]interface{} {
+	switch r := result.(type) {
+	case map[string]interface{}:
+		// undo_change reports recreated products under "created", import_products under
+		// "created_products"
+		if toolName == "undo_change" {
+			created, _ := r["created"].([]map[string]interface{})
+			return created
+		}
+		if toolName == "import_products" {
+			created, _ := r["created_products"].([]map[string]interface{})
+			return created
+		}
+		if _, ok := r["id"]; ok && toolName == "create_product" {
+			return []map[string]interface{}{r}
+		}
+	case []interface{}:
+		var products []map[string]interface{}
+		for _, item := range r {
+			if p, ok := item.(map[string]interface{}); ok && p["id"] != nil {
+				products = append(products, p)
+			}
+		}
+		return products
+	}
+	return nil
+}
+
+// snapshotProducts returns the current state of the given products; products that do not
+// exist are absent from the map
+func snapshotProducts(ctx context.Context, ids []string) (map[string]map[string]interface{}, error) {
+	current := map[string]map[string]interface{}{}
+	if len(ids) == 1 {
+		p, err := fetchProduct(ctx, ids[0])
+		if err != nil {
+			if isNotFound(err) {
+				return current, nil
+			}
+			return current, err
+		}
+		current[ids[0]] = p
+		return current, nil
+	}
+	products, err := fetchProducts(ctx, "/products", productServiceBaseURL+"/products")
+	if err != nil {
+		return current, err
+	}
+	wanted := map[string]bool{}
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	for _, p := range products {
+		if id := fmt.Sprint(p["id"]); wanted[id] {
+			current[id] = p
+		}
+	}
+	return current, nil
+}
+
+// auditOutcome is "ok", the error kind of the call, or the status class of a failed write
+func auditOutcome(rec *auditRecord, err error) string {
+	if err != nil {
+		return toolErrorKind(err)
+	}
+	for _, call := range rec.Backend {
+		switch {
+		case call.Error != "":
+			return "backend_unavailable"
+		case call.Status >= 500:
+			return "backend_5xx"
+		case call.Status >= 400:
+			return "backend_4xx"
+		}
+	}
+	return "ok"
+}
+
+func newChangeID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return "chg_" + hex.EncodeToString(buf)
+}
+
+// auditArguments copies the arguments of a call for its audit record; an argument whose
+// JSON exceeds maxAuditArgumentBytes (e.g. import content or a long product list) is
+// replaced by its size and SHA-256, since the changes already record every product
+func auditArguments(params map[string]interface{}) map[string]interface{} {
+	args := copyParams(params)
+	for k, v := range args {
+		raw, err := json.Marshal(v)
+		if err != nil || len(raw) <= maxAuditArgumentBytes {
+			continue
+		}
+		sum := sha256.Sum256(raw)
+		args[k] = map[string]interface{}{
+			"omitted": true,
+			"bytes":   len(raw),
+			"sha256":  hex.EncodeToString(sum[:]),
+		}
+	}
+	return args
+}
+
+func copyParams(params map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		copied[k] = v
+	}
+	return copied
+}
+
+// memoryAuditSink keeps the most recent records in memory
+type memoryAuditSink struct {
+	mu      sync.Mutex
+	entries []*auditRecord
+	max     int
+}
+
+func newMemoryAuditSink(max int) *memoryAuditSink {
+	return &memoryAuditSink{max: max}
+}
+
+func (s *memoryAuditSink) append(rec *auditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, rec)
+	if len(s.entries) > s.max {
+		s.entries = append([]*auditRecord(nil), s.entries[len(s.entries)-s.max:]...)
+	}
+	return nil
+}
+
+func (s *memoryAuditSink) records() ([]*auditRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*auditRecord(nil), s.entries...), nil
+}
+
+// fileAuditSink appends records as JSON lines to a size-rotated file
+type fileAuditSink struct {
+	mu         sync.Mutex
+	path       string
+	maxBytes   int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newFileAuditSink(path string, maxBytes int64, maxBackups int) (*fileAuditSink, error) {
+	s := &fileAuditSink{path: path, maxBytes: maxBytes, maxBackups: maxBackups}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileAuditSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %v", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open audit log: %v", err)
+	}
+	s.file, s.size = f, info.Size()
+	return nil
+}
+
+func (s *fileAuditSink) append(rec *auditRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size > 0 && s.size+int64(len(line)) > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+// rotate must be called with s.mu held
+func (s *fileAuditSink) rotate() error {
+	s.file.Close()
+	os.Remove(s.backupPath(s.maxBackups))
+	for i := s.maxBackups - 1; i >= 1; i-- {
+		os.Rename(s.backupPath(i), s.backupPath(i+1))
+	}
+	if s.maxBackups > 0 {
+		if err := os.Rename(s.path, s.backupPath(1)); err != nil {
+			return fmt.Errorf("failed to rotate audit log: %v", err)
+		}
+	} else {
+		os.Remove(s.path)
+	}
+	return s.open()
+}
+
+func (s *fileAuditSink) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", s.path, i)
+}
+
+func (s *fileAuditSink) records() ([]*auditRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var recs []*auditRecord
+	paths := []string{}
+	for i := s.maxBackups; i >= 1; i-- {
+		paths = append(paths, s.backupPath(i))
+	}
+	paths = append(paths, s.path)
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %v", err)
+		}
+		reader := bufio.NewReaderSize(f, 64<<10)
+		for lineNo := 1; ; lineNo++ {
+			line, oversized, err := readAuditLine(reader)
+			rec := &auditRecord{}
+			if oversized {
+				slog.Warn("Skipping an oversized audit record", "file", path, "line", lineNo, "max_bytes", maxAuditRecordBytes)
+			} else if len(line) > 0 && json.Unmarshal(line, rec) == nil {
+				recs = append(recs, rec)
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				f.Close()
+				return nil, fmt.Errorf("failed to read audit log: %v", err)
+			}
+		}
+		f.Close()
+	}
+	return recs, nil
+}
+
+// readAuditLine reads one line of an audit file; a line longer than maxAuditRecordBytes is
+// consumed and reported as oversized instead of returned
+func readAuditLine(r *bufio.Reader) (line []byte, oversized bool, err error) {
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if oversized || len(line)+len(chunk) > maxAuditRecordBytes {
+			line, oversized = nil, true
+		} else {
+			line = append(line, chunk...)
+		}
+		if err != bufio.ErrBufferFull {
+			return line, oversized, err
+		}
+	}
+}
+
+// getAuditLog returns the audit records matching the filters, newest first
+func getAuditLog(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	sink := auditLog
+	if sink == nil {
+		return nil, errAuditDisabled
+	}
+
+	stringFilters := map[string]string{}
+	for _, key := range []string{"tool", "caller", "session", "product", "outcome", "change_id"} {
+		if v, present := params[key]; present {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid '%s' argument: must be a string", key)
+			}
+			stringFilters[key] = s
+		}
+	}
+	var since, until time.Time
+	for key, dst := range map[string]*time.Time{"since": &since, "until": &until} {
+		if v, present := params[key]; present {
+			s, _ := v.(string)
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid '%s' argument: must be an RFC 3339 timestamp", key)
+			}
+			*dst = t
+		}
+	}
+	limit := defaultAuditLimit
+	if v, present := params["limit"]; present {
+		l, ok := v.(float64)
+		if !ok || l < 1 || l != float64(int(l)) {
+			return nil, fmt.Errorf("invalid 'limit' argument: must be a positive integer")
+		}
+		limit = min(int(l), maxAuditLimit)
+	}
+
+	recs, err := sink.records()
+	if err != nil {
+		return nil, err
+	}
+	entries := []*auditRecord{}
+	total := 0
+	for i := len(recs) - 1; i >= 0; i-- {
+		rec := recs[i]
+		if !auditRecordMatches(rec, stringFilters, since, until) {
+			continue
+		}
+		total++
+		if len(entries) < limit {
+			entries = append(entries, rec)
+		}
+	}
+	return map[string]interface{}{"entries": entries, "total": total}, nil
+}
+
+func auditRecordMatches(rec *auditRecord, filters map[string]string, since, until time.Time) bool {
+	if !since.IsZero() && rec.Time.Before(since) {
+		return false
+	}
+	if !until.IsZero() && rec.Time.After(until) {
+		return false
+	}
+	for key, want := range filters {
+		switch key {
+		case "tool":
+			if rec.Tool != want {
+				return false
+			}
+		case "caller":
+			if rec.Caller != want {
+				return false
+			}
+		case "session":
+			if rec.Session != want {
+				return false
+			}
+		case "outcome":
+			if rec.Outcome != want {
+				return false
+			}
+		case "change_id":
+			if rec.ID != want {
+				return false
+			}
+		case "product":
+			found := false
+			for _, c := range rec.Changes {
+				if c.ProductID == want || strings.EqualFold(productName(c), want) {
+					found = true
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// productName is the name of the product a change applies to, before or after the change
+func productName(c auditChange) string {
+	for _, state := range []map[string]interface{}{c.After, c.Before} {
+		if name, ok := state["name"].(string); ok {
+			return name
+		}
+	}
+	return ""
+}
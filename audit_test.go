@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLogRecordsMutations(t *testing.T) {
+	fake := newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Laptops", 999),
+		testProduct("2", "Office Chair", "Furniture", "Budget", 149),
+	)
+	swapGlobal[auditSink](t, &auditLog, newMemoryAuditSink(100))
+	ctx := context.WithValue(context.Background(), callerContextKey{}, "pricing-agent")
+
+	if _, err := executeToolCall(ctx, "update_product", map[string]interface{}{"id": "2", "price": 129.0}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executeToolCall(ctx, "delete_product", map[string]interface{}{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executeToolCall(ctx, "create_product", map[string]interface{}{"name": "Desk", "category": "Furniture", "price": 299.0}); err != nil {
+		t.Fatal(err)
+	}
+	// reads and dry runs are not recorded
+	executeToolCall(ctx, "list_products", map[string]interface{}{})
+	executeToolCall(ctx, "delete_product", map[string]interface{}{"id": "2", "dry_run": true})
+	if fake.get("2") == nil {
+		t.Fatal("Expected the dry run to leave the product in place")
+	}
+
+	result, err := executeToolCall(context.Background(), "get_audit_log", map[string]interface{}{"product": "laptop5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := result.(map[string]interface{})["entries"].([]*auditRecord)
+	if len(entries) != 1 {
+		t.Fatalf("Expected one record for Laptop5, got %d", len(entries))
+	}
+	rec := entries[0]
+	if rec.Tool != "delete_product" || rec.Caller != "pricing-agent" || rec.Outcome != "ok" || rec.ID == "" {
+		t.Errorf("Unexpected record %+v", rec)
+	}
+	if len(rec.Changes) != 1 || rec.Changes[0].Before["name"] != "Laptop5" || rec.Changes[0].After != nil {
+		t.Errorf("Expected the deleted product's before state, got %+v", rec.Changes)
+	}
+	if len(rec.Backend) != 1 || rec.Backend[0].Status != 200 {
+		t.Errorf("Expected the backend DELETE outcome, got %+v", rec.Backend)
+	}
+
+	result, _ = executeToolCall(context.Background(), "get_audit_log", map[string]interface{}{})
+	all := result.(map[string]interface{})
+	if all["total"] != 3 {
+		t.Fatalf("Expected 3 records, got %v", all["total"])
+	}
+	entries = all["entries"].([]*auditRecord)
+	if entries[0].Tool != "create_product" || entries[0].Changes[0].After["name"] != "Desk" {
+		t.Errorf("Expected the newest record first with the created product, got %+v", entries[0])
+	}
+	update := entries[2].Changes[0]
+	if update.Before["price"] != 149.0 || update.After["price"] != 129.0 {
+		t.Errorf("Expected the price before and after the update, got %+v", update)
+	}
+
+	if _, err := executeToolCall(context.Background(), "get_audit_log", map[string]interface{}{"since": "yesterday"}); err == nil {
+		t.Error("Expected an error for an invalid 'since'")
+	}
+}
+
+func TestAuditLogRecordsFailedWrites(t *testing.T) {
+	newFakeProductService(t)
+	swapGlobal[auditSink](t, &auditLog, newMemoryAuditSink(100))
+
+	executeToolCall(context.Background(), "delete_product", map[string]interface{}{"id": "missing"})
+	recs, _ := auditLog.records()
+	if len(recs) != 1 || recs[0].Outcome != "backend_4xx" {
+		t.Fatalf("Expected a backend_4xx record, got %+v", recs)
+	}
+}
+
+func TestAuditLogReusesTheConfirmationPlan(t *testing.T) {
+	fake := newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Laptops", 999),
+		testProduct("2", "Office Chair", "Furniture", "Budget", 149),
+	)
+	swapGlobal[auditSink](t, &auditLog, newMemoryAuditSink(100))
+	ctx := withPlanMemo(context.Background())
+	params := map[string]interface{}{"ids": []interface{}{"1", "2"}}
+
+	if _, _, err := describeMutation(ctx, "delete_products", params); err != nil {
+		t.Fatal(err)
+	}
+	reads := fake.readCount()
+	if _, err := executeToolCall(ctx, "delete_products", params); err != nil {
+		t.Fatal(err)
+	}
+	// planning read each product; under the locks the record re-reads them in one call
+	if fake.readCount() != reads+1 {
+		t.Errorf("Expected the audit log to reuse the confirmation plan, got %d more reads", fake.readCount()-reads)
+	}
+	recs, _ := auditLog.records()
+	if len(recs) != 1 || len(recs[0].Changes) != 2 || recs[0].Changes[1].Before["name"] != "Office Chair" {
+		t.Errorf("Expected both deleted products in the record, got %+v", recs)
+	}
+}
+
+func TestAuditLogReadsTheBeforeStateUnderTheLock(t *testing.T) {
+	fake := newFakeProductService(t, testProduct("1", "Laptop5", "Electronics", "Laptops", 999))
+	swapGlobal[auditSink](t, &auditLog, newMemoryAuditSink(100))
+	unlock := lockProducts([]string{"1"})
+	done := make(chan error, 1)
+	go func() {
+		_, err := executeToolCall(context.Background(), "update_product", map[string]interface{}{"id": "1", "segment": "Gaming"})
+		done <- err
+	}()
+
+	// another write lands after the call was planned, while it waits for the lock
+	time.Sleep(50 * time.Millisecond)
+	fake.mu.Lock()
+	fake.products["1"]["price"] = 899.0
+	fake.mu.Unlock()
+	unlock()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	recs, _ := auditLog.records()
+	if len(recs) != 1 || recs[0].Changes[0].Before["price"] != 899.0 {
+		t.Fatalf("Expected the before state read under the lock, got %+v", recs)
+	}
+
+	// undoing the update keeps the other write
+	if _, err := executeToolCall(context.Background(), "undo_change", map[string]interface{}{"change_id": recs[0].ID}); err != nil {
+		t.Fatal(err)
+	}
+	if p := fake.get("1"); p["price"] != 899.0 || p["segment"] != "Laptops" {
+		t.Errorf("Expected only the segment to be reverted, got %v", p)
+	}
+}
+
+func TestAuditArgumentsOmitLargeValues(t *testing.T) {
+	content := strings.Repeat("Desk;Furniture;199\n", 1000)
+	args := auditArguments(map[string]interface{}{"content": content, "skip_invalid": true})
+	omitted, ok := args["content"].(map[string]interface{})
+	if !ok || omitted["omitted"] != true || omitted["bytes"].(int) <= maxAuditArgumentBytes || len(omitted["sha256"].(string)) != 64 {
+		t.Errorf("Expected the content to be replaced by its size and hash, got %v", args["content"])
+	}
+	if args["skip_invalid"] != true {
+		t.Errorf("Expected small arguments to be kept, got %v", args)
+	}
+}
+
+func TestFileAuditSinkRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := newFileAuditSink(path, 300, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := sink.append(&auditRecord{ID: fmt.Sprintf("chg_%d", i), Tool: "delete_product", Outcome: "ok"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(path + ".2"); err != nil {
+		t.Errorf("Expected two backups: %v", err)
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Error("Expected backups beyond max_backups to be removed")
+	}
+
+	recs, err := sink.records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) == 0 || len(recs) >= 10 || recs[len(recs)-1].ID != "chg_9" {
+		t.Errorf("Expected the most recent records in order, got %d ending with %v", len(recs), recs[len(recs)-1].ID)
+	}
+	for i := 1; i < len(recs); i++ {
+		if recs[i-1].ID >= recs[i].ID {
+			t.Errorf("Expected records oldest first, got %s before %s", recs[i-1].ID, recs[i].ID)
+		}
+	}
+}
+
+func TestFileAuditSinkSkipsOversizedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := newFileAuditSink(path, 64<<20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	huge := make([]map[string]interface{}, 0, 250000)
+	for i := 0; i < cap(huge); i++ {
+		huge = append(huge, map[string]interface{}{"id": fmt.Sprint(i), "name": "Office Chair", "category": "Furniture", "price": 149.0})
+	}
+	for _, rec := range []*auditRecord{
+		{ID: "chg_1", Tool: "delete_product", Outcome: "ok"},
+		{ID: "chg_2", Tool: "import_products", Outcome: "ok", Changes: []auditChange{{Action: "create", After: map[string]interface{}{"products": huge}}}},
+		{ID: "chg_3", Tool: "delete_product", Outcome: "ok"},
+	} {
+		if err := sink.append(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if info, _ := os.Stat(path); info.Size() <= maxAuditRecordBytes {
+		t.Fatalf("Expected the record to exceed %d bytes, got a %d byte file", maxAuditRecordBytes, info.Size())
+	}
+
+	recs, err := sink.records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 || recs[0].ID != "chg_1" || recs[1].ID != "chg_3" {
+		t.Errorf("Expected the records around the oversized one, got %d records", len(recs))
+	}
+}
@@ -0,0 +1,48 @@
+// Package main - auth.go
+//
+// This file implements optional API key authentication for the MCP endpoints and /metrics.
+//
+// Key Responsibilities:
+//   - Check the X-API-Key header against auth.api_keys from the server config
+//   - Store the authenticated caller name in the request context
+//
+// Authentication is disabled when no API keys are configured; every request is then
+// attributed to the "anonymous" caller. The X-API-Key header is used instead of
+// Authorization so that it does not collide with Cloud Run identity tokens.
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+)
+
+type callerContextKey struct{}
+
+const anonymousCaller = "anonymous"
+
+// requireAPIKey wraps next with API key authentication when keys are configured
+func requireAPIKey(auth AuthConfig, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(auth.APIKeys) == 0 || r.Method == http.MethodOptions {
+			next(w, r.WithContext(context.WithValue(r.Context(), callerContextKey{}, anonymousCaller)))
+			return
+		}
+		presented := r.Header.Get("X-API-Key")
+		for name, key := range auth.APIKeys {
+			if presented != "" && subtle.ConstantTimeCompare([]byte(presented), []byte(key)) == 1 {
+				next(w, r.WithContext(context.WithValue(r.Context(), callerContextKey{}, name)))
+				return
+			}
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}
+}
+
+// callerFromContext returns the authenticated caller name for the request
+func callerFromContext(ctx context.Context) string {
+	if caller, ok := ctx.Value(callerContextKey{}).(string); ok {
+		return caller
+	}
+	return anonymousCaller
+}
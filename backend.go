@@ -0,0 +1,253 @@
+// Package main - backend.go
+//
+// This file contains the HTTP client used for every call to the backend product service.
+//
+// Key Responsibilities:
+//   - Send requests to the product service through a single shared http.Client
+//   - Retry idempotent (GET) requests on transient failures
+//   - Stop calling an unhealthy backend through a simple circuit breaker
+//   - Record backend metrics (requests by route and status, latency, retries, circuit state)
+//   - Trace every attempt with a client span and propagate the W3C traceparent header
+//
+// Circuit Breaker:
+//   - closed:    requests flow normally; consecutive failures are counted
+//   - open:      after circuitFailureThreshold consecutive failures requests fail fast
+//   - half-open: after circuitCooldown a single probe request is let through; success
+//     closes the circuit, failure opens it again
+//
+// A failure is a transport error or a 5xx response. 4xx responses are successful calls
+// from the circuit's point of view, since the backend answered correctly.
+//
+// The retries and the circuit breaker are what the backend_retries_total and
+// backend_circuit_* metrics (metrics.go) count; both only change behavior when the backend
+// fails: a healthy backend sees exactly one request per call, as before.
+//
+// Routes:
+//
+//	Every call is labelled with a route template (e.g. "/products/{id}") rather than the
+//	concrete URL so that metrics keep a bounded set of label values.
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	backendMaxRetries       = 2
+	backendRetryBackoff     = 100 * time.Millisecond
+	circuitFailureThreshold = 5
+	circuitCooldown         = 30 * time.Second
+)
+
+var backendHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// backendResponse holds a fully read response from the product service
+type backendResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Cached     bool // served from a read cache entry (see cache.go) rather than by the backend
+}
+
+// backendStatusError is returned when the product service answers with an unexpected status
+type backendStatusError struct {
+	StatusCode int
+}
+
+func (e *backendStatusError) Error() string {
+	return fmt.Sprintf("product service returned status %d", e.StatusCode)
+}
+
+// backendUnavailableError is returned when the product service could not be reached
+type backendUnavailableError struct {
+	Err error
+}
+
+func (e *backendUnavailableError) Error() string {
+	return fmt.Sprintf("failed to call microservice: %v", e.Err)
+}
+
+func (e *backendUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+var errCircuitOpen = errors.New("product service is unavailable (circuit breaker open), try again later")
+
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half_open"
+)
+
+type circuitBreaker struct {
+	mu            sync.Mutex
+	state         string
+	failures      int
+	openedAt      time.Time
+	probeInFlight bool
+}
+
+var backendCircuit = &circuitBreaker{state: circuitClosed}
+
+// allow reports whether a request may be sent to the backend
+func (c *circuitBreaker) allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch c.state {
+	case circuitOpen:
+		if time.Since(c.openedAt) < circuitCooldown {
+			return false
+		}
+		c.setState(circuitHalfOpen)
+		c.probeInFlight = true
+		return true
+	case circuitHalfOpen:
+		if c.probeInFlight {
+			return false
+		}
+		c.probeInFlight = true
+		return true
+	}
+	return true
+}
+
+// record updates the circuit with the outcome of a request that allow() let through
+func (c *circuitBreaker) record(success bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.probeInFlight = false
+	if success {
+		c.failures = 0
+		if c.state != circuitClosed {
+			c.setState(circuitClosed)
+		}
+		return
+	}
+	c.failures++
+	if c.state == circuitHalfOpen || c.failures >= circuitFailureThreshold {
+		c.openedAt = time.Now()
+		if c.state != circuitOpen {
+			c.setState(circuitOpen)
+		}
+	}
+}
+
+// setState must be called with c.mu held
+func (c *circuitBreaker) setState(state string) {
+	c.state = state
+	backendCircuitTransitions.WithLabelValues(state).Inc()
+	for _, s := range []string{circuitClosed, circuitOpen, circuitHalfOpen} {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		backendCircuitState.WithLabelValues(s).Set(value)
+	}
+}
+
+// callBackend sends a request to the product service and returns the fully read response.
+// GET requests are retried on transport errors and 5xx responses. header, if not nil, is
+// added to the request (e.g. If-None-Match).
+func callBackend(ctx context.Context, method, route, url string, body []byte, header http.Header) (*backendResponse, error) {
+	if !backendCircuit.allow() {
+		backendCircuitRejections.WithLabelValues(route).Inc()
+		return nil, errCircuitOpen
+	}
+
+	attempts := 1
+	if method == http.MethodGet {
+		attempts += backendMaxRetries
+	}
+
+	backendInFlight.Inc()
+	defer backendInFlight.Dec()
+
+	var resp *backendResponse
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if ctx.Err() != nil {
+				break
+			}
+			backendRetries.WithLabelValues(method, route).Inc()
+			// a cancelled request stops waiting and returns the last attempt
+			backoff := time.NewTimer(backendRetryBackoff << (attempt - 1))
+			select {
+			case <-ctx.Done():
+				backoff.Stop()
+			case <-backoff.C:
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		resp, err = doBackendRequest(ctx, method, route, url, body, header, attempt)
+		if err == nil && resp.StatusCode < 500 {
+			break
+		}
+	}
+
+	backendCircuit.record(err == nil && resp.StatusCode < 500)
+	return resp, err
+}
+
+func doBackendRequest(ctx context.Context, method, route, url string, body []byte, header http.Header, attempt int) (result *backendResponse, err error) {
+	ctx, span := tracer.Start(ctx, method+" "+route,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(method),
+			semconv.HTTPRoute(route),
+			semconv.URLFull(url),
+			attribute.Int("http.request.resend_count", attempt),
+		),
+	)
+	start := time.Now()
+	status := "error"
+	defer func() {
+		backendRequests.WithLabelValues(method, route, status).Inc()
+		backendRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+		endSpanWithError(span, err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := backendHTTPClient.Do(req)
+	if err != nil {
+		return nil, &backendUnavailableError{Err: err}
+	}
+	defer resp.Body.Close()
+	status = strconv.Itoa(resp.StatusCode)
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 500 {
+		span.SetStatus(codes.Error, "HTTP "+status)
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read microservice response: %v", err)
+	}
+	return &backendResponse{StatusCode: resp.StatusCode, Header: resp.Header, Body: respBody}, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	c := &circuitBreaker{state: circuitClosed}
+
+	for i := 0; i < circuitFailureThreshold; i++ {
+		if !c.allow() {
+			t.Fatalf("Expected request %d to be allowed while circuit is closed", i)
+		}
+		c.record(false)
+	}
+
+	if c.state != circuitOpen {
+		t.Fatalf("Expected circuit to be '%s', got '%s'", circuitOpen, c.state)
+	}
+	if c.allow() {
+		t.Errorf("Expected request to be rejected while circuit is open")
+	}
+
+	// after the cooldown a single probe is allowed
+	c.openedAt = time.Now().Add(-circuitCooldown)
+	if !c.allow() {
+		t.Fatalf("Expected probe request to be allowed after cooldown")
+	}
+	if c.allow() {
+		t.Errorf("Expected only one probe while circuit is half-open")
+	}
+	c.record(true)
+	if c.state != circuitClosed {
+		t.Errorf("Expected circuit to be '%s' after successful probe, got '%s'", circuitClosed, c.state)
+	}
+}
+
+func TestCallBackendStopsRetryingWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		// cancel while the client waits for the first retry
+		time.AfterFunc(10*time.Millisecond, cancel)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	defer backendCircuit.record(true)
+
+	start := time.Now()
+	resp, err := callBackend(ctx, http.MethodGet, "/products", srv.URL+"/products", nil, nil)
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected the last response to be returned, got %v, %v", resp, err)
+	}
+	if elapsed := time.Since(start); elapsed >= backendRetryBackoff || calls.Load() != 1 {
+		t.Errorf("Expected the backoff to end with the request context, took %v and %d calls", elapsed, calls.Load())
+	}
+}
+
+func TestToolErrorKind(t *testing.T) {
+	cases := map[string]error{
+		"unknown_tool":        fmt.Errorf("%w: %s", errUnknownTool, "nope"),
+		"circuit_open":        errCircuitOpen,
+		"backend_unavailable": &backendUnavailableError{Err: fmt.Errorf("connection refused")},
+		"backend_5xx":         &backendStatusError{StatusCode: 503},
+		"backend_4xx":         &backendStatusError{StatusCode: 404},
+		"tool":                fmt.Errorf("missing or invalid product id"),
+	}
+	for expected, err := range cases {
+		if kind := toolErrorKind(err); kind != expected {
+			t.Errorf("Expected kind '%s' for %q, got '%s'", expected, err, kind)
+		}
+	}
+}
@@ -11,12 +11,17 @@
 //   - Handle errors from the backend service
 //
 // Tool Execution Flow:
-//   1. executeToolCall() receives tool name and parameters
-//   2. Routes to specific tool function based on tool name
+//   1. executeToolCall() receives the request context, tool name and parameters
+//   2. Routes to specific tool function based on tool name (runTool); mutating tools are
+//      wrapped by auditMutation() (audit.go), which records them in the audit log
 //   3. Tool function validates parameters and constructs HTTP request
 //   4. invokeMicroservice() makes the actual HTTP call
 //   5. Response is parsed and returned to handler
 //
+//   Mutating tools called with dry_run=true are routed to previewMutation() (dryrun.go)
+//   instead, which only performs reads. Creates with an idempotency_key go through
+//   idempotentCalls (idempotency.go) first.
+//
 // Tool Functions:
 //
 //   Service Tools:
@@ -26,217 +31,325 @@
 //   Single Product Operations:
 //     - createProduct: POST /products
 //     - getProduct: GET /products/{id}
-//     - updateProduct: PUT /products/{id}
+//     - updateProduct: GET /products/{id}, then PUT /products/{id} with the merge-patched product
+//       (optionally conditional, see concurrency.go)
 //     - deleteProduct: DELETE /products/{id}
-//     - listProducts: GET /products
+//     - listProducts: GET /products (paginated, see pagination.go)
 //
 //   Batch Operations:
 //     - createMultipleProducts: POST /products/create-multiple
-//     - updateProducts: POST /products/update
+//     - updateProducts: POST /products/update (optionally conditional, see concurrency.go)
 //     - deleteProducts: POST /products/delete
+//     - with 'atomic' (creates and updates), one write per product and a rollback on
+//       failure (see atomic.go)
+//
+//   Price Operations (pricing.go):
+//     - adjustPrices: GET /products, then POST /products/update with the computed prices
+//
+//   Analytics (stats.go):
+//     - catalogStats: GET /products, then aggregates prices per group server-side
 //
 //   Query Operations:
-//     - getProductsByCategory: GET /products/category/{category}
-//     - getProductsBySegment: GET /products/segment/{segment}
-//     - getProductByName: GET /products/{name}
+//     - getProductsByCategory: GET /products/category/{category} (paginated)
+//     - getProductsBySegment: GET /products/segment/{segment} (paginated)
+//     - getProductByName: GET /products/{name}, then fuzzy name resolution over GET /products (fuzzy.go)
+//     - listTaxonomy: GET /products, then distinct categories/segments (taxonomy.go)
+//
+//   Duplicates (duplicates.go):
+//     - findDuplicates: GET /products, then clusters near-duplicate products
+//     - mergeProducts: PUT /products/{id} on the kept product, then POST /products/delete
+//
+//   Data Quality (validation.go):
+//     - validateCatalog: GET /products, then checks every product against the validation rules
+//     - createProduct, createMultipleProducts, updateProduct, updateProducts and mergeProducts
+//       refuse values that break the rules before calling the product service
+//
+//   Import (import.go):
+//     - importProducts: CSV or JSON Lines rows in chunks to POST /products/create-multiple, and
+//       POST /products/update for rows matching an existing product by name (upsert_by_name)
+//
+//   Audit (audit.go):
+//     - getAuditLog: reads the audit log of mutating tool calls; no backend call
+//     - undoChange: reverts a recorded change with DELETE, PUT or POST (undo.go)
+//
+//   History (history.go):
+//     - getProductHistory: GET /products/{id}, then the recorded versions of the product
+//     - getProduct / listProducts with 'as_of': reconstructed from the history, no backend call
 //
 // Helper Functions:
-//   - invokeMicroservice: Generic HTTP client for backend service calls
+//   - invokeMicroservice: Generic JSON call to the backend service (any method)
+//   - getJSON / fetchProducts: GET helpers that fail on non-200 responses
+//   - All of them go through callBackend() in backend.go (retries, circuit breaker, metrics);
+//     GET requests go through the read-through cache first (cache.go)
 //
 // Backend Service:
-//   - Base URL: https://product-service-256110662801.europe-west3.run.app
-//   - TODO: Read from MICROSERVICE_URL environment variable
+//   - Base URL: microservice_url from the server config (see config.go), set once at startup
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	neturl "net/url"
+	"reflect"
+	"slices"
 	"sort"
 	"strings"
 )
 
-// TODO: read it from environment variable MICROSERVICE_URL
-const productServiceBaseURL = "https://product-service-256110662801.europe-west3.run.app"
+// productServiceBaseURL is set from Config.MicroserviceURL in main()
+var productServiceBaseURL = defaultMicroserviceURL
+
+var errUnknownTool = errors.New("unknown tool")
 
 // business logic functions for MCP server
-func executeToolCall(toolName string, params map[string]interface{}) (interface{}, error) {
+func executeToolCall(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+	dryRun, _ := params["dry_run"].(bool)
+	delete(params, "dry_run")
+	var idempotencyKey string
+	if idempotentTools[toolName] {
+		key, err := idempotencyKeyParam(params)
+		if err != nil {
+			return nil, err
+		}
+		idempotencyKey = key
+	}
+	if dryRun && mutatingTools[toolName] {
+		return previewMutation(ctx, toolName, params)
+	}
+	if idempotencyKey != "" {
+		// a retried create returns the first result instead of creating duplicates (see
+		// idempotency.go)
+		return idempotentCalls.do(ctx, idempotencyKey, toolName, params, func(ctx context.Context) (interface{}, error) {
+			return runMutation(ctx, toolName, params)
+		})
+	}
+	if mutatingTools[toolName] {
+		return runMutation(ctx, toolName, params)
+	}
+	return runTool(ctx, toolName, params)
+}
+
+// runMutation runs a mutating tool through the audit log
+func runMutation(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+	// writes through this server make cached reads and the search index stale (see
+	// cache.go and searchindex.go); failed calls invalidate too, since a batch may have
+	// been partially applied. The cache goes first so the index rebuild reads fresh data.
+	defer catalogSearch.invalidate()
+	defer catalogCache.invalidate()
+	return auditMutation(ctx, toolName, params, runTool)
+}
+
+// toolFunc executes a tool call
+type toolFunc func(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error)
+
+// runTool routes a tool call to its implementation
+func runTool(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
 	switch toolName {
 	case "welcome_message":
 		return map[string]string{"message": "Welcome to the MCP Product Service!"}, nil
 	case "health_check":
 		return map[string]string{"status": "ok"}, nil
 	case "create_product":
-		return createProduct(params)
+		return createProduct(ctx, params)
 	case "get_product":
-		return getProduct(params)
+		return getProduct(ctx, params)
 	case "get_products_by_category":
-		return getProductsByCategory(params)
+		return getProductsByCategory(ctx, params)
 	case "get_products_by_segment":
-		return getProductsBySegment(params)
+		return getProductsBySegment(ctx, params)
 	case "get_product_by_name":
-		return getProductByName(params)
+		return getProductByName(ctx, params)
 	case "list_products":
-		return listProducts(params)
+		return listProducts(ctx, params)
 	case "create_multiple_products":
-		return createMultipleProducts(params)
+		return createMultipleProducts(ctx, params)
+	case "find_duplicates":
+		return findDuplicates(ctx, params)
+	case "merge_products":
+		return mergeProducts(ctx, params)
+	case "import_products":
+		return importProducts(ctx, params)
+	case "validate_catalog":
+		return validateCatalog(ctx, params)
 	case "update_product":
-		return updateProduct(params)
+		return updateProduct(ctx, params)
 	case "update_products":
-		return updateProducts(params)
+		return updateProducts(ctx, params)
 	case "delete_product":
-		return deleteProduct(params)
+		return deleteProduct(ctx, params)
 	case "delete_products":
-		return deleteProducts(params)
+		return deleteProducts(ctx, params)
 	case "search_products":
-		return searchProducts(params)
-	}
-	return nil, fmt.Errorf("unknown tool: %s", toolName)
+		return searchProducts(ctx, params)
+	case "adjust_prices":
+		return adjustPrices(ctx, params)
+	case "catalog_stats":
+		return catalogStats(ctx, params)
+	case "list_taxonomy":
+		return listTaxonomy(ctx, params)
+	case "get_audit_log":
+		return getAuditLog(ctx, params)
+	case "undo_change":
+		return undoChange(ctx, params)
+	case "get_product_history":
+		return getProductHistory(ctx, params)
+	}
+	return nil, fmt.Errorf("%w: %s", errUnknownTool, toolName)
 }
 
-// Returns all products in the store
-func listProducts(params map[string]interface{}) (interface{}, error) {
-	url := productServiceBaseURL + "/products"
-	resp, err := http.Get(url)
+// Returns one page of the products in the store
+func listProducts(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	page, err := parsePageRequest("list_products", params)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("product service returned status %d", resp.StatusCode)
+	asOf, err := asOfParam(params)
+	if err != nil {
+		return nil, err
 	}
 	var products []map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&products); err != nil {
+	if !asOf.IsZero() {
+		products, err = catalogHistory.catalogAt(asOf)
+	} else {
+		products, err = fetchProducts(ctx, "/products", productServiceBaseURL+"/products")
+	}
+	if err != nil {
 		return nil, err
 	}
-	return products, nil
+	return page.paginate(products), nil
 }
 
-// Returns all products matching a given category
-func getProductsByCategory(params map[string]interface{}) (interface{}, error) {
+// Returns one page of the products matching a given category
+func getProductsByCategory(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 	category, ok := params["category"].(string)
 	if !ok || category == "" {
 		return nil, fmt.Errorf("missing or invalid 'category' argument")
 	}
-	url := productServiceBaseURL + "/products/category/" + category
-	resp, err := http.Get(url)
+	page, err := parsePageRequest("get_products_by_category", params)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("product service returned status %d", resp.StatusCode)
-	}
-	var products []map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&products); err != nil {
+	url := productServiceBaseURL + "/products/category/" + category
+	products, err := fetchProducts(ctx, "/products/category/{category}", url)
+	if err != nil {
 		return nil, err
 	}
-	return products, nil
+	return page.paginate(products), nil
 }
 
-// Returns all products matching a given segment
-func getProductsBySegment(params map[string]interface{}) (interface{}, error) {
+// Returns one page of the products matching a given segment
+func getProductsBySegment(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 	segment, ok := params["segment"].(string)
 	if !ok || segment == "" {
 		return nil, fmt.Errorf("missing or invalid 'segment' argument")
 	}
-	url := productServiceBaseURL + "/products/segment/" + segment
-	resp, err := http.Get(url)
+	page, err := parsePageRequest("get_products_by_segment", params)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("product service returned status %d", resp.StatusCode)
-	}
-	var products []map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&products); err != nil {
+	url := productServiceBaseURL + "/products/segment/" + segment
+	products, err := fetchProducts(ctx, "/products/segment/{segment}", url)
+	if err != nil {
 		return nil, err
 	}
-	return products, nil
+	return page.paginate(products), nil
 }
 
-// Returns all products matching a given name
-func getProductByName(params map[string]interface{}) (interface{}, error) {
+// Returns the product with a given name, falling back to case-insensitive and fuzzy
+// matching over the catalog when there is no exact match (see fuzzy.go)
+func getProductByName(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 	name, ok := params["name"].(string)
 	if !ok || name == "" {
 		return nil, fmt.Errorf("missing or invalid 'name' argument")
 	}
-	url := productServiceBaseURL + "/products/" + name
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
+	url := productServiceBaseURL + "/products/" + neturl.PathEscape(name)
+	var result interface{}
+	_, err := getJSON(ctx, "/products/{name}", url, &result)
+	if err == nil {
+		return result, nil
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("product service returned status %d", resp.StatusCode)
+	if !isNotFound(err) {
+		return nil, err
 	}
-	var result interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+
+	products, err := fetchProducts(ctx, "/products", productServiceBaseURL+"/products")
+	if err != nil {
 		return nil, err
 	}
-	return result, nil
+	return resolveProductName(products, name)
 }
 
 // business logic implementations
-func deleteProducts(params map[string]interface{}) (interface{}, error) {
+func deleteProducts(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	if err := refuseAtomicDelete(params); err != nil {
+		return nil, err
+	}
+	ids, err := stringListParam(params, "ids")
+	if err != nil {
+		return nil, err
+	}
+	defer lockProducts(ids)()
+	auditLockedProducts(ctx, ids)
 	url := productServiceBaseURL + "/products/delete"
-	return invokeMicroservice("POST", url, params)
+	body := copyParams(params)
+	delete(body, "atomic")
+	return invokeMicroservice(ctx, "POST", "/products/delete", url, body)
 }
 
-// Searches, filters, and sorts products with optional category/segment/name filters
-func searchProducts(params map[string]interface{}) (interface{}, error) {
-	// Fetch all products from backend
-	url := productServiceBaseURL + "/products"
-	resp, err := http.Get(url)
+// Searches, filters, and sorts products with an optional full-text query, category/segment/name
+// filters, a filter expression and a multi-key sort spec
+func searchProducts(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	// Parse the filter expression and sort spec before calling the backend (see filter.go)
+	filterExpr, _ := params["filter"].(string)
+	filter, err := parseFilter(filterExpr)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid 'filter' argument: %v", err)
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("product service returned status %d", resp.StatusCode)
+	var sortKeys []sortKey
+	if spec, ok := params["sort"]; ok {
+		if sortKeys, err = parseSortSpec(spec); err != nil {
+			return nil, err
+		}
 	}
-	var products []map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&products); err != nil {
+	page, err := parsePageRequest("search_products", params)
+	if err != nil {
 		return nil, err
 	}
 
-	// Filter by category
-	if category, ok := params["category"].(string); ok && category != "" {
-		var filtered []map[string]interface{}
-		for _, p := range products {
-			if cat, ok := p["category"].(string); ok && strings.EqualFold(cat, category) {
-				filtered = append(filtered, p)
-			}
-		}
-		products = filtered
+	// Use the in-process catalog snapshot when it is current, otherwise fetch all products
+	// from backend (see searchindex.go)
+	index := catalogSearch.snapshot()
+	var products []map[string]interface{}
+	if index != nil {
+		products = index.allProducts()
+	} else if products, err = fetchProducts(ctx, "/products", productServiceBaseURL+"/products"); err != nil {
+		return nil, err
 	}
 
-	// Filter by segment
-	if segment, ok := params["segment"].(string); ok && segment != "" {
-		var filtered []map[string]interface{}
-		for _, p := range products {
-			if seg, ok := p["segment"].(string); ok && strings.EqualFold(seg, segment) {
-				filtered = append(filtered, p)
-			}
+	// Full-text query: ranked matches, best first
+	query, _ := params["query"].(string)
+	if strings.TrimSpace(query) != "" {
+		if index == nil {
+			index = buildSearchIndex(products)
 		}
-		products = filtered
+		products = index.search(query)
 	}
 
-	// Filter by name (partial, case-insensitive)
-	if name, ok := params["name"].(string); ok && name != "" {
-		var filtered []map[string]interface{}
-		for _, p := range products {
-			if n, ok := p["name"].(string); ok && strings.Contains(strings.ToLower(n), strings.ToLower(name)) {
-				filtered = append(filtered, p)
-			}
-		}
-		products = filtered
-	}
+	products = filterProducts(products, params)
+	products = applyFilter(products, filter)
 
 	// Sort
+	if sortKeys != nil {
+		sortProducts(products, sortKeys)
+		return page.paginate(limitProducts(products, params)), nil
+	}
+	if _, explicit := params["sort_by"]; strings.TrimSpace(query) != "" && !explicit {
+		// keep the relevance order
+		return page.paginate(limitProducts(products, params)), nil
+	}
 	sortBy := "price"
 	if sb, ok := params["sort_by"].(string); ok && sb != "" {
 		sortBy = sb
@@ -264,15 +377,57 @@ func searchProducts(params map[string]interface{}) (interface{}, error) {
 		return pi > pj
 	})
 
-	// Limit results
+	return page.paginate(limitProducts(products, params)), nil
+}
+
+// limitProducts truncates the result to the optional 'limit' argument
+func limitProducts(products []map[string]interface{}, params map[string]interface{}) []map[string]interface{} {
 	if limitVal, ok := params["limit"]; ok {
 		limit := int(toFloat64(limitVal))
 		if limit > 0 && limit < len(products) {
 			products = products[:limit]
 		}
 	}
+	return products
+}
 
-	return products, nil
+// filterProducts applies the optional category, segment (exact, case-insensitive) and
+// name (partial, case-insensitive) filters from params
+func filterProducts(products []map[string]interface{}, params map[string]interface{}) []map[string]interface{} {
+	// Filter by category
+	if category, ok := params["category"].(string); ok && category != "" {
+		var filtered []map[string]interface{}
+		for _, p := range products {
+			if cat, ok := p["category"].(string); ok && strings.EqualFold(cat, category) {
+				filtered = append(filtered, p)
+			}
+		}
+		products = filtered
+	}
+
+	// Filter by segment
+	if segment, ok := params["segment"].(string); ok && segment != "" {
+		var filtered []map[string]interface{}
+		for _, p := range products {
+			if seg, ok := p["segment"].(string); ok && strings.EqualFold(seg, segment) {
+				filtered = append(filtered, p)
+			}
+		}
+		products = filtered
+	}
+
+	// Filter by name (partial, case-insensitive)
+	if name, ok := params["name"].(string); ok && name != "" {
+		var filtered []map[string]interface{}
+		for _, p := range products {
+			if n, ok := p["name"].(string); ok && strings.Contains(strings.ToLower(n), strings.ToLower(name)) {
+				filtered = append(filtered, p)
+			}
+		}
+		products = filtered
+	}
+
+	return products
 }
 
 // toFloat64 converts a numeric interface value to float64
@@ -294,96 +449,303 @@ func toFloat64(v interface{}) float64 {
 	}
 }
 
-func createProduct(params map[string]interface{}) (interface{}, error) {
+func createProduct(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	if err := validateNewProduct(params); err != nil {
+		return nil, err
+	}
 	url := productServiceBaseURL + "/products"
-	return invokeMicroservice("POST", url, params)
+	return invokeMicroservice(ctx, "POST", "/products", url, params)
 }
 
-func getProduct(params map[string]interface{}) (interface{}, error) {
+func getProduct(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 	id, ok := params["id"].(string)
 	if !ok || id == "" {
 		return nil, fmt.Errorf("missing or invalid product id")
 	}
+	asOf, err := asOfParam(params)
+	if err != nil {
+		return nil, err
+	}
+	if !asOf.IsZero() {
+		return getProductAsOf(id, asOf)
+	}
 	url := fmt.Sprintf(productServiceBaseURL+"/products/%s", id)
-	return invokeMicroservice("GET", url, nil)
+	resp, err := catalogCache.get(ctx, "/products/{id}", url)
+	if err != nil {
+		return nil, err
+	}
+	result := decodeBackendBody(resp.Body)
+	// a cached response may predate later writes; only current states are versions
+	if p, ok := result.(map[string]interface{}); ok && !resp.Cached && p["id"] != nil {
+		catalogHistory.observe([]map[string]interface{}{p}, false)
+	}
+	return result, nil
 }
-func updateProduct(params map[string]interface{}) (interface{}, error) {
+func updateProduct(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	id, updateFields, err := buildProductUpdate(params)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := parseExpectation(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := productRules.checkWrite([]map[string]interface{}{updateFields}, false); err != nil {
+		return nil, err
+	}
+
+	defer lockProducts([]string{id})()
+	current, err := snapshotProducts(withFreshReads(ctx), []string{id})
+	if err != nil {
+		return nil, err
+	}
+	auditLockedSnapshot(ctx, []string{id}, current)
+	if exp != nil {
+		if err := expectationConflicts(current, map[string]*expectation{id: exp}); err != nil {
+			return nil, err
+		}
+	}
+	product, exists := current[id]
+	if !exists {
+		return nil, &backendStatusError{StatusCode: http.StatusNotFound}
+	}
+
+	body, changed := applyMergePatch(product, updateFields)
+	if len(changed) == 0 {
+		return map[string]interface{}{"product": product, "changed_fields": changed}, nil
+	}
+	updated, err := sendWrite(ctx, http.MethodPut, "/products/{id}", productServiceBaseURL+"/products/"+id, body)
+	if err != nil {
+		return nil, err
+	}
+	if updated["id"] == nil {
+		// the product service did not echo the product
+		updated = body
+	}
+	return map[string]interface{}{"product": updated, "changed_fields": changed}, nil
+}
+
+// productFields are the product fields update_product can set or clear
+var productFields = []string{"name", "category", "segment", "price"}
+
+// buildProductUpdate returns the product id and the JSON Merge Patch (RFC 7396) of an
+// update_product call: every product field present in params, where null clears the field
+func buildProductUpdate(params map[string]interface{}) (string, map[string]interface{}, error) {
 	id, ok := params["id"].(string)
 	if !ok || id == "" {
-		return nil, fmt.Errorf("missing or invalid product id")
-	}
-	// Only include fields that are present in params
-	updateFields := make(map[string]interface{})
-	updateFields["id"] = id
-	if name, ok := params["name"].(string); ok && name != "" {
-		updateFields["name"] = name
+		return "", nil, fmt.Errorf("missing or invalid product id")
+	}
+	patch := map[string]interface{}{"id": id}
+	for key, value := range params {
+		switch {
+		case key == "id" || slices.Contains(expectationKeys, key):
+			continue
+		case !slices.Contains(productFields, key):
+			return "", nil, fmt.Errorf("unknown product field '%s' (expected %s)", key, strings.Join(productFields, ", "))
+		case value == nil:
+			patch[key] = nil
+		case key == "price":
+			switch value.(type) {
+			case float64, float32, int, int64, json.Number:
+				patch[key] = toFloat64(value)
+			default:
+				return "", nil, fmt.Errorf("invalid 'price' argument: must be a number or null")
+			}
+		default:
+			s, ok := value.(string)
+			if !ok {
+				return "", nil, fmt.Errorf("invalid '%s' argument: must be a string or null", key)
+			}
+			patch[key] = s
+		}
 	}
-	if price, ok := params["price"].(float64); ok {
-		updateFields["price"] = price
+	if len(patch) == 1 {
+		return "", nil, fmt.Errorf("missing fields to update (any of %s)", strings.Join(productFields, ", "))
 	}
-	if category, ok := params["category"].(string); ok && category != "" {
-		updateFields["category"] = category
+	return id, patch, nil
+}
+
+// applyMergePatch applies an update_product patch to a product. It returns the PUT body,
+// the full product with cleared fields sent as null, and the fields whose value changes.
+func applyMergePatch(product, patch map[string]interface{}) (map[string]interface{}, []string) {
+	body := copyParams(product)
+	changed := []string{}
+	for key, value := range patch {
+		if key == "id" {
+			continue
+		}
+		current, present := product[key]
+		if value == nil && (!present || current == nil) {
+			continue
+		}
+		if present && reflect.DeepEqual(current, value) {
+			continue
+		}
+		body[key] = value
+		changed = append(changed, key)
 	}
-	url := fmt.Sprintf(productServiceBaseURL+"/products/%s", id)
-	return invokeMicroservice("PUT", url, updateFields)
+	sort.Strings(changed)
+	return body, changed
 }
 
-func deleteProduct(params map[string]interface{}) (interface{}, error) {
+func deleteProduct(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 	id, ok := params["id"].(string)
 	if !ok || id == "" {
 		return nil, fmt.Errorf("missing or invalid product id")
 	}
+	defer lockProducts([]string{id})()
+	auditLockedProducts(ctx, []string{id})
 	url := fmt.Sprintf(productServiceBaseURL+"/products/%s", id)
-	return invokeMicroservice("DELETE", url, nil)
+	return invokeMicroservice(ctx, "DELETE", "/products/{id}", url, nil)
 }
 
-// TODO: add pagination support and use params to filter results
 
-func createMultipleProducts(params map[string]interface{}) (interface{}, error) {
+func createMultipleProducts(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	items, err := objectListParam(params, "products")
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range items {
+		if err := checkProductTypes(item); err != nil {
+			return nil, fmt.Errorf("'products[%d]': %v", i, err)
+		}
+	}
+	if err := productRules.checkWrite(items, true); err != nil {
+		return nil, err
+	}
+	atomic, err := atomicParam(params)
+	if err != nil {
+		return nil, err
+	}
+	if atomic {
+		return createProductsAtomically(ctx, items)
+	}
 	url := productServiceBaseURL + "/products/create-multiple"
-	return invokeMicroservice("POST", url, params)
+	body := copyParams(params)
+	delete(body, "atomic")
+	return invokeMicroservice(ctx, "POST", "/products/create-multiple", url, body)
 }
 
-func updateProducts(params map[string]interface{}) (interface{}, error) {
+func updateProducts(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	items, err := objectListParam(params, "products")
+	if err != nil {
+		return nil, err
+	}
+	atomic, err := atomicParam(params)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(items))
+	expectations := map[string]*expectation{}
+	cleaned := make([]interface{}, 0, len(items))
+	updates := make([]map[string]interface{}, 0, len(items))
+	for i, item := range items {
+		id, _ := item["id"].(string)
+		if id == "" {
+			return nil, fmt.Errorf("'products[%d]': missing or invalid product id", i)
+		}
+		exp, err := parseExpectation(item)
+		if err != nil {
+			return nil, fmt.Errorf("'products[%d]': %v", i, err)
+		}
+		if exp != nil {
+			expectations[id] = exp
+		}
+		ids = append(ids, id)
+		update := withoutExpectations(item)
+		cleaned = append(cleaned, update)
+		updates = append(updates, update)
+	}
+	if err := productRules.checkWrite(updates, false); err != nil {
+		return nil, err
+	}
+
+	defer lockProducts(ids)()
+	auditLockedProducts(ctx, ids)
+	if len(expectations) > 0 {
+		if err := checkExpectations(ctx, expectations); err != nil {
+			return nil, err
+		}
+	}
+	if atomic {
+		return updateProductsAtomically(ctx, ids, updates)
+	}
 	url := productServiceBaseURL + "/products/update"
-	return invokeMicroservice("POST", url, params)
+	body := copyParams(params)
+	body["products"] = cleaned
+	delete(body, "atomic")
+	return invokeMicroservice(ctx, "POST", "/products/update", url, body)
 }
 
 // helper to make HTTP requests to microservice and parse response
-func invokeMicroservice(method, url string, params map[string]interface{}) (interface{}, error) {
-	var reqBody *bytes.Buffer
+func invokeMicroservice(ctx context.Context, method, route, url string, params map[string]interface{}) (interface{}, error) {
+	var body []byte
 	if params != nil {
-		body, err := json.Marshal(params)
+		var err error
+		body, err = json.Marshal(params)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal params: %v", err)
 		}
-		reqBody = bytes.NewBuffer(body)
-	} else {
-		reqBody = &bytes.Buffer{}
 	}
 
-	req, err := http.NewRequest(method, url, reqBody)
+	var resp *backendResponse
+	var err error
+	if method == http.MethodGet {
+		resp, err = catalogCache.get(ctx, route, url)
+	} else {
+		resp, err = callBackend(ctx, method, route, url, body, nil)
+		recordBackendWrite(ctx, method, route, resp, err)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		return nil, err
+	}
+	return decodeBackendBody(resp.Body), nil
+}
+
+// decodeBackendBody decodes a JSON response body, or wraps a non-JSON one as raw text
+func decodeBackendBody(body []byte) interface{} {
+	var result interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return map[string]string{"response": string(body)}
 	}
-	req.Header.Set("Content-Type", "application/json")
+	return result
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// getJSON performs a GET against the microservice and decodes a 200 response into out;
+// cached reports whether the response came from the read cache
+func getJSON(ctx context.Context, route, url string, out interface{}) (cached bool, err error) {
+	resp, err := catalogCache.get(ctx, route, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call microservice: %v", err)
+		return false, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, &backendStatusError{StatusCode: resp.StatusCode}
 	}
-	defer resp.Body.Close()
+	return resp.Cached, json.Unmarshal(resp.Body, out)
+}
 
-	respBody, err := io.ReadAll(resp.Body)
+// fetchProduct returns a single product by id
+func fetchProduct(ctx context.Context, id string) (map[string]interface{}, error) {
+	var product map[string]interface{}
+	cached, err := getJSON(ctx, "/products/{id}", productServiceBaseURL+"/products/"+id, &product)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read microservice response: %v", err)
+		return nil, err
+	}
+	if !cached {
+		catalogHistory.observe([]map[string]interface{}{product}, false)
 	}
+	return product, nil
+}
 
-	var result interface{}
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		// If not JSON, return raw text
-		return map[string]string{"response": string(respBody)}, nil
+// fetchProducts returns the product list served by a microservice collection route
+func fetchProducts(ctx context.Context, route, url string) ([]map[string]interface{}, error) {
+	var products []map[string]interface{}
+	cached, err := getJSON(ctx, route, url, &products)
+	if err != nil {
+		return nil, err
 	}
-	return result, nil
+	if !cached {
+		catalogHistory.observe(products, route == "/products")
+	}
+	return products, nil
 }
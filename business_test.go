@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestUpdateProductMergePatch(t *testing.T) {
+	fake := newFakeProductService(t, testProduct("1", "Laptop5", "Electronics", "Laptops", 999))
+	ctx := context.Background()
+
+	result, err := executeToolCall(ctx, "update_product", map[string]interface{}{"id": "1", "segment": "Gaming", "price": 0, "name": "Laptop5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := result.(map[string]interface{})
+	if changed := r["changed_fields"].([]string); !reflect.DeepEqual(changed, []string{"price", "segment"}) {
+		t.Errorf("Expected price and segment to change, got %v", changed)
+	}
+	if p := fake.get("1"); p["segment"] != "Gaming" || p["price"] != 0.0 {
+		t.Errorf("Expected segment and an integer zero price to be written, got %v", p)
+	}
+	if product := r["product"].(map[string]interface{}); product["segment"] != "Gaming" {
+		t.Errorf("Expected the updated product in the result, got %v", product)
+	}
+
+	// null clears a field
+	if _, err := executeToolCall(ctx, "update_product", map[string]interface{}{"id": "1", "segment": nil}); err != nil {
+		t.Fatal(err)
+	}
+	if p := fake.get("1"); p["segment"] != nil || p["name"] != "Laptop5" {
+		t.Errorf("Expected only the segment to be cleared, got %v", p)
+	}
+
+	writes := fake.writeCount()
+	result, err = executeToolCall(ctx, "update_product", map[string]interface{}{"id": "1", "category": "Electronics"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed := result.(map[string]interface{})["changed_fields"].([]string); len(changed) != 0 || fake.writeCount() != writes {
+		t.Errorf("Expected an unchanged product not to be written, got %v and %d writes", changed, fake.writeCount()-writes)
+	}
+
+	for _, params := range []map[string]interface{}{
+		{"id": "1", "colour": "red"},
+		{"id": "1", "price": "cheap"},
+		{"id": "1"},
+	} {
+		if _, err := executeToolCall(ctx, "update_product", params); err == nil {
+			t.Errorf("Expected an error for %v", params)
+		}
+	}
+	if _, err := executeToolCall(ctx, "update_product", map[string]interface{}{"id": "9", "price": 1.0}); !isNotFound(err) {
+		t.Errorf("Expected not found for an unknown product, got %v", err)
+	}
+}
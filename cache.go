@@ -0,0 +1,212 @@
+// Package main - cache.go
+//
+// This file implements the read-through cache in front of the product service's GET routes.
+//
+// Key Responsibilities:
+//   - Serve repeated reads (list_products, search_products, get_products_by_category,
+//     get_products_by_segment, get_product, ...) from memory for cache.ttl
+//   - Revalidate expired entries with If-None-Match when the product service sent an ETag,
+//     so an unchanged catalog costs a 304 instead of a full response
+//   - Coalesce concurrent identical reads into a single backend call, cancelled once every
+//     caller waiting for it has gone away
+//   - Drop every entry when a mutating tool runs through this server
+//   - Let callers that need the current state (e.g. the audit log) bypass cached entries
+//
+// Caching rules:
+//   - Only 200 responses to GET requests are stored, keyed by URL; errors and 404s are not
+//   - A cache.ttl of 0 disables storage; concurrent identical reads are still coalesced
+//   - Writes made directly against the product service are visible after at most cache.ttl
+//     (immediately if the product service answers the revalidation with a new ETag)
+//
+// Consistency with writes:
+//
+//	Invalidation bumps a generation counter. A read that started before a write neither
+//	stores its response nor is joined by reads that start after the write, so a tool call
+//	never sees data older than a write that completed before it started.
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultCacheMaxEntries = 1000
+
+// cacheEntry is a stored 200 response
+type cacheEntry struct {
+	body    []byte
+	etag    string
+	expires time.Time
+}
+
+// cacheCall is a backend read shared by concurrent identical requests
+type cacheCall struct {
+	done    chan struct{}
+	resp    *backendResponse
+	err     error
+	waiters int // callers still waiting, guarded by readCache.mu
+	cancel  context.CancelFunc
+}
+
+// readCache caches and coalesces GET requests to the product service
+type readCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*cacheEntry
+	inflight   map[string]*cacheCall
+	generation int64
+	now        func() time.Time
+}
+
+// catalogCache starts disabled (ttl 0) and is configured from Config.Cache in main()
+var catalogCache = newReadCache(0, defaultCacheMaxEntries)
+
+func newReadCache(ttl time.Duration, maxEntries int) *readCache {
+	return &readCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    map[string]*cacheEntry{},
+		inflight:   map[string]*cacheCall{},
+		now:        time.Now,
+	}
+}
+
+// configure applies the cache settings and drops every entry
+func (c *readCache) configure(cfg CacheConfig) {
+	c.mu.Lock()
+	c.ttl = cfg.TTL.Duration
+	c.maxEntries = cfg.MaxEntries
+	c.mu.Unlock()
+	c.invalidate()
+}
+
+type freshReadsContextKey struct{}
+
+// withFreshReads returns a context whose reads skip cached entries and in-flight calls
+// and go to the product service (with If-None-Match when possible)
+func withFreshReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, freshReadsContextKey{}, true)
+}
+
+// get returns the response to GET url from the cache, a shared in-flight request or the
+// product service, in that order
+func (c *readCache) get(ctx context.Context, route, url string) (*backendResponse, error) {
+	fresh, _ := ctx.Value(freshReadsContextKey{}).(bool)
+
+	c.mu.Lock()
+	entry := c.entries[url]
+	if entry != nil && !fresh && c.now().Before(entry.expires) {
+		c.mu.Unlock()
+		backendCacheRequests.WithLabelValues(route, "hit").Inc()
+		return &backendResponse{StatusCode: http.StatusOK, Body: entry.body, Cached: true}, nil
+	}
+	if call, ok := c.inflight[url]; ok && !fresh {
+		call.waiters++
+		c.mu.Unlock()
+		backendCacheRequests.WithLabelValues(route, "coalesced").Inc()
+		return c.wait(ctx, url, call)
+	}
+
+	// the call is shared, so one caller going away must not fail the others; it is
+	// cancelled when the last one leaves
+	flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	call := &cacheCall{done: make(chan struct{}), waiters: 1, cancel: cancel}
+	if !fresh {
+		c.inflight[url] = call
+	}
+	generation := c.generation
+	var header http.Header
+	if entry != nil && entry.etag != "" {
+		header = http.Header{"If-None-Match": {entry.etag}}
+	}
+	c.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		resp, err := callBackend(flightCtx, http.MethodGet, route, url, nil, header)
+		result := "miss"
+		if err == nil && resp.StatusCode == http.StatusNotModified && header != nil {
+			resp = &backendResponse{StatusCode: http.StatusOK, Header: resp.Header, Body: entry.body}
+			result = "revalidated"
+		}
+		backendCacheRequests.WithLabelValues(route, result).Inc()
+
+		c.mu.Lock()
+		if c.inflight[url] == call {
+			delete(c.inflight, url)
+		}
+		if err == nil && resp.StatusCode == http.StatusOK && generation == c.generation {
+			c.store(url, resp)
+		}
+		c.mu.Unlock()
+
+		call.resp, call.err = resp, err
+		close(call.done)
+	}()
+	return c.wait(ctx, url, call)
+}
+
+// store must be called with c.mu held
+func (c *readCache) store(url string, resp *backendResponse) {
+	if c.ttl <= 0 {
+		return
+	}
+	if _, ok := c.entries[url]; !ok && len(c.entries) >= c.maxEntries {
+		now := c.now()
+		for key, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, key)
+			}
+		}
+		// still full: evict an arbitrary entry
+		for key := range c.entries {
+			if len(c.entries) < c.maxEntries {
+				break
+			}
+			delete(c.entries, key)
+		}
+	}
+	c.entries[url] = &cacheEntry{
+		body:    resp.Body,
+		etag:    resp.Header.Get("ETag"),
+		expires: c.now().Add(c.ttl),
+	}
+}
+
+// invalidate drops every entry and detaches in-flight reads from later callers
+func (c *readCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.entries = map[string]*cacheEntry{}
+	c.inflight = map[string]*cacheCall{}
+}
+
+// wait returns the result of a shared call, or leaves it when ctx ends first
+func (c *readCache) wait(ctx context.Context, url string, call *cacheCall) (*backendResponse, error) {
+	select {
+	case <-call.done:
+		return call.resp, call.err
+	case <-ctx.Done():
+		c.leave(url, call)
+		return nil, ctx.Err()
+	}
+}
+
+// leave drops a waiter of a shared call and cancels the call when it was the last one; a
+// cancelled call is no longer joined by later reads
+func (c *readCache) leave(url string, call *cacheCall) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+	if c.inflight[url] == call {
+		delete(c.inflight, url)
+	}
+	call.cancel()
+}
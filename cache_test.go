@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// withCatalogCache enables the global cache for the duration of a test
+func withCatalogCache(t *testing.T, ttl time.Duration) {
+	t.Helper()
+	catalogCache.configure(CacheConfig{TTL: Duration{ttl}, MaxEntries: defaultCacheMaxEntries})
+	t.Cleanup(func() {
+		catalogCache.configure(CacheConfig{MaxEntries: defaultCacheMaxEntries})
+	})
+}
+
+func TestReadCacheHitsAndInvalidation(t *testing.T) {
+	fake := newFakeProductService(t, testProduct("1", "Office Chair", "Furniture", "Budget", 149))
+	withCatalogCache(t, time.Minute)
+
+	var gets atomic.Int64
+	countingURL := productServiceBaseURL
+	counter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+		}
+		r.URL.Host, r.URL.Scheme = "", ""
+		fake.serveHTTP(w, r)
+	}))
+	defer counter.Close()
+	productServiceBaseURL = counter.URL
+	defer func() { productServiceBaseURL = countingURL }()
+
+	for i := 0; i < 3; i++ {
+		if _, err := executeToolCall(context.Background(), "list_products", map[string]interface{}{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := gets.Load(); n != 1 {
+		t.Fatalf("Expected repeated reads to be served from the cache, got %d backend GETs", n)
+	}
+
+	if _, err := executeToolCall(context.Background(), "create_product", map[string]interface{}{"name": "Desk", "category": "Furniture", "price": 299.0}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := executeToolCall(context.Background(), "list_products", map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page := result.(productPage); page.Total != 2 {
+		t.Errorf("Expected the new product after a write, got %d products", page.Total)
+	}
+}
+
+func TestReadCacheRevalidatesWithETag(t *testing.T) {
+	var gets, notModified atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gets.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fakeWriteJSON(w, http.StatusOK, []map[string]interface{}{testProduct("1", "Office Chair", "Furniture", "Budget", 149)})
+	}))
+	defer srv.Close()
+
+	c := newReadCache(time.Minute, 10)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		resp, err := c.get(context.Background(), "/products", srv.URL+"/products")
+		if err != nil || resp.StatusCode != http.StatusOK || len(resp.Body) == 0 {
+			t.Fatalf("Unexpected response %v, %v", resp, err)
+		}
+	}
+	if gets.Load() != 1 {
+		t.Fatalf("Expected one backend GET before expiry, got %d", gets.Load())
+	}
+
+	now = now.Add(2 * time.Minute)
+	resp, err := c.get(context.Background(), "/products", srv.URL+"/products")
+	if err != nil || resp.StatusCode != http.StatusOK || len(resp.Body) == 0 {
+		t.Fatalf("Expected the cached body after a 304, got %v, %v", resp, err)
+	}
+	if notModified.Load() != 1 {
+		t.Errorf("Expected an If-None-Match revalidation, got %d", notModified.Load())
+	}
+}
+
+func TestReadCacheCoalescesConcurrentReads(t *testing.T) {
+	var gets atomic.Int64
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gets.Add(1)
+		<-release
+		fakeWriteJSON(w, http.StatusOK, []map[string]interface{}{})
+	}))
+	defer srv.Close()
+
+	// ttl 0: nothing is stored, but concurrent reads still share one call
+	c := newReadCache(0, 10)
+	coalesced := map[string]string{"route": "/products", "result": "coalesced"}
+	before := counterValue(t, "backend_cache_requests_total", coalesced)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.get(context.Background(), "/products", srv.URL+"/products"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	// wait until the other readers have joined the in-flight call
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if counterValue(t, "backend_cache_requests_total", coalesced)-before >= 4 {
+			break
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	if n := gets.Load(); n != 1 {
+		t.Errorf("Expected concurrent reads to share one backend GET, got %d", n)
+	}
+	if len(c.entries) != 0 {
+		t.Errorf("Expected nothing stored with ttl 0, got %d entries", len(c.entries))
+	}
+}
+
+func TestReadCacheCancelsReadsNobodyWaitsFor(t *testing.T) {
+	release := make(chan struct{})
+	cancelled := make(chan struct{}, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+			fakeWriteJSON(w, http.StatusOK, []map[string]interface{}{})
+		case <-r.Context().Done():
+			cancelled <- struct{}{}
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	c := newReadCache(0, 10)
+
+	// the only caller goes away: the backend request is cancelled with it
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if _, err := c.get(ctx, "/products", srv.URL+"/products"); err != context.Canceled {
+		t.Errorf("Expected the read to end with its caller, got %v", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the backend request to be cancelled")
+	}
+
+	// the first caller goes away while another waits: the call goes on for the second
+	first, cancelFirst := context.WithCancel(context.Background())
+	firstDone := make(chan error, 1)
+	go func() {
+		_, err := c.get(first, "/products", srv.URL+"/products")
+		firstDone <- err
+	}()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		c.mu.Lock()
+		started := c.inflight[srv.URL+"/products"] != nil
+		c.mu.Unlock()
+		if started {
+			break
+		}
+	}
+	second, cancelSecond := context.WithCancel(context.Background())
+	defer cancelSecond()
+	secondDone := make(chan error, 1)
+	go func() {
+		_, err := c.get(second, "/products", srv.URL+"/products")
+		secondDone <- err
+	}()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		c.mu.Lock()
+		joined := c.inflight[srv.URL+"/products"] != nil && c.inflight[srv.URL+"/products"].waiters == 2
+		c.mu.Unlock()
+		if joined {
+			break
+		}
+	}
+	cancelFirst()
+	if err := <-firstDone; err != context.Canceled {
+		t.Errorf("Expected the first read to end with its caller, got %v", err)
+	}
+	select {
+	case <-cancelled:
+		t.Fatal("Expected the backend request to go on while a caller waits")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release <- struct{}{}
+	if err := <-secondDone; err != nil {
+		t.Errorf("Expected the second read to get the response, got %v", err)
+	}
+}
+
+// counterValue reads a counter from the metrics registry
+func counterValue(t *testing.T, name string, labels map[string]string) float64 {
+	families, err := metricsRegistry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0.0
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if want, ok := labels[label.GetName()]; ok && want != label.GetValue() {
+					continue metrics
+				}
+			}
+			total += m.GetCounter().GetValue()
+		}
+	}
+	return total
+}
@@ -0,0 +1,174 @@
+// Package main - concurrency.go
+//
+// This file implements optimistic concurrency for update_product and update_products.
+//
+// Key Responsibilities:
+//   - Read the 'expected' (previously read field values) and 'expected_version' arguments
+//   - Compare them with the current product state before writing
+//   - Refuse the whole write with a conflict error listing the current values on mismatch
+//   - Serialize writes made through this server to the same product: update_product,
+//     delete_product, the batch updates and deletes, merge_products, import_products and
+//     undo_change hold the product locks from their check to their last write. Creates
+//     get a new id from the product service and take no lock.
+//
+// Arguments (on update_product, and on each item of update_products):
+//   - expected:         {"price": 999, "name": "Laptop5"}: every listed field must still have
+//     this value
+//   - expected_version: must equal the product's 'version' field; only for product services
+//     that maintain one
+//
+// Calls without these arguments write unconditionally, as before. The product service has
+// no conditional write, so the check protects against concurrent writes made through this
+// server (they wait on a per-product lock) but not against writes made directly against the
+// product service between the check and the write.
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// expectationKeys are the arguments consumed by the concurrency check; they are never
+// sent to the product service
+var expectationKeys = []string{"expected", "expected_version"}
+
+// expectation is the product state an update is based on
+type expectation struct {
+	fields     map[string]interface{}
+	version    interface{}
+	hasVersion bool
+}
+
+// parseExpectation reads 'expected' and 'expected_version' from an update; nil means the
+// update is unconditional
+func parseExpectation(obj map[string]interface{}) (*expectation, error) {
+	var exp expectation
+	if v, present := obj["expected"]; present {
+		fields, ok := v.(map[string]interface{})
+		if !ok || len(fields) == 0 {
+			return nil, fmt.Errorf("invalid 'expected' argument: must be an object of field values")
+		}
+		exp.fields = fields
+	}
+	if v, present := obj["expected_version"]; present {
+		exp.version, exp.hasVersion = v, true
+	}
+	if exp.fields == nil && !exp.hasVersion {
+		return nil, nil
+	}
+	return &exp, nil
+}
+
+// mismatches returns the fields of current that differ from the expectation
+func (exp *expectation) mismatches(current map[string]interface{}) ([]string, error) {
+	var fields []string
+	if exp.hasVersion {
+		version, ok := current["version"]
+		if !ok {
+			return nil, fmt.Errorf("invalid 'expected_version' argument: the product service does not report product versions; pass the previously read field values as 'expected' instead")
+		}
+		if !sameValue(version, exp.version) {
+			fields = append(fields, "version")
+		}
+	}
+	for k, v := range exp.fields {
+		if !sameValue(current[k], v) {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+// sameValue compares JSON values, treating numbers and numeric strings from different
+// sources (e.g. version 3 and "3") as equal
+func sameValue(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b) || (a != nil && b != nil && fmt.Sprint(a) == fmt.Sprint(b))
+}
+
+// checkExpectations compares the current state of the products with the expectations and
+// returns a conflictError for every product that changed
+func checkExpectations(ctx context.Context, expectations map[string]*expectation) error {
+	ids := make([]string, 0, len(expectations))
+	for id := range expectations {
+		ids = append(ids, id)
+	}
+	current, err := snapshotProducts(withFreshReads(ctx), ids)
+	if err != nil {
+		return err
+	}
+	return expectationConflicts(current, expectations)
+}
+
+// expectationConflicts compares already read products with the expectations
+func expectationConflicts(current map[string]map[string]interface{}, expectations map[string]*expectation) error {
+	ids := make([]string, 0, len(expectations))
+	for id := range expectations {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	conflict := &conflictError{Message: "products changed since they were read; nothing was updated"}
+	for _, id := range ids {
+		product, exists := current[id]
+		if !exists {
+			conflict.Conflicts = append(conflict.Conflicts, productConflict{ProductID: id, Expected: expectations[id].fields})
+			continue
+		}
+		fields, err := expectations[id].mismatches(product)
+		if err != nil {
+			return err
+		}
+		if len(fields) > 0 {
+			expected := expectations[id].fields
+			if expectations[id].hasVersion {
+				expected = copyParams(expected)
+				expected["version"] = expectations[id].version
+			}
+			conflict.Conflicts = append(conflict.Conflicts, productConflict{ProductID: id, Expected: expected, Current: product, Fields: fields})
+		}
+	}
+	if len(conflict.Conflicts) > 0 {
+		return conflict
+	}
+	return nil
+}
+
+// withoutExpectations returns a copy of an update without the concurrency arguments
+func withoutExpectations(obj map[string]interface{}) map[string]interface{} {
+	cleaned := copyParams(obj)
+	for _, key := range expectationKeys {
+		delete(cleaned, key)
+	}
+	return cleaned
+}
+
+// productLockStripes serialize concurrent check-and-write sequences per product
+var productLockStripes [64]sync.Mutex
+
+// lockProducts locks the stripes of the given products in a fixed order and returns the
+// function that unlocks them
+func lockProducts(ids []string) func() {
+	stripes := map[int]bool{}
+	for _, id := range ids {
+		h := fnv.New32a()
+		h.Write([]byte(id))
+		stripes[int(h.Sum32()%uint32(len(productLockStripes)))] = true
+	}
+	order := make([]int, 0, len(stripes))
+	for i := range stripes {
+		order = append(order, i)
+	}
+	sort.Ints(order)
+	for _, i := range order {
+		productLockStripes[i].Lock()
+	}
+	return func() {
+		for j := len(order) - 1; j >= 0; j-- {
+			productLockStripes[order[j]].Unlock()
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpdateProductRefusesOnConflict(t *testing.T) {
+	fake := newFakeProductService(t, testProduct("1", "Laptop5", "Electronics", "Laptops", 999))
+	ctx := context.Background()
+
+	// someone else changed the price after it was read at 999
+	fake.mu.Lock()
+	fake.products["1"]["price"] = 949.0
+	fake.mu.Unlock()
+
+	_, err := executeToolCall(ctx, "update_product", map[string]interface{}{
+		"id": "1", "price": 899.0, "expected": map[string]interface{}{"price": 999.0, "name": "Laptop5"},
+	})
+	var conflict *conflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected a conflict error, got %v", err)
+	}
+	c := conflict.Conflicts[0]
+	if len(conflict.Conflicts) != 1 || c.Current["price"] != 949.0 || len(c.Fields) != 1 || c.Fields[0] != "price" {
+		t.Errorf("Unexpected conflicts %+v", conflict.Conflicts)
+	}
+	if p := fake.get("1"); p["price"] != 949.0 {
+		t.Errorf("Expected no write on conflict, got %v", p)
+	}
+
+	if _, err := executeToolCall(ctx, "update_product", map[string]interface{}{
+		"id": "1", "price": 899.0, "expected": map[string]interface{}{"price": 949.0},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	p := fake.get("1")
+	if p["price"] != 899.0 {
+		t.Errorf("Expected the update to be applied, got %v", p)
+	}
+	if _, sent := p["expected"]; sent {
+		t.Errorf("Expected the concurrency arguments not to be sent to the product service, got %v", p)
+	}
+
+	if _, err := executeToolCall(ctx, "update_product", map[string]interface{}{
+		"id": "1", "price": 799.0, "expected_version": 3.0,
+	}); err == nil || errors.As(err, &conflict) {
+		t.Errorf("Expected expected_version to be rejected for products without a version, got %v", err)
+	}
+}
+
+func TestUpdateProductsRefusesWholeBatchOnConflict(t *testing.T) {
+	fake := newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Laptops", 999),
+		testProduct("2", "Office Chair", "Furniture", "Budget", 149),
+	)
+	fake.products["2"]["version"] = 4.0
+	ctx := context.Background()
+
+	params := map[string]interface{}{"products": []interface{}{
+		map[string]interface{}{"id": "1", "price": 899.0, "expected": map[string]interface{}{"price": 999.0}},
+		map[string]interface{}{"id": "2", "price": 99.0, "expected_version": 3.0},
+	}}
+	_, err := executeToolCall(ctx, "update_products", params)
+	var conflict *conflictError
+	if !errors.As(err, &conflict) || len(conflict.Conflicts) != 1 || conflict.Conflicts[0].ProductID != "2" {
+		t.Fatalf("Expected a conflict on product 2, got %v", err)
+	}
+	if fake.writeCount() != 0 {
+		t.Errorf("Expected no write on conflict, got %d", fake.writeCount())
+	}
+
+	preview, err := executeToolCall(ctx, "update_products", map[string]interface{}{"products": params["products"], "dry_run": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan := preview.(mutationPlan); plan.Valid || plan.Changes[1].Error == "" {
+		t.Errorf("Expected the preview to report the conflict, got %+v", plan)
+	}
+
+	params["products"].([]interface{})[1].(map[string]interface{})["expected_version"] = "4"
+	if _, err := executeToolCall(ctx, "update_products", params); err != nil {
+		t.Fatal(err)
+	}
+	if p := fake.get("2"); p["price"] != 99.0 {
+		t.Errorf("Expected the batch to be applied, got %v", p)
+	}
+}
+
+func TestUpdateProductsRefusesItemsWithoutID(t *testing.T) {
+	fake := newFakeProductService(t, testProduct("1", "Laptop5", "Electronics", "Laptops", 999))
+	for _, item := range []map[string]interface{}{
+		{"price": 899.0},
+		{"id": 1.0, "price": 899.0},
+	} {
+		params := map[string]interface{}{"products": []interface{}{map[string]interface{}{"id": "1", "price": 899.0}, item}}
+		if _, err := executeToolCall(context.Background(), "update_products", params); err == nil || !strings.Contains(err.Error(), "products[1]") {
+			t.Errorf("Expected item %v to be refused, got %v", item, err)
+		}
+	}
+	if fake.writeCount() != 0 {
+		t.Errorf("Expected no write, got %d", fake.writeCount())
+	}
+}
+
+func TestSingleProductWritesWaitForTheProductLock(t *testing.T) {
+	fake := newFakeProductService(t, testProduct("1", "Laptop5", "Electronics", "Laptops", 999))
+	unlock := lockProducts([]string{"1"})
+	done := make(chan error, 1)
+	go func() {
+		_, err := executeToolCall(context.Background(), "delete_product", map[string]interface{}{"id": "1"})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Expected the delete to wait for the lock, returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if fake.get("1") == nil {
+		t.Fatal("Expected no write while the product is locked")
+	}
+	unlock()
+	if err := <-done; err != nil || fake.get("1") != nil {
+		t.Errorf("Expected the delete to go through after the lock was released, got %v", err)
+	}
+}
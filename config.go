@@ -0,0 +1,652 @@
+// Package main - config.go
+//
+// This file loads, validates and prints the server configuration.
+//
+// Key Responsibilities:
+//   - Read an optional YAML or JSON config file (JSON is parsed as YAML)
+//   - Apply environment variable overrides, then command-line flag overrides
+//   - Validate the effective configuration and report every problem at once
+//   - Render the effective configuration with secrets redacted (--print-config)
+//
+// Precedence (lowest to highest):
+//  1. Built-in defaults (defaultConfig)
+//  2. Config file (--config flag or MCP_CONFIG environment variable)
+//  3. Environment variables
+//  4. Command-line flags
+//
+// Environment Variables:
+//   - MCP_CONFIG:               Path to the config file
+//   - MICROSERVICE_URL:         Backend product service URL
+//   - PORT:                     Server port
+//   - MCP_HOST:                 Listen address (empty = all interfaces)
+//   - MCP_BACKEND_TIMEOUT:      Timeout for a single backend request (e.g. "30s")
+//   - MCP_SHUTDOWN_GRACE:       Time allowed for in-flight requests to finish on SIGTERM (e.g. "10s")
+//   - MCP_API_KEYS:             Comma-separated name=key pairs accepted in the X-API-Key header
+//   - MCP_CORS_ALLOWED_ORIGINS: Comma-separated list of allowed CORS origins
+//   - MCP_ENABLED_TOOLS:        Comma-separated list of enabled tools (empty = all)
+//   - MCP_LOG_LEVEL:            debug, info, warn or error
+//   - MCP_LOG_FORMAT:           text or json
+//   - MCP_REQUIRE_CONFIRMATION: Refuse confirmable tool calls from clients without elicitation (true/false)
+//   - MCP_CONFIRM_THRESHOLD:    Ask for confirmation of mutations touching more products than this (0 = off)
+//   - MCP_AUDIT_FILE:           Audit log file (JSONL, rotated); empty keeps recent records in memory
+//   - MCP_CACHE_TTL:            How long catalog reads are cached (e.g. "30s", 0 = no caching)
+//   - MCP_IDEMPOTENCY_WINDOW:   How long create results are remembered by idempotency_key (e.g. "24h", 0 = off)
+//   - MCP_VALIDATION_ENFORCE:   Refuse writes that break the catalog validation rules (true/false)
+//   - MCP_IMPORT_DIR:           Directory of product lists offered as import:// resources to import_products
+//   - MCP_SEARCH_INDEX:         Enable the in-process full-text search index (true/false)
+//   - MCP_SEARCH_REFRESH:       Search index refresh interval (e.g. "1m", 0 = only after writes)
+//   - OTEL_TRACES_EXPORTER:     none, otlp, stdout or file
+//   - OTEL_TRACES_FILE:         Output file for the 'file' trace exporter
+//
+// Example config file (YAML):
+//
+//	microservice_url: https://product-service.example.com
+//	port: "8080"
+//	timeouts:
+//	  backend: 30s
+//	  write: 120s
+//	  shutdown_grace: 10s
+//	auth:
+//	  api_keys:
+//	    copilot-agent: change-me
+//	cors:
+//	  allowed_origins: ["https://app.example.com"]
+//	  allow_credentials: true
+//	  max_age: 10m
+//	tools:
+//	  enabled: [list_products, search_products]
+//	logging:
+//	  level: info
+//	  format: json
+//	audit:
+//	  file: /var/log/mcp/audit.jsonl
+//	  max_size_mb: 10
+//	  max_backups: 5
+//	cache:
+//	  ttl: 30s
+//	  max_entries: 1000
+//	idempotency:
+//	  window: 24h
+//	validation:
+//	  enforce: true
+//	  required_fields: [name, category, segment, price]
+//	  categories: [Electronics, Furniture]
+//	  min_price: 0
+//	  max_price: 100000
+//	  name_pattern: '^\S.*\S$'
+//	search:
+//	  index: true
+//	  refresh_interval: 1m
+//	confirmation:
+//	  required: true
+//	  item_threshold: 10
+//	  timeout: 2m
+//	tracing:
+//	  exporter: otlp
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultMicroserviceURL = "https://product-service-256110662801.europe-west3.run.app"
+
+// Duration is a time.Duration that is written as a string such as "30s" in config files
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %v", value.Value, err)
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+type TimeoutsConfig struct {
+	Backend       Duration `yaml:"backend"`
+	Read          Duration `yaml:"read"`
+	ReadHeader    Duration `yaml:"read_header"`
+	Write         Duration `yaml:"write"`
+	Idle          Duration `yaml:"idle"`
+	ShutdownGrace Duration `yaml:"shutdown_grace"`
+}
+
+type AuthConfig struct {
+	// APIKeys maps a caller name to the key it sends in the X-API-Key header.
+	// Authentication is disabled when no keys are configured.
+	APIKeys map[string]string `yaml:"api_keys"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins   []string `yaml:"allowed_origins"`
+	AllowedMethods   []string `yaml:"allowed_methods"`
+	AllowedHeaders   []string `yaml:"allowed_headers"`
+	ExposedHeaders   []string `yaml:"exposed_headers"`
+	AllowCredentials bool     `yaml:"allow_credentials"`
+	MaxAge           Duration `yaml:"max_age"`
+}
+
+type ToolsConfig struct {
+	// Enabled lists the tools exposed by the server; empty means every tool
+	Enabled []string `yaml:"enabled"`
+}
+
+type AuditConfig struct {
+	// Enabled records every mutating tool call (see audit.go)
+	Enabled bool `yaml:"enabled"`
+	// File is the JSONL audit log; empty keeps the most recent records in memory only
+	File       string `yaml:"file"`
+	MaxSizeMB  int    `yaml:"max_size_mb"`
+	MaxBackups int    `yaml:"max_backups"`
+}
+
+type CacheConfig struct {
+	// TTL is how long a catalog read is served from memory; 0 disables caching
+	TTL        Duration `yaml:"ttl"`
+	MaxEntries int      `yaml:"max_entries"`
+}
+
+type IdempotencyConfig struct {
+	// Window is how long the result of a create is remembered under its idempotency_key;
+	// 0 disables idempotency keys
+	Window Duration `yaml:"window"`
+}
+
+type ValidationConfig struct {
+	// Enforce refuses create and update tool calls that break a rule (see validation.go);
+	// validate_catalog reports violations either way
+	Enforce        bool     `yaml:"enforce"`
+	RequiredFields []string `yaml:"required_fields"`
+	// Categories and Segments are the allowed values; empty allows any value
+	Categories  []string `yaml:"categories"`
+	Segments    []string `yaml:"segments"`
+	MinPrice    *float64 `yaml:"min_price"`
+	MaxPrice    *float64 `yaml:"max_price"`
+	NamePattern string   `yaml:"name_pattern"`
+}
+
+type ImportConfig struct {
+	// Directory holds product lists offered as import:// resources to import_products;
+	// empty disables importing from resources
+	Directory string `yaml:"directory"`
+	// MaxBytes limits the size of an import, inline or from a file
+	MaxBytes int64 `yaml:"max_bytes"`
+	// ChunkSize is the number of products per create-multiple request
+	ChunkSize int `yaml:"chunk_size"`
+}
+
+type SearchConfig struct {
+	// Index enables the in-process full-text index used by search_products
+	Index bool `yaml:"index"`
+	// RefreshInterval rebuilds the index periodically to pick up writes made directly
+	// against the product service; 0 rebuilds only after writes through this server
+	RefreshInterval Duration `yaml:"refresh_interval"`
+}
+
+type ConfirmationConfig struct {
+	// Required refuses confirmable tool calls from clients that cannot answer an
+	// elicitation; otherwise those calls proceed unconfirmed
+	Required bool `yaml:"required"`
+	// ItemThreshold asks for confirmation of any mutation touching more products than
+	// this; 0 limits confirmation to destructive tools
+	ItemThreshold int      `yaml:"item_threshold"`
+	Timeout       Duration `yaml:"timeout"`
+}
+
+type LoggingConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+type TransportConfig struct {
+	Type           string `yaml:"type"`
+	Host           string `yaml:"host"`
+	MaxHeaderBytes int    `yaml:"max_header_bytes"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		MicroserviceURL: defaultMicroserviceURL,
+		Port:            "8080",
+		Timeouts: TimeoutsConfig{
+			Backend:       Duration{30 * time.Second},
+			Read:          Duration{30 * time.Second},
+			ReadHeader:    Duration{10 * time.Second},
+			Write:         Duration{120 * time.Second},
+			Idle:          Duration{120 * time.Second},
+			ShutdownGrace: Duration{10 * time.Second},
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodOptions},
+			AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key", "Mcp-Session-Id", "Mcp-Protocol-Version"},
+			ExposedHeaders: []string{"Mcp-Session-Id"},
+			MaxAge:         Duration{10 * time.Minute},
+		},
+		Audit:       AuditConfig{Enabled: true, MaxSizeMB: 10, MaxBackups: 5},
+		Cache:       CacheConfig{TTL: Duration{30 * time.Second}, MaxEntries: defaultCacheMaxEntries},
+		Idempotency: IdempotencyConfig{Window: Duration{24 * time.Hour}},
+		Validation: ValidationConfig{
+			Enforce:        true,
+			RequiredFields: []string{"name", "category", "price"},
+			MinPrice:       new(float64),
+		},
+		Import:       ImportConfig{MaxBytes: defaultImportMaxBytes, ChunkSize: defaultImportChunkSize},
+		Search:       SearchConfig{Index: true, RefreshInterval: Duration{time.Minute}},
+		Confirmation: ConfirmationConfig{Timeout: Duration{2 * time.Minute}},
+		Logging:      LoggingConfig{Level: "info", Format: "text"},
+		Transport:    TransportConfig{Type: "http", MaxHeaderBytes: 64 << 10},
+		Tracing:      TracingConfig{Exporter: "none", File: "traces.json"},
+	}
+}
+
+// loadConfig builds the effective configuration from defaults, the config file, the
+// environment and command-line flags. printOnly reports whether --print-config was given.
+func loadConfig(args []string, getenv func(string) string) (config Config, printOnly bool, err error) {
+	fs := flag.NewFlagSet("ravi-mcp-server", flag.ContinueOnError)
+	configPath := fs.String("config", getenv("MCP_CONFIG"), "path to a YAML or JSON config file")
+	microserviceURL := fs.String("microservice-url", "", "backend product service URL")
+	port := fs.String("port", "", "server port")
+	host := fs.String("host", "", "listen address")
+	backendTimeout := fs.Duration("backend-timeout", 0, "timeout for a single backend request")
+	shutdownGrace := fs.Duration("shutdown-grace", 0, "time allowed for in-flight requests to finish on shutdown")
+	corsOrigins := fs.String("cors-allowed-origins", "", "comma-separated list of allowed CORS origins")
+	enabledTools := fs.String("enabled-tools", "", "comma-separated list of enabled tools")
+	logLevel := fs.String("log-level", "", "log level: debug, info, warn or error")
+	logFormat := fs.String("log-format", "", "log format: text or json")
+	traceExporter := fs.String("trace-exporter", "", "trace exporter: none, otlp, stdout or file")
+	requireConfirmation := fs.Bool("require-confirmation", false, "refuse confirmable tool calls from clients without elicitation support")
+	fs.BoolVar(&printOnly, "print-config", false, "print the effective configuration (secrets redacted) and exit")
+	if err := fs.Parse(args); err != nil {
+		return Config{}, false, err
+	}
+
+	config = defaultConfig()
+
+	if *configPath != "" {
+		data, err := os.ReadFile(*configPath)
+		if err != nil {
+			return Config{}, false, fmt.Errorf("failed to read config file: %v", err)
+		}
+		// a misspelled key (e.g. cors.allowed_origin) is refused rather than silently ignored
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+			return Config{}, false, fmt.Errorf("failed to parse config file %s: %v", *configPath, err)
+		}
+	}
+
+	if err := applyEnv(&config, getenv); err != nil {
+		return Config{}, false, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "microservice-url":
+			config.MicroserviceURL = *microserviceURL
+		case "port":
+			config.Port = *port
+		case "host":
+			config.Transport.Host = *host
+		case "backend-timeout":
+			config.Timeouts.Backend = Duration{*backendTimeout}
+		case "shutdown-grace":
+			config.Timeouts.ShutdownGrace = Duration{*shutdownGrace}
+		case "cors-allowed-origins":
+			config.CORS.AllowedOrigins = splitList(*corsOrigins)
+		case "enabled-tools":
+			config.Tools.Enabled = splitList(*enabledTools)
+		case "log-level":
+			config.Logging.Level = *logLevel
+		case "log-format":
+			config.Logging.Format = *logFormat
+		case "trace-exporter":
+			config.Tracing.Exporter = *traceExporter
+		case "require-confirmation":
+			config.Confirmation.Required = *requireConfirmation
+		}
+	})
+
+	if err := config.validate(); err != nil {
+		return Config{}, false, err
+	}
+	return config, printOnly, nil
+}
+
+func applyEnv(config *Config, getenv func(string) string) error {
+	if v := getenv("MICROSERVICE_URL"); v != "" {
+		config.MicroserviceURL = v
+	}
+	if v := getenv("PORT"); v != "" {
+		config.Port = v
+	}
+	if v := getenv("MCP_HOST"); v != "" {
+		config.Transport.Host = v
+	}
+	if v := getenv("MCP_BACKEND_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid MCP_BACKEND_TIMEOUT %q: %v", v, err)
+		}
+		config.Timeouts.Backend = Duration{d}
+	}
+	if v := getenv("MCP_SHUTDOWN_GRACE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid MCP_SHUTDOWN_GRACE %q: %v", v, err)
+		}
+		config.Timeouts.ShutdownGrace = Duration{d}
+	}
+	if v := getenv("MCP_API_KEYS"); v != "" {
+		config.Auth.APIKeys = map[string]string{}
+		for _, pair := range splitList(v) {
+			name, key, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("invalid MCP_API_KEYS entry %q: expected name=key", pair)
+			}
+			config.Auth.APIKeys[strings.TrimSpace(name)] = strings.TrimSpace(key)
+		}
+	}
+	if v := getenv("MCP_CORS_ALLOWED_ORIGINS"); v != "" {
+		config.CORS.AllowedOrigins = splitList(v)
+	}
+	if v := getenv("MCP_ENABLED_TOOLS"); v != "" {
+		config.Tools.Enabled = splitList(v)
+	}
+	if v := getenv("MCP_LOG_LEVEL"); v != "" {
+		config.Logging.Level = v
+	}
+	if v := getenv("MCP_LOG_FORMAT"); v != "" {
+		config.Logging.Format = v
+	}
+	if v := getenv("MCP_AUDIT_FILE"); v != "" {
+		config.Audit.File = v
+	}
+	if v := getenv("MCP_CACHE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid MCP_CACHE_TTL %q: %v", v, err)
+		}
+		config.Cache.TTL = Duration{d}
+	}
+	if v := getenv("MCP_IDEMPOTENCY_WINDOW"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid MCP_IDEMPOTENCY_WINDOW %q: %v", v, err)
+		}
+		config.Idempotency.Window = Duration{d}
+	}
+	if v := getenv("MCP_VALIDATION_ENFORCE"); v != "" {
+		enforce, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid MCP_VALIDATION_ENFORCE %q: %v", v, err)
+		}
+		config.Validation.Enforce = enforce
+	}
+	if v := getenv("MCP_IMPORT_DIR"); v != "" {
+		config.Import.Directory = v
+	}
+	if v := getenv("MCP_SEARCH_INDEX"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid MCP_SEARCH_INDEX %q: %v", v, err)
+		}
+		config.Search.Index = enabled
+	}
+	if v := getenv("MCP_SEARCH_REFRESH"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid MCP_SEARCH_REFRESH %q: %v", v, err)
+		}
+		config.Search.RefreshInterval = Duration{d}
+	}
+	if v := getenv("MCP_REQUIRE_CONFIRMATION"); v != "" {
+		required, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid MCP_REQUIRE_CONFIRMATION %q: %v", v, err)
+		}
+		config.Confirmation.Required = required
+	}
+	if v := getenv("MCP_CONFIRM_THRESHOLD"); v != "" {
+		threshold, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid MCP_CONFIRM_THRESHOLD %q: %v", v, err)
+		}
+		config.Confirmation.ItemThreshold = threshold
+	}
+	if v := getenv("OTEL_TRACES_EXPORTER"); v != "" {
+		config.Tracing.Exporter = v
+	}
+	if v := getenv("OTEL_TRACES_FILE"); v != "" {
+		config.Tracing.File = v
+	}
+	return nil
+}
+
+// validate reports every invalid setting in a single error
+func (c Config) validate() error {
+	var problems []string
+
+	if u, err := url.Parse(c.MicroserviceURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems = append(problems, fmt.Sprintf("microservice_url: %q is not an absolute http(s) URL", c.MicroserviceURL))
+	}
+	if p, err := strconv.Atoi(c.Port); err != nil || p < 1 || p > 65535 {
+		problems = append(problems, fmt.Sprintf("port: %q is not a valid port number", c.Port))
+	}
+	if c.CORS.MaxAge.Duration < 0 {
+		problems = append(problems, "cors.max_age: must not be negative")
+	}
+	for name, d := range map[string]Duration{
+		"backend":        c.Timeouts.Backend,
+		"read":           c.Timeouts.Read,
+		"read_header":    c.Timeouts.ReadHeader,
+		"write":          c.Timeouts.Write,
+		"idle":           c.Timeouts.Idle,
+		"shutdown_grace": c.Timeouts.ShutdownGrace,
+	} {
+		if d.Duration <= 0 {
+			problems = append(problems, fmt.Sprintf("timeouts.%s: must be greater than zero", name))
+		}
+	}
+	if c.Timeouts.Write.Duration < c.Timeouts.Backend.Duration {
+		problems = append(problems, "timeouts.write: must not be shorter than timeouts.backend, or slow tool calls are cut off")
+	}
+	if c.Audit.MaxSizeMB <= 0 {
+		problems = append(problems, "audit.max_size_mb: must be greater than zero")
+	}
+	if c.Audit.MaxBackups < 0 {
+		problems = append(problems, "audit.max_backups: must not be negative")
+	}
+	if c.Cache.TTL.Duration < 0 {
+		problems = append(problems, "cache.ttl: must not be negative")
+	}
+	if c.Cache.MaxEntries <= 0 {
+		problems = append(problems, "cache.max_entries: must be greater than zero")
+	}
+	if c.Idempotency.Window.Duration < 0 {
+		problems = append(problems, "idempotency.window: must not be negative")
+	}
+	for _, field := range c.Validation.RequiredFields {
+		if !slices.Contains(validationFields, field) {
+			problems = append(problems, fmt.Sprintf("validation.required_fields: unknown field %q (expected one of %s)", field, strings.Join(validationFields, ", ")))
+		}
+	}
+	for name, values := range map[string][]string{"categories": c.Validation.Categories, "segments": c.Validation.Segments} {
+		seen := map[string]bool{}
+		for _, v := range values {
+			key := strings.ToLower(strings.TrimSpace(v))
+			if key == "" || seen[key] {
+				problems = append(problems, fmt.Sprintf("validation.%s: %q is blank or listed twice (values are compared case-insensitively)", name, v))
+			}
+			seen[key] = true
+		}
+	}
+	if c.Validation.MinPrice != nil && c.Validation.MaxPrice != nil && *c.Validation.MinPrice > *c.Validation.MaxPrice {
+		problems = append(problems, "validation.min_price: must not be greater than validation.max_price")
+	}
+	if _, err := regexp.Compile(c.Validation.NamePattern); err != nil {
+		problems = append(problems, fmt.Sprintf("validation.name_pattern: %v", err))
+	}
+	if c.Import.Directory != "" {
+		if info, err := os.Stat(c.Import.Directory); err != nil || !info.IsDir() {
+			problems = append(problems, fmt.Sprintf("import.directory: %q is not a directory", c.Import.Directory))
+		}
+	}
+	if c.Import.MaxBytes <= 0 {
+		problems = append(problems, "import.max_bytes: must be greater than zero")
+	}
+	if c.Import.ChunkSize < 1 || c.Import.ChunkSize > maxImportChunkSize {
+		problems = append(problems, fmt.Sprintf("import.chunk_size: must be between 1 and %d", maxImportChunkSize))
+	}
+	if c.Search.RefreshInterval.Duration < 0 {
+		problems = append(problems, "search.refresh_interval: must not be negative")
+	}
+	if c.Confirmation.ItemThreshold < 0 {
+		problems = append(problems, "confirmation.item_threshold: must not be negative")
+	}
+	if c.Confirmation.Timeout.Duration <= 0 {
+		problems = append(problems, "confirmation.timeout: must be greater than zero")
+	}
+	if c.Transport.MaxHeaderBytes <= 0 {
+		problems = append(problems, "transport.max_header_bytes: must be greater than zero")
+	}
+	for name, key := range c.Auth.APIKeys {
+		if name == "" || key == "" {
+			problems = append(problems, "auth.api_keys: caller names and keys must not be empty")
+			break
+		}
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			if c.CORS.AllowCredentials {
+				problems = append(problems, `cors.allow_credentials: cannot be true when allowed_origins contains "*"`)
+			}
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("cors.allowed_origins: %q is not a valid origin", origin))
+		}
+	}
+	for _, name := range c.Tools.Enabled {
+		if toolLabel(name) == "unknown" {
+			problems = append(problems, fmt.Sprintf("tools.enabled: unknown tool %q", name))
+		}
+	}
+	switch c.Logging.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("logging.level: %q must be one of debug, info, warn, error", c.Logging.Level))
+	}
+	switch c.Logging.Format {
+	case "text", "json":
+	default:
+		problems = append(problems, fmt.Sprintf("logging.format: %q must be text or json", c.Logging.Format))
+	}
+	if c.Transport.Type != "http" {
+		problems = append(problems, fmt.Sprintf("transport.type: %q is not supported (only http)", c.Transport.Type))
+	}
+	switch c.Tracing.Exporter {
+	case "none", "otlp", "stdout", "file":
+	default:
+		problems = append(problems, fmt.Sprintf("tracing.exporter: %q must be one of none, otlp, stdout, file", c.Tracing.Exporter))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New("invalid configuration:\n  - " + strings.Join(problems, "\n  - "))
+}
+
+// toolEnabled reports whether a tool is exposed by this server
+func (c Config) toolEnabled(name string) bool {
+	if len(c.Tools.Enabled) == 0 {
+		return true
+	}
+	for _, enabled := range c.Tools.Enabled {
+		if enabled == name {
+			return true
+		}
+	}
+	return false
+}
+
+// enabledTools returns the tool schemas exposed by this server, in tools.go order
+func (c Config) enabledTools() []ToolSchema {
+	var result []ToolSchema
+	for _, tool := range tools {
+		if c.toolEnabled(tool.Name) {
+			result = append(result, tool)
+		}
+	}
+	return result
+}
+
+// redacted returns a copy of the configuration that is safe to print
+func (c Config) redacted() Config {
+	if len(c.Auth.APIKeys) > 0 {
+		keys := make(map[string]string, len(c.Auth.APIKeys))
+		for name := range c.Auth.APIKeys {
+			keys[name] = "REDACTED"
+		}
+		c.Auth.APIKeys = keys
+	}
+	return c
+}
+
+// configureLogging routes slog and the standard logger to w with the configured level and
+// format. Per-request messages are logged at debug level, failures at warn or error.
+func configureLogging(c LoggingConfig, w io.Writer) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return fmt.Errorf("invalid logging level %q: %w", c.Level, err)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler = slog.NewTextHandler(w, opts)
+	if c.Format == "json" {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// printConfig writes the effective configuration as YAML with secrets redacted
+func printConfig(w io.Writer, c Config) error {
+	out, err := yaml.Marshal(c.redacted())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func envFrom(m map[string]string) func(string) string {
+	return func(key string) string { return m[key] }
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	config, printOnly, err := loadConfig(nil, envFrom(nil))
+	if err != nil {
+		t.Fatalf("Expected default config to be valid, got %v", err)
+	}
+	if printOnly {
+		t.Errorf("Expected printOnly to be false")
+	}
+	if config.MicroserviceURL != defaultMicroserviceURL {
+		t.Errorf("Expected MicroserviceURL to be '%s', got '%s'", defaultMicroserviceURL, config.MicroserviceURL)
+	}
+	if config.Port != "8080" {
+		t.Errorf("Expected Port to be '8080', got '%s'", config.Port)
+	}
+	if config.Timeouts.Backend.Duration != 30*time.Second {
+		t.Errorf("Expected backend timeout to be 30s, got %s", config.Timeouts.Backend)
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	file := `
+microservice_url: https://file.example.com
+port: "9000"
+timeouts:
+  backend: 5s
+logging:
+  level: debug
+`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	env := envFrom(map[string]string{
+		"MCP_CONFIG":       path,
+		"MICROSERVICE_URL": "https://env.example.com",
+		"PORT":             "9001",
+	})
+	config, _, err := loadConfig([]string{"--port", "9002"}, env)
+	if err != nil {
+		t.Fatalf("Expected config to load, got %v", err)
+	}
+
+	if config.MicroserviceURL != "https://env.example.com" {
+		t.Errorf("Expected env to override file, got '%s'", config.MicroserviceURL)
+	}
+	if config.Port != "9002" {
+		t.Errorf("Expected flag to override env, got '%s'", config.Port)
+	}
+	if config.Timeouts.Backend.Duration != 5*time.Second {
+		t.Errorf("Expected backend timeout from file to be 5s, got %s", config.Timeouts.Backend)
+	}
+	if config.Logging.Level != "debug" {
+		t.Errorf("Expected log level from file to be 'debug', got '%s'", config.Logging.Level)
+	}
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	args := []string{"--port", "http", "--enabled-tools", "list_products,drop_tables", "--log-level", "loud"}
+	_, _, err := loadConfig(args, envFrom(map[string]string{"MICROSERVICE_URL": "not a url"}))
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, want := range []string{"microservice_url", "port", `unknown tool "drop_tables"`, "logging.level"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s, got: %v", want, err)
+		}
+	}
+}
+
+func TestLoadConfigRefusesUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("cors:\n  allowed_origin: [\"https://app.example.com\"]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := loadConfig([]string{"--config", path}, envFrom(nil)); err == nil || !strings.Contains(err.Error(), "allowed_origin") {
+		t.Errorf("Expected the misspelled key to be refused, got %v", err)
+	}
+	// the documented example uses only known keys
+	if _, _, err := loadConfig([]string{"--config", "docs/configuration/server.yaml"}, envFrom(nil)); err != nil {
+		t.Errorf("Expected the example config to load, got %v", err)
+	}
+}
+
+func TestPrintConfigRedactsSecrets(t *testing.T) {
+	config, printOnly, err := loadConfig([]string{"--print-config"}, envFrom(map[string]string{
+		"MCP_API_KEYS": "agent=super-secret",
+	}))
+	if err != nil {
+		t.Fatalf("Expected config to load, got %v", err)
+	}
+	if !printOnly {
+		t.Errorf("Expected printOnly to be true")
+	}
+
+	var out bytes.Buffer
+	if err := printConfig(&out, config); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "super-secret") {
+		t.Errorf("Expected API key to be redacted, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "agent: REDACTED") {
+		t.Errorf("Expected redacted API key entry, got:\n%s", out.String())
+	}
+	if config.Auth.APIKeys["agent"] != "super-secret" {
+		t.Errorf("Expected redaction not to modify the effective config")
+	}
+}
+
+func TestConfigureLogging(t *testing.T) {
+	t.Cleanup(func() { configureLogging(defaultConfig().Logging, os.Stderr) })
+
+	var out bytes.Buffer
+	if err := configureLogging(LoggingConfig{Level: "warn", Format: "json"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	slog.Debug("Received JSON-RPC request", "method", "tools/list")
+	slog.Warn("Failed to refresh search index", "error", "timeout")
+	log.Printf("Failed to configure validation rules")
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"level":"WARN"`) || !strings.Contains(lines[0], `"error":"timeout"`) {
+		t.Errorf("Expected only the warning, as JSON, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := configureLogging(LoggingConfig{Level: "info", Format: "text"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	log.Printf("Starting MCP server on port %s", "8080")
+	if !strings.Contains(out.String(), `level=INFO msg="Starting MCP server on port 8080"`) {
+		t.Errorf("Expected the standard logger to go through the text handler, got:\n%s", out.String())
+	}
+
+	if err := configureLogging(LoggingConfig{Level: "verbose", Format: "text"}, &out); err == nil {
+		t.Error("Expected an unknown level to be refused")
+	}
+}
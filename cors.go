@@ -0,0 +1,53 @@
+// Package main - cors.go
+//
+// This file builds the CORS middleware shared by the MCP and health endpoints.
+//
+// Key Responsibilities:
+//   - Translate the cors section of the server config into github.com/rs/cors options
+//   - Answer preflight (OPTIONS) requests before authentication runs
+//   - Allow and expose the Mcp-Session-Id header used by MCP clients
+//   - Scope the allowed methods per route, so a preflight for /health cannot allow POST
+//
+// Defaults (see defaultConfig in config.go):
+//   - allowed_origins:   ["*"]
+//   - allowed_methods:   GET, POST, DELETE, OPTIONS
+//   - allowed_headers:   Content-Type, Authorization, X-API-Key, Mcp-Session-Id, Mcp-Protocol-Version
+//   - exposed_headers:   Mcp-Session-Id
+//   - allow_credentials: false (must stay false when allowed_origins contains "*")
+//   - max_age:           10m
+//
+// Each route allows the configured methods it actually serves: /health GET and HEAD,
+// /mcp POST and DELETE, /mcp/discover GET. A route with none of its methods configured
+// gets no CORS middleware at all, so cross-origin requests to it are refused.
+package main
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/rs/cors"
+)
+
+// newCORS creates the CORS middleware for a route serving methods; of those, only the ones
+// listed in the config's allowed_methods are allowed. If none is, the route is served
+// without CORS: rs/cors would fall back to its default methods for an empty list.
+func newCORS(c CORSConfig, methods ...string) func(http.Handler) http.Handler {
+	allowed := make([]string, 0, len(methods))
+	for _, m := range methods {
+		if slices.Contains(c.AllowedMethods, m) {
+			allowed = append(allowed, m)
+		}
+	}
+	if len(allowed) == 0 {
+		return func(h http.Handler) http.Handler { return h }
+	}
+	return cors.New(cors.Options{
+		AllowedOrigins:       c.AllowedOrigins,
+		AllowedMethods:       allowed,
+		AllowedHeaders:       c.AllowedHeaders,
+		ExposedHeaders:       c.ExposedHeaders,
+		AllowCredentials:     c.AllowCredentials,
+		MaxAge:               int(c.MaxAge.Seconds()),
+		OptionsSuccessStatus: http.StatusNoContent,
+	}).Handler
+}
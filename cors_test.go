@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func preflight(h http.Handler, method string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("Origin", "https://agent.example.com")
+	req.Header.Set("Access-Control-Request-Method", method)
+	req.Header.Set("Access-Control-Request-Headers", "content-type,mcp-session-id")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORSPreflightIsScopedPerRoute(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	config := defaultConfig().CORS
+	health := newCORS(config, http.MethodGet, http.MethodHead)(ok)
+	mcp := newCORS(config, http.MethodPost, http.MethodDelete)(ok)
+
+	for _, tc := range []struct {
+		name    string
+		handler http.Handler
+		method  string
+		allowed bool
+	}{
+		{"health GET", health, http.MethodGet, true},
+		{"health POST", health, http.MethodPost, false},
+		{"health DELETE", health, http.MethodDelete, false},
+		{"mcp POST", mcp, http.MethodPost, true},
+		{"mcp DELETE", mcp, http.MethodDelete, true},
+		{"mcp PUT", mcp, http.MethodPut, false},
+	} {
+		rec := preflight(tc.handler, tc.method)
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("%s: expected preflight status 204, got %d", tc.name, rec.Code)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin") != ""; got != tc.allowed {
+			t.Errorf("%s: expected allowed=%v, got headers %v", tc.name, tc.allowed, rec.Header())
+		}
+	}
+
+	// a method the route serves stays refused when the config does not allow it
+	config.AllowedMethods = []string{http.MethodPost, http.MethodOptions}
+	restricted := newCORS(config, http.MethodPost, http.MethodDelete)(ok)
+	if rec := preflight(restricted, http.MethodDelete); rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected DELETE to be refused when not configured, got headers %v", rec.Header())
+	}
+	if rec := preflight(restricted, http.MethodPost); rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Expected POST to be allowed, got headers %v", rec.Header())
+	}
+
+	// a route none of whose methods is configured gets no CORS, not the rs/cors defaults
+	refused := newCORS(config, http.MethodGet, http.MethodHead)(http.NotFoundHandler())
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodPost} {
+		if rec := preflight(refused, method); rec.Header().Get("Access-Control-Allow-Origin") != "" || rec.Code == http.StatusNoContent {
+			t.Errorf("Expected %s to be refused on a route without allowed methods, got %d %v", method, rec.Code, rec.Header())
+		}
+	}
+}
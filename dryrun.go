@@ -0,0 +1,342 @@
+// Package main - dryrun.go
+//
+// This file implements dry-run previews for every mutating tool.
+//
+// Key Responsibilities:
+//   - Identify the mutating tools that accept the 'dry_run' argument
+//   - Validate the request the same way the real call would be built
+//   - Resolve the affected products with read-only backend calls (GET)
+//   - Return the intended changes as a diff without calling any write endpoint
+//
+// Preview Format:
+//
+//	{
+//	  "dry_run": true,
+//	  "tool": "update_product",
+//	  "valid": true,
+//	  "changes": [
+//	    {"action": "update", "id": "12345",
+//	     "before": {...}, "after": {...},
+//	     "fields": {"price": {"from": 999, "to": 1099}}}
+//	  ]
+//	}
+//
+// Problems with individual items (validation failures, products that do not exist) are
+// reported in the item's "error" field and make "valid" false, so an agent can show the
+// full plan before asking the user for confirmation.
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// mutatingTools lists the tools that change the catalog
+var mutatingTools = map[string]bool{
+	"create_product":           true,
+	"update_product":           true,
+	"delete_product":           true,
+	"create_multiple_products": true,
+	"update_products":          true,
+	"delete_products":          true,
+	"adjust_prices":            true,
+	"undo_change":              true,
+	"merge_products":           true,
+	"import_products":          true,
+}
+
+type fieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// plannedChange describes what a mutating tool would do to a single product
+type plannedChange struct {
+	Action string                 `json:"action"`
+	ID     interface{}            `json:"id,omitempty"`
+	Before map[string]interface{} `json:"before,omitempty"`
+	After  map[string]interface{} `json:"after,omitempty"`
+	Fields map[string]fieldChange `json:"fields,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+type mutationPlan struct {
+	DryRun  bool            `json:"dry_run"`
+	Tool    string          `json:"tool"`
+	Valid   bool            `json:"valid"`
+	Changes []plannedChange `json:"changes"`
+}
+
+// previewMutation returns the changes a mutating tool call would make without applying them
+func previewMutation(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+	changes, err := planMutation(ctx, toolName, params)
+	if err != nil {
+		return nil, err
+	}
+	plan := mutationPlan{DryRun: true, Tool: toolName, Valid: true, Changes: changes}
+	for _, change := range changes {
+		if change.Error != "" {
+			plan.Valid = false
+		}
+	}
+	return plan, nil
+}
+
+// planMemo holds the plan of the tool call being handled, so that confirmation and the
+// audit log read the affected products once
+type planMemo struct {
+	done    bool
+	changes []plannedChange
+	err     error
+}
+
+type planMemoContextKey struct{}
+
+// withPlanMemo returns a context in which plannedChanges plans at most once
+func withPlanMemo(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(planMemoContextKey{}).(*planMemo); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, planMemoContextKey{}, &planMemo{})
+}
+
+// plannedChanges is planMutation with fresh reads, computed once per tool call when the
+// context carries a memo (see withPlanMemo)
+func plannedChanges(ctx context.Context, toolName string, params map[string]interface{}) ([]plannedChange, error) {
+	memo, _ := ctx.Value(planMemoContextKey{}).(*planMemo)
+	if memo != nil && memo.done {
+		return memo.changes, memo.err
+	}
+	changes, err := planMutation(withFreshReads(ctx), toolName, params)
+	if memo != nil {
+		memo.done, memo.changes, memo.err = true, changes, err
+	}
+	return changes, err
+}
+
+// planMutation resolves the per-product changes of a mutating tool call using only reads
+func planMutation(ctx context.Context, toolName string, params map[string]interface{}) ([]plannedChange, error) {
+	switch toolName {
+	case "create_product":
+		return []plannedChange{planCreate(params)}, nil
+	case "create_multiple_products":
+		items, err := objectListParam(params, "products")
+		if err != nil {
+			return nil, err
+		}
+		changes := make([]plannedChange, 0, len(items))
+		for _, item := range items {
+			changes = append(changes, planCreate(item))
+		}
+		return changes, nil
+	case "update_product":
+		id, fields, err := buildProductUpdate(params)
+		if err != nil {
+			return nil, err
+		}
+		exp, err := parseExpectation(params)
+		if err != nil {
+			return nil, err
+		}
+		return []plannedChange{checkPlannedExpectation(planUpdate(ctx, id, fields), exp)}, nil
+	case "update_products":
+		items, err := objectListParam(params, "products")
+		if err != nil {
+			return nil, err
+		}
+		changes := make([]plannedChange, 0, len(items))
+		for _, item := range items {
+			id, _ := item["id"].(string)
+			if id == "" {
+				changes = append(changes, plannedChange{Action: "update", After: item, Error: "missing or invalid product id"})
+				continue
+			}
+			exp, err := parseExpectation(item)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, checkPlannedExpectation(planUpdate(ctx, id, withoutExpectations(item)), exp))
+		}
+		return changes, nil
+	case "delete_product":
+		id, ok := params["id"].(string)
+		if !ok || id == "" {
+			return nil, fmt.Errorf("missing or invalid product id")
+		}
+		return []plannedChange{planDelete(ctx, id)}, nil
+	case "delete_products":
+		if err := refuseAtomicDelete(params); err != nil {
+			return nil, err
+		}
+		ids, err := stringListParam(params, "ids")
+		if err != nil {
+			return nil, err
+		}
+		changes := make([]plannedChange, 0, len(ids))
+		for _, id := range ids {
+			changes = append(changes, planDelete(ctx, id))
+		}
+		return changes, nil
+	case "adjust_prices":
+		priceChanges, _, err := planPriceAdjustment(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		changes := make([]plannedChange, 0, len(priceChanges))
+		for _, pc := range priceChanges {
+			change := plannedChange{Action: "update", ID: pc.ID}
+			if pc.Changed {
+				change.Fields = map[string]fieldChange{"price": {From: pc.OldPrice, To: pc.NewPrice}}
+			}
+			changes = append(changes, change)
+		}
+		return changes, nil
+	case "merge_products":
+		req, err := parseMergeRequest(params)
+		if err != nil {
+			return nil, err
+		}
+		return planMerge(ctx, req)
+	case "import_products":
+		plan, err := planImport(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		return plan.changes(), nil
+	case "undo_change":
+		_, steps, err := planUndo(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		changes := make([]plannedChange, 0, len(steps))
+		for _, step := range steps {
+			changes = append(changes, step.plannedChange)
+		}
+		return changes, nil
+	}
+	return nil, fmt.Errorf("tool %s does not support dry_run", toolName)
+}
+
+func planCreate(product map[string]interface{}) plannedChange {
+	change := plannedChange{Action: "create", After: product}
+	if err := validateNewProduct(product); err != nil {
+		change.Error = err.Error()
+	}
+	return change
+}
+
+func planUpdate(ctx context.Context, id string, fields map[string]interface{}) plannedChange {
+	change := plannedChange{Action: "update", ID: id}
+	current, err := fetchProduct(ctx, id)
+	if err != nil {
+		change.Error = fmt.Sprintf("cannot resolve product %s: %v", id, err)
+		return change
+	}
+	change.Before = current
+	if err := productRules.checkWrite([]map[string]interface{}{fields}, false); err != nil {
+		change.Error = err.Error()
+	}
+	change.After = make(map[string]interface{}, len(current))
+	for k, v := range current {
+		change.After[k] = v
+	}
+	change.Fields = map[string]fieldChange{}
+	for k, v := range fields {
+		if k == "id" {
+			continue
+		}
+		change.After[k] = v
+		if !reflect.DeepEqual(current[k], v) {
+			change.Fields[k] = fieldChange{From: current[k], To: v}
+		}
+	}
+	return change
+}
+
+// checkPlannedExpectation reports an update whose product no longer matches the expected
+// state as a conflict (see concurrency.go)
+func checkPlannedExpectation(change plannedChange, exp *expectation) plannedChange {
+	if exp == nil || change.Error != "" {
+		return change
+	}
+	fields, err := exp.mismatches(change.Before)
+	switch {
+	case err != nil:
+		change.Error = err.Error()
+	case len(fields) > 0:
+		change.Error = fmt.Sprintf("conflict: %v changed since it was read", fields)
+	}
+	return change
+}
+
+func planDelete(ctx context.Context, id string) plannedChange {
+	change := plannedChange{Action: "delete", ID: id}
+	current, err := fetchProduct(ctx, id)
+	if err != nil {
+		change.Error = fmt.Sprintf("cannot resolve product %s: %v", id, err)
+		return change
+	}
+	change.Before = current
+	return change
+}
+
+// validateNewProduct checks a product for create_product against the field types and the
+// catalog rules (validation.go)
+func validateNewProduct(product map[string]interface{}) error {
+	if err := checkProductTypes(product); err != nil {
+		return err
+	}
+	return productRules.checkWrite([]map[string]interface{}{product}, true)
+}
+
+// checkProductTypes checks the types of the product fields that are present
+func checkProductTypes(product map[string]interface{}) error {
+	for _, field := range []string{"name", "category", "segment"} {
+		if v, present := product[field]; present {
+			if _, ok := v.(string); !ok {
+				return fmt.Errorf("invalid '%s'", field)
+			}
+		}
+	}
+	if v, present := product["price"]; present {
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("invalid 'price'")
+		}
+	}
+	return nil
+}
+
+// objectListParam returns an array-of-objects argument such as 'products'
+func objectListParam(params map[string]interface{}, key string) ([]map[string]interface{}, error) {
+	raw, ok := params[key].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid '%s' argument", key)
+	}
+	items := make([]map[string]interface{}, 0, len(raw))
+	for i, item := range raw {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("'%s[%d]' must be an object", key, i)
+		}
+		items = append(items, obj)
+	}
+	return items, nil
+}
+
+// stringListParam returns an array-of-strings argument such as 'ids'
+func stringListParam(params map[string]interface{}, key string) ([]string, error) {
+	raw, ok := params[key].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid '%s' argument", key)
+	}
+	items := make([]string, 0, len(raw))
+	for i, item := range raw {
+		s, ok := item.(string)
+		if !ok || s == "" {
+			return nil, fmt.Errorf("'%s[%d]' must be a non-empty string", key, i)
+		}
+		items = append(items, s)
+	}
+	return items, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDryRunDoesNotWrite(t *testing.T) {
+	fake := newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Laptops", 999),
+		testProduct("2", "Chair", "Furniture", "Budget", 49),
+	)
+
+	calls := []struct {
+		tool   string
+		params map[string]interface{}
+	}{
+		{"create_product", map[string]interface{}{"name": "Phone", "category": "Electronics", "price": 599.0}},
+		{"update_product", map[string]interface{}{"id": "1", "price": 1099.0}},
+		{"delete_product", map[string]interface{}{"id": "2"}},
+		{"create_multiple_products", map[string]interface{}{"products": []interface{}{
+			map[string]interface{}{"name": "Desk", "category": "Furniture", "price": 199.0},
+		}}},
+		{"update_products", map[string]interface{}{"products": []interface{}{
+			map[string]interface{}{"id": "2", "segment": "Premium"},
+		}}},
+		{"delete_products", map[string]interface{}{"ids": []interface{}{"1", "2"}}},
+		{"adjust_prices", map[string]interface{}{"category": "Electronics", "operation": "percent", "value": 10.0}},
+	}
+	for _, call := range calls {
+		call.params["dry_run"] = true
+		result, err := executeToolCall(context.Background(), call.tool, call.params)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", call.tool, err)
+		}
+		plan, ok := result.(mutationPlan)
+		if !ok || !plan.DryRun || !plan.Valid {
+			t.Errorf("%s: expected a valid dry-run plan, got %#v", call.tool, result)
+		}
+	}
+
+	if fake.writeCount() != 0 {
+		t.Errorf("Expected no write calls to the product service, got %d", fake.writeCount())
+	}
+}
+
+func TestDryRunReportsDiffAndErrors(t *testing.T) {
+	newFakeProductService(t, testProduct("1", "Laptop5", "Electronics", "Laptops", 999))
+
+	result, err := executeToolCall(context.Background(), "update_product", map[string]interface{}{
+		"id": "1", "name": "Laptop5", "price": 1099.0, "dry_run": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	change := result.(mutationPlan).Changes[0]
+	if len(change.Fields) != 1 || change.Fields["price"].From != 999.0 || change.Fields["price"].To != 1099.0 {
+		t.Errorf("Expected only a price change from 999 to 1099, got %#v", change.Fields)
+	}
+
+	result, err = executeToolCall(context.Background(), "delete_products", map[string]interface{}{
+		"ids": []interface{}{"1", "missing"}, "dry_run": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := result.(mutationPlan)
+	if plan.Valid || plan.Changes[1].Error == "" {
+		t.Errorf("Expected unknown product to invalidate the plan, got %#v", plan)
+	}
+}
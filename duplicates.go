@@ -0,0 +1,406 @@
+// Package main - duplicates.go
+//
+// This file implements the find_duplicates and merge_products tools, which clean up
+// near-duplicate products created by different agents ("iPhone 17", "iphone17",
+// "IPhone 17 ").
+//
+// Key Responsibilities:
+//   - Score every pair of products by name similarity (see fuzzy.go), category and price
+//     proximity, and cluster pairs scoring at least min_score
+//   - Report each cluster with a confidence, a suggested product to keep and the values
+//     its products disagree on
+//   - Merge a cluster in one tool call: update the kept product with the chosen field
+//     values, then delete the others
+//
+// Confidence of a pair (0..1):
+//
+//	0.6 * name similarity + 0.2 * category match + 0.2 * price proximity, where category
+//	match is 1 for the same category (ignoring case and spacing), 0.5 if one product has
+//	none and 0 otherwise, and price proximity is 1 - |a - b| / max(a, b). Only pairs whose
+//	names score at least duplicateNameScore and contain the same numbers are considered
+//	("iPhone 16" and "iPhone 17" are different models). A cluster's confidence is the
+//	lowest score among the pairs that link it.
+//
+// Merging:
+//
+//	merge_products is audited as one change (an update and the deletes), so it can be
+//	reverted with undo_change. The kept product is updated first: if deleting the others
+//	fails, the catalog still holds every product.
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	defaultDuplicateMinScore = 0.85
+	duplicateNameScore       = 0.8
+	defaultDuplicateClusters = 50
+	maxDuplicateClusters     = 200
+)
+
+// mergeableFields are the product fields merge_products may set on the kept product
+var mergeableFields = []string{"name", "category", "segment", "price"}
+
+// duplicateCluster is a group of products that are probably the same product
+type duplicateCluster struct {
+	Confidence    float64                  `json:"confidence"`
+	SuggestedKeep interface{}              `json:"suggested_keep"`
+	Products      []map[string]interface{} `json:"products"`
+	Differences   map[string][]interface{} `json:"differences,omitempty"` // distinct values per field
+}
+
+// findDuplicates clusters near-duplicate products
+func findDuplicates(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	minScore := defaultDuplicateMinScore
+	if v, present := params["min_score"]; present {
+		s, ok := v.(float64)
+		if !ok || s <= 0 || s > 1 {
+			return nil, fmt.Errorf("invalid 'min_score' argument: must be a number in (0, 1]")
+		}
+		minScore = s
+	}
+	limit := defaultDuplicateClusters
+	if v, present := params["limit"]; present {
+		n, ok := v.(float64)
+		if !ok || n < 1 || n != math.Trunc(n) {
+			return nil, fmt.Errorf("invalid 'limit' argument: must be a positive integer")
+		}
+		limit = int(math.Min(n, maxDuplicateClusters))
+	}
+
+	products, err := fetchProducts(ctx, "/products", productServiceBaseURL+"/products")
+	if err != nil {
+		return nil, err
+	}
+	products = filterProducts(products, params)
+
+	clusters := clusterDuplicates(products, minScore)
+	total := len(clusters)
+	if len(clusters) > limit {
+		clusters = clusters[:limit]
+	}
+	return map[string]interface{}{
+		"clusters":  clusters,
+		"total":     total,
+		"min_score": minScore,
+		"products":  len(products),
+	}, nil
+}
+
+// clusterDuplicates links every pair of products scoring at least minScore and returns the
+// connected groups, most confident first
+func clusterDuplicates(products []map[string]interface{}, minScore float64) []duplicateCluster {
+	parent := make([]int, len(products))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	names := make([]string, len(products))
+	for i, p := range products {
+		names[i] = strings.Join(nameTokens(fmt.Sprint(p["name"])), "")
+	}
+	weakest := map[int]float64{}
+	type link struct {
+		a, b  int
+		score float64
+	}
+	var links []link
+	for i := range products {
+		for j := i + 1; j < len(products); j++ {
+			// names less than half as long as the other cannot reach duplicateNameScore
+			if shorter, longer := len(names[i]), len(names[j]); 2*min(shorter, longer) < max(shorter, longer) {
+				continue
+			}
+			if score := duplicateScore(products[i], products[j]); score >= minScore {
+				links = append(links, link{i, j, score})
+				parent[find(i)] = find(j)
+			}
+		}
+	}
+	for _, l := range links {
+		root := find(l.a)
+		if s, ok := weakest[root]; !ok || l.score < s {
+			weakest[root] = l.score
+		}
+	}
+
+	members := map[int][]map[string]interface{}{}
+	var roots []int
+	for i, p := range products {
+		root := find(i)
+		if _, linked := weakest[root]; !linked {
+			continue
+		}
+		if members[root] == nil {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], p)
+	}
+
+	clusters := make([]duplicateCluster, 0, len(roots))
+	for _, root := range roots {
+		group := members[root]
+		clusters = append(clusters, duplicateCluster{
+			Confidence:    weakest[root],
+			SuggestedKeep: group[0]["id"],
+			Products:      group,
+			Differences:   fieldDifferences(group),
+		})
+	}
+	sort.SliceStable(clusters, func(i, j int) bool { return clusters[i].Confidence > clusters[j].Confidence })
+	return clusters
+}
+
+// duplicateScore is the confidence that two products are the same product
+func duplicateScore(a, b map[string]interface{}) float64 {
+	na, nb := fmt.Sprint(a["name"]), fmt.Sprint(b["name"])
+	name := nameSimilarity(na, nb)
+	if name < duplicateNameScore || !slices.Equal(nameNumbers(na), nameNumbers(nb)) {
+		return 0
+	}
+
+	category := 0.0
+	ca, cb := normalizedValue(a["category"]), normalizedValue(b["category"])
+	switch {
+	case ca == "" || cb == "":
+		category = 0.5
+	case ca == cb:
+		category = 1
+	}
+
+	price := 0.0
+	pa, pb := math.Abs(toFloat64(a["price"])), math.Abs(toFloat64(b["price"]))
+	if highest := math.Max(pa, pb); highest == 0 {
+		price = 1
+	} else {
+		price = 1 - math.Abs(pa-pb)/highest
+	}
+	return roundScore(0.6*name + 0.2*category + 0.2*price)
+}
+
+// nameNumbers returns the digit runs of a name, e.g. [17] for "iPhone 17 Pro"
+func nameNumbers(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsDigit(r) })
+}
+
+// normalizedValue lowercases a string field and drops spacing and punctuation
+func normalizedValue(v interface{}) string {
+	s, _ := v.(string)
+	return strings.Join(nameTokens(s), "")
+}
+
+// fieldDifferences returns the distinct values of every mergeable field the products
+// disagree on
+func fieldDifferences(products []map[string]interface{}) map[string][]interface{} {
+	differences := map[string][]interface{}{}
+	for _, field := range mergeableFields {
+		var values []interface{}
+		seen := map[string]bool{}
+		for _, p := range products {
+			key := fmt.Sprintf("%#v", p[field])
+			if !seen[key] {
+				seen[key] = true
+				values = append(values, p[field])
+			}
+		}
+		if len(values) > 1 {
+			differences[field] = values
+		}
+	}
+	if len(differences) == 0 {
+		return nil
+	}
+	return differences
+}
+
+// mergeRequest is a validated merge_products call
+type mergeRequest struct {
+	keepID   string
+	mergeIDs []string
+	fields   map[string]interface{} // values to set on the kept product
+	takeFrom map[string]string      // field -> id of the product to copy it from
+}
+
+// parseMergeRequest validates the arguments of merge_products
+func parseMergeRequest(params map[string]interface{}) (mergeRequest, error) {
+	req := mergeRequest{fields: map[string]interface{}{}, takeFrom: map[string]string{}}
+	keepID, ok := params["keep_id"].(string)
+	if !ok || keepID == "" {
+		return req, fmt.Errorf("missing or invalid 'keep_id' argument")
+	}
+	req.keepID = keepID
+	mergeIDs, err := stringListParam(params, "merge_ids")
+	if err != nil {
+		return req, err
+	}
+	if len(mergeIDs) == 0 {
+		return req, fmt.Errorf("invalid 'merge_ids' argument: must list at least one product")
+	}
+	seen := map[string]bool{keepID: true}
+	for _, id := range mergeIDs {
+		if seen[id] {
+			return req, fmt.Errorf("invalid 'merge_ids' argument: product %s is listed twice or is the kept product", id)
+		}
+		seen[id] = true
+	}
+	req.mergeIDs = mergeIDs
+
+	if v, present := params["fields"]; present {
+		fields, ok := v.(map[string]interface{})
+		if !ok {
+			return req, fmt.Errorf("invalid 'fields' argument: must be an object of field values")
+		}
+		for k, v := range fields {
+			if err := validateMergeField(k, v); err != nil {
+				return req, err
+			}
+			req.fields[k] = v
+		}
+	}
+	if v, present := params["take_from"]; present {
+		takeFrom, ok := v.(map[string]interface{})
+		if !ok {
+			return req, fmt.Errorf("invalid 'take_from' argument: must map field names to product ids")
+		}
+		for k, v := range takeFrom {
+			id, ok := v.(string)
+			if !ok || !seen[id] {
+				return req, fmt.Errorf("invalid 'take_from' argument: %q must name the kept or a merged product", k)
+			}
+			if _, both := req.fields[k]; both {
+				return req, fmt.Errorf("invalid 'take_from' argument: %q is also set in 'fields'", k)
+			}
+			if err := validateMergeField(k, nil); err != nil {
+				return req, err
+			}
+			req.takeFrom[k] = id
+		}
+	}
+	return req, nil
+}
+
+// validateMergeField checks a field set by merge_products; a nil value only checks the name
+func validateMergeField(field string, value interface{}) error {
+	switch field {
+	case "name", "category", "segment":
+		if s, ok := value.(string); value != nil && (!ok || strings.TrimSpace(s) == "") {
+			return fmt.Errorf("invalid 'fields.%s': must be a non-empty string", field)
+		}
+	case "price":
+		if p, ok := value.(float64); value != nil && (!ok || p < 0) {
+			return fmt.Errorf("invalid 'fields.price': must be a non-negative number")
+		}
+	default:
+		return fmt.Errorf("field %q cannot be merged (allowed: %s)", field, strings.Join(mergeableFields, ", "))
+	}
+	return nil
+}
+
+// planMerge resolves the update of the kept product and the deletes of the others
+func planMerge(ctx context.Context, req mergeRequest) ([]plannedChange, error) {
+	ids := append([]string{req.keepID}, req.mergeIDs...)
+	current, err := snapshotProducts(withFreshReads(ctx), ids)
+	if err != nil {
+		return nil, err
+	}
+
+	update := map[string]interface{}{}
+	for k, v := range req.fields {
+		update[k] = v
+	}
+	for k, id := range req.takeFrom {
+		if p, ok := current[id]; ok && p[k] != nil {
+			update[k] = p[k]
+		}
+	}
+	keep := plannedChange{Action: "update", ID: req.keepID, Before: current[req.keepID]}
+	if keep.Before == nil {
+		keep.Error = fmt.Sprintf("product %s does not exist", req.keepID)
+	} else {
+		keep.After = copyParams(keep.Before)
+		keep.Fields = map[string]fieldChange{}
+		for k, v := range update {
+			keep.After[k] = v
+			if !reflect.DeepEqual(keep.Before[k], v) {
+				keep.Fields[k] = fieldChange{From: keep.Before[k], To: v}
+			}
+		}
+	}
+	changes := []plannedChange{keep}
+	for _, id := range req.mergeIDs {
+		change := plannedChange{Action: "delete", ID: id, Before: current[id]}
+		if change.Before == nil {
+			change.Error = fmt.Sprintf("product %s does not exist", id)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// mergeProducts keeps one product, applies the chosen field values and deletes the rest
+func mergeProducts(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	req, err := parseMergeRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	locked := append([]string{req.keepID}, req.mergeIDs...)
+	defer lockProducts(locked)()
+	auditLockedProducts(ctx, locked)
+
+	changes, err := planMerge(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range changes {
+		if c.Error != "" {
+			return nil, fmt.Errorf("cannot merge: %s; nothing was changed", c.Error)
+		}
+	}
+
+	kept := changes[0].Before
+	if len(changes[0].Fields) > 0 {
+		body := map[string]interface{}{"id": req.keepID}
+		for k, f := range changes[0].Fields {
+			body[k] = f.To
+		}
+		if err := productRules.checkWrite([]map[string]interface{}{body}, false); err != nil {
+			return nil, err
+		}
+		kept, err = sendWrite(ctx, http.MethodPut, "/products/{id}", productServiceBaseURL+"/products/"+req.keepID, body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update product %s; nothing was deleted: %w", req.keepID, err)
+		}
+		if kept == nil {
+			kept = changes[0].After
+		}
+	}
+
+	ids := make([]interface{}, len(req.mergeIDs))
+	for i, id := range req.mergeIDs {
+		ids[i] = id
+	}
+	if _, err := sendWrite(ctx, http.MethodPost, "/products/delete", productServiceBaseURL+"/products/delete", map[string]interface{}{"ids": ids}); err != nil {
+		return nil, fmt.Errorf("product %s was updated but deleting the merged products failed: %w", req.keepID, err)
+	}
+	return map[string]interface{}{
+		"kept":    kept,
+		"deleted": req.mergeIDs,
+		"fields":  changes[0].Fields,
+	}, nil
+}
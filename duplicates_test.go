@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFindDuplicatesClustersNearDuplicates(t *testing.T) {
+	newFakeProductService(t,
+		testProduct("1", "iPhone 17", "Electronics", "Phones", 999),
+		testProduct("2", "iphone17", "electronics", "Phones", 989),
+		testProduct("3", "IPhone 17 ", "Electronics", "Phones", 999),
+		testProduct("4", "iPhone 16", "Electronics", "Phones", 799),
+		testProduct("5", "Office Chair", "Furniture", "Budget", 149),
+	)
+
+	result, err := executeToolCall(context.Background(), "find_duplicates", map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clusters := result.(map[string]interface{})["clusters"].([]duplicateCluster)
+	if len(clusters) != 1 {
+		t.Fatalf("Expected one cluster, got %+v", clusters)
+	}
+	c := clusters[0]
+	if len(c.Products) != 3 || c.SuggestedKeep != "1" || c.Confidence < 0.9 {
+		t.Errorf("Expected the three iPhone 17 products with high confidence, got %+v", c)
+	}
+	if len(c.Differences["price"]) != 2 || len(c.Differences["name"]) != 3 {
+		t.Errorf("Expected the differing names and prices, got %v", c.Differences)
+	}
+
+	if s := duplicateScore(testProduct("1", "iPhone 17", "Electronics", "Phones", 999), testProduct("4", "iPhone 16", "Electronics", "Phones", 799)); s >= defaultDuplicateMinScore {
+		t.Errorf("Expected different model numbers not to be duplicates, got %v", s)
+	}
+	if _, err := executeToolCall(context.Background(), "find_duplicates", map[string]interface{}{"min_score": 2.0}); err == nil {
+		t.Error("Expected an error for min_score above 1")
+	}
+}
+
+func TestMergeProducts(t *testing.T) {
+	fake := newFakeProductService(t,
+		testProduct("1", "iPhone 17", "Electronics", "Phones", 999),
+		testProduct("2", "iphone17", "Electronics", "Phones", 989),
+		testProduct("3", "IPhone 17 ", "Electronics", "Phones", 999),
+	)
+	swapGlobal[auditSink](t, &auditLog, newMemoryAuditSink(100))
+	ctx := context.Background()
+
+	if _, err := executeToolCall(ctx, "merge_products", map[string]interface{}{"keep_id": "1", "merge_ids": []interface{}{"2", "9"}}); err == nil {
+		t.Error("Expected a merge with an unknown product to be refused")
+	}
+	if _, err := executeToolCall(ctx, "merge_products", map[string]interface{}{"keep_id": "1", "merge_ids": []interface{}{"1"}}); err == nil {
+		t.Error("Expected a merge of a product into itself to be refused")
+	}
+	if fake.writeCount() != 0 {
+		t.Fatalf("Expected refused merges not to write, got %d writes", fake.writeCount())
+	}
+
+	params := map[string]interface{}{
+		"keep_id":   "1",
+		"merge_ids": []interface{}{"2", "3"},
+		"fields":    map[string]interface{}{"segment": "Smartphones"},
+		"take_from": map[string]interface{}{"price": "2"},
+	}
+	preview, err := executeToolCall(ctx, "merge_products", map[string]interface{}{
+		"keep_id": "1", "merge_ids": params["merge_ids"], "fields": params["fields"], "take_from": params["take_from"], "dry_run": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan := preview.(mutationPlan); !plan.Valid || len(plan.Changes) != 3 || plan.Changes[0].Fields["price"].To != 989.0 {
+		t.Errorf("Unexpected merge preview %+v", plan)
+	}
+
+	if _, err := executeToolCall(ctx, "merge_products", params); err != nil {
+		t.Fatal(err)
+	}
+	if p := fake.get("1"); p["price"] != 989.0 || p["segment"] != "Smartphones" || p["name"] != "iPhone 17" {
+		t.Errorf("Expected the chosen values on the kept product, got %v", p)
+	}
+	if fake.get("2") != nil || fake.get("3") != nil {
+		t.Error("Expected the duplicates to be deleted")
+	}
+
+	// the merge is one audited change and can be reverted as a whole
+	if _, err := executeToolCall(ctx, "undo_change", map[string]interface{}{"change_id": lastChangeID(t)}); err != nil {
+		t.Fatal(err)
+	}
+	if p := fake.get("1"); p["price"] != 999.0 || p["segment"] != "Phones" {
+		t.Errorf("Expected the kept product to be restored, got %v", p)
+	}
+	if len(fake.matching("category", "Electronics")) != 3 {
+		t.Errorf("Expected the merged products to be recreated, got %v", fake.sortedProducts())
+	}
+}
@@ -0,0 +1,329 @@
+// Package main - elicitation.go
+//
+// This file asks the user for confirmation, through the MCP client, before destructive
+// or large mutations are applied.
+//
+// Key Responsibilities:
+//   - Decide which tool calls need confirmation (confirmMutation)
+//   - Summarize what will be deleted or changed, using the same read-only planning as dry_run
+//   - Send an 'elicitation/create' request to the client and wait for its answer
+//
+// Which calls need confirmation:
+//   - delete_product and delete_products, always
+//   - any other mutating tool touching more than confirmation.item_threshold products
+//     (0 disables the threshold)
+//   - calls with dry_run=true never need confirmation, since they do not write
+//
+// Transport (Streamable HTTP):
+//  1. The client initializes with the 'elicitation' capability and sends Mcp-Session-Id
+//     plus "Accept: text/event-stream" on its tools/call POST
+//  2. The response to that POST is upgraded to an SSE stream and the server sends the
+//     elicitation/create request as the first event
+//  3. The client POSTs its JSON-RPC response to /mcp; it is delivered to the waiting call
+//     (see sessions.go) and answered with 202 Accepted
+//  4. The tool runs only if the user accepted with confirm=true, and the tools/call
+//     result is sent as the final SSE event
+//
+// When the client cannot elicit, the call proceeds unless confirmation.required is set,
+// in which case it is refused with a tool error. A call naming an unknown session gets
+// 404 instead, so the client initializes again and can be asked.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// destructiveTools always need confirmation when the client supports elicitation
+var destructiveTools = map[string]bool{
+	"delete_product":  true,
+	"delete_products": true,
+	"merge_products":  true,
+}
+
+var (
+	errConfirmationDeclined    = errors.New("operation cancelled: the user did not confirm it")
+	errConfirmationUnavailable = errors.New("confirmation required")
+	errSessionNotFound         = errors.New("session not found")
+)
+
+// maxSummaryLines limits how many products are listed in a confirmation message
+const maxSummaryLines = 20
+
+var confirmationSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"confirm": map[string]interface{}{
+			"type":        "boolean",
+			"title":       "Proceed",
+			"description": "Apply the changes described above",
+		},
+	},
+	"required": []string{"confirm"},
+}
+
+// confirmMutation returns nil when the tool call may proceed
+func confirmMutation(ctx context.Context, el *elicitor, cfg ConfirmationConfig, toolName string, params map[string]interface{}) error {
+	if !mutatingTools[toolName] {
+		return nil
+	}
+	if dryRun, _ := params["dry_run"].(bool); dryRun {
+		return nil
+	}
+	if !destructiveTools[toolName] && cfg.ItemThreshold == 0 {
+		return nil
+	}
+
+	count, summary, err := describeMutation(ctx, toolName, params)
+	if err != nil {
+		return err
+	}
+	if !destructiveTools[toolName] && count <= cfg.ItemThreshold {
+		return nil
+	}
+
+	// the client may have been able to confirm in a session this instance does not know;
+	// it has to initialize again rather than have the call proceed unconfirmed
+	if el != nil && el.staleSession {
+		return errSessionNotFound
+	}
+	if !el.available() {
+		if cfg.Required {
+			return fmt.Errorf("%w: %s needs explicit confirmation but the client does not support elicitation. %s",
+				errConfirmationUnavailable, toolName, summary)
+		}
+		return nil
+	}
+
+	result, err := el.elicit(ctx, summary+"\nDo you want to proceed?", confirmationSchema)
+	if err != nil {
+		return err
+	}
+	if confirmed, _ := result.Content["confirm"].(bool); result.Action != "accept" || !confirmed {
+		return fmt.Errorf("%w (%s)", errConfirmationDeclined, result.Action)
+	}
+	return nil
+}
+
+// describeMutation counts the products a call would touch and summarizes them for the user
+func describeMutation(ctx context.Context, toolName string, params map[string]interface{}) (int, string, error) {
+	var lines []string
+	switch toolName {
+	case "delete_product", "delete_products", "adjust_prices":
+		changes, err := plannedChanges(ctx, toolName, params)
+		if err != nil {
+			return 0, "", err
+		}
+		for _, change := range changes {
+			switch {
+			case change.Error != "":
+				lines = append(lines, fmt.Sprintf("%v: %s", change.ID, change.Error))
+			case change.Action == "delete":
+				lines = append(lines, fmt.Sprintf("%v (id %v, %v, price %v)", change.Before["name"], change.ID, change.Before["category"], change.Before["price"]))
+			case len(change.Fields) > 0:
+				price := change.Fields["price"]
+				lines = append(lines, fmt.Sprintf("id %v: price %v -> %v", change.ID, price.From, price.To))
+			}
+		}
+	case "create_multiple_products", "update_products":
+		items, err := objectListParam(params, "products")
+		if err != nil {
+			return 0, "", err
+		}
+		for _, item := range items {
+			if toolName == "update_products" {
+				lines = append(lines, fmt.Sprintf("id %v", item["id"]))
+			} else {
+				lines = append(lines, fmt.Sprintf("%v", item["name"]))
+			}
+		}
+	case "create_product":
+		lines = append(lines, fmt.Sprintf("%v", params["name"]))
+	case "update_product":
+		lines = append(lines, fmt.Sprintf("id %v", params["id"]))
+	case "undo_change":
+		changes, err := plannedChanges(ctx, toolName, params)
+		if err != nil {
+			return 0, "", err
+		}
+		for _, change := range changes {
+			lines = append(lines, fmt.Sprintf("%s id %v", change.Action, change.ID))
+		}
+	case "import_products":
+		changes, err := plannedChanges(ctx, toolName, params)
+		if err != nil {
+			return 0, "", err
+		}
+		for _, change := range changes {
+			switch {
+			case change.Error != "":
+			case change.Action == "create":
+				lines = append(lines, fmt.Sprintf("create %v", change.After["name"]))
+			case len(change.Fields) > 0:
+				lines = append(lines, fmt.Sprintf("update %v (id %v)", change.Before["name"], change.ID))
+			}
+		}
+	case "merge_products":
+		changes, err := plannedChanges(ctx, toolName, params)
+		if err != nil {
+			return 0, "", err
+		}
+		for _, change := range changes {
+			switch {
+			case change.Error != "":
+				lines = append(lines, fmt.Sprintf("%v: %s", change.ID, change.Error))
+			case change.Action == "delete":
+				lines = append(lines, fmt.Sprintf("delete %v (id %v, %v, price %v)", change.Before["name"], change.ID, change.Before["category"], change.Before["price"]))
+			default:
+				lines = append(lines, fmt.Sprintf("keep %v (id %v)", change.Before["name"], change.ID))
+			}
+		}
+	}
+
+	verb := map[string]string{
+		"delete_product":           "Delete",
+		"delete_products":          "Delete",
+		"adjust_prices":            "Change the price of",
+		"create_product":           "Create",
+		"create_multiple_products": "Create",
+		"update_product":           "Update",
+		"update_products":          "Update",
+		"undo_change":              "Revert changes to",
+		"merge_products":           "Merge",
+		"import_products":          "Import",
+	}[toolName]
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %d product(s):\n", verb, len(lines))
+	for i, line := range lines {
+		if i == maxSummaryLines {
+			fmt.Fprintf(&sb, "  ... and %d more\n", len(lines)-maxSummaryLines)
+			break
+		}
+		fmt.Fprintf(&sb, "  - %s\n", line)
+	}
+	if destructiveTools[toolName] {
+		if auditLog != nil {
+			sb.WriteString("Deleted products can be restored with undo_change.\n")
+		} else {
+			sb.WriteString("This cannot be undone.\n")
+		}
+	}
+	return len(lines), sb.String(), nil
+}
+
+// elicitor sends server-initiated requests on the response stream of a tools/call POST
+type elicitor struct {
+	w             http.ResponseWriter
+	session       *session
+	staleSession  bool // the request named a session that is unknown or expired
+	acceptsStream bool
+	timeout       time.Duration
+	streaming     bool
+}
+
+type elicitorContextKey struct{}
+
+func withElicitor(ctx context.Context, el *elicitor) context.Context {
+	return context.WithValue(ctx, elicitorContextKey{}, el)
+}
+
+func elicitorFromContext(ctx context.Context) *elicitor {
+	el, _ := ctx.Value(elicitorContextKey{}).(*elicitor)
+	return el
+}
+
+// available reports whether the client can answer an elicitation on this request
+func (e *elicitor) available() bool {
+	return e != nil && e.session != nil && e.acceptsStream && e.session.supports("elicitation")
+}
+
+// elicit sends elicitation/create and waits for the client's answer
+func (e *elicitor) elicit(ctx context.Context, message string, schema map[string]interface{}) (ElicitResult, error) {
+	var result ElicitResult
+	rc := http.NewResponseController(e.w)
+	if !e.streaming {
+		e.w.Header().Set("Content-Type", "text/event-stream")
+		e.w.Header().Set("Cache-Control", "no-cache")
+		e.w.WriteHeader(http.StatusOK)
+		e.streaming = true
+	}
+	// the user may take a while to answer; keep the write deadline ahead of the wait
+	rc.SetWriteDeadline(time.Now().Add(e.timeout + time.Minute))
+
+	id, responses := e.session.expectResponse("elicit")
+	defer e.session.forget(id)
+
+	request := JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      id,
+		Method:  "elicitation/create",
+		Params:  ElicitRequestParams{Message: message, RequestedSchema: schema},
+	}
+	if err := writeSSEEvent(e.w, request); err != nil {
+		return result, fmt.Errorf("failed to send confirmation request: %v", err)
+	}
+
+	timer := time.NewTimer(e.timeout)
+	defer timer.Stop()
+	select {
+	case resp := <-responses:
+		if resp.Error != nil {
+			return result, fmt.Errorf("%w: client rejected the confirmation request: %s", errConfirmationDeclined, resp.Error.Message)
+		}
+		raw, _ := json.Marshal(resp.Result)
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return result, fmt.Errorf("invalid elicitation response: %v", err)
+		}
+		return result, nil
+	case <-timer.C:
+		return result, fmt.Errorf("%w: no answer within %s", errConfirmationDeclined, e.timeout)
+	case <-serverStopping:
+		return result, fmt.Errorf("%w: server is shutting down", errConfirmationDeclined)
+	case <-ctx.Done():
+		return result, ctx.Err()
+	}
+}
+
+// responseWriter returns the writer for the final JSON-RPC response, framing it as an
+// SSE event if the response was upgraded to a stream
+func (e *elicitor) responseWriter(w http.ResponseWriter) http.ResponseWriter {
+	if e == nil || !e.streaming {
+		return w
+	}
+	return &sseWriter{ResponseWriter: w}
+}
+
+// sseWriter frames each JSON message written by sendJSONRPCResponse as an SSE event
+type sseWriter struct {
+	http.ResponseWriter
+}
+
+func (s *sseWriter) Write(p []byte) (int, error) {
+	if _, err := fmt.Fprintf(s.ResponseWriter, "event: message\ndata: %s\n\n", bytes.TrimRight(p, "\n")); err != nil {
+		return 0, err
+	}
+	http.NewResponseController(s.ResponseWriter).Flush()
+	return len(p), nil
+}
+
+func (s *sseWriter) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+func writeSSEEvent(w http.ResponseWriter, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: message\ndata: %s\n\n", data); err != nil {
+		return err
+	}
+	return http.NewResponseController(w).Flush()
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfirmMutationWithoutElicitation(t *testing.T) {
+	fake := newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Laptops", 999),
+		testProduct("2", "Chair", "Furniture", "Budget", 49),
+	)
+	ctx := context.Background()
+	required := ConfirmationConfig{Required: true, ItemThreshold: 2, Timeout: Duration{time.Second}}
+
+	err := confirmMutation(ctx, nil, required, "delete_product", map[string]interface{}{"id": "1"})
+	if !errors.Is(err, errConfirmationUnavailable) || !strings.Contains(err.Error(), "Laptop5") {
+		t.Errorf("Expected a confirmation error naming the product, got %v", err)
+	}
+	if err := confirmMutation(ctx, nil, required, "delete_product", map[string]interface{}{"id": "1", "dry_run": true}); err != nil {
+		t.Errorf("Expected dry runs to need no confirmation, got %v", err)
+	}
+	twoUpdates := map[string]interface{}{"products": []interface{}{
+		map[string]interface{}{"id": "1", "price": 1.0},
+		map[string]interface{}{"id": "2", "price": 2.0},
+	}}
+	if err := confirmMutation(ctx, nil, required, "update_products", twoUpdates); err != nil {
+		t.Errorf("Expected updates within the threshold to proceed, got %v", err)
+	}
+	required.ItemThreshold = 1
+	if err := confirmMutation(ctx, nil, required, "update_products", twoUpdates); !errors.Is(err, errConfirmationUnavailable) {
+		t.Errorf("Expected updates above the threshold to need confirmation, got %v", err)
+	}
+	if err := confirmMutation(ctx, nil, ConfirmationConfig{}, "delete_product", map[string]interface{}{"id": "1"}); err != nil {
+		t.Errorf("Expected unconfirmed deletes to proceed when confirmation is not required, got %v", err)
+	}
+	if fake.writeCount() != 0 {
+		t.Errorf("Expected no write calls, got %d", fake.writeCount())
+	}
+}
+
+func TestElicitationConfirmsDelete(t *testing.T) {
+	fake := newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Laptops", 999),
+		testProduct("2", "Chair", "Furniture", "Budget", 49),
+	)
+	config := defaultConfig()
+	config.Confirmation.Required = true
+	server := httptest.NewServer(mcpHandler(config))
+	defer server.Close()
+
+	post := func(sessionID string, body interface{}) *http.Response {
+		data, _ := json.Marshal(body)
+		req, _ := http.NewRequest(http.MethodPost, server.URL, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if sessionID != "" {
+			req.Header.Set("Mcp-Session-Id", sessionID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := post("", map[string]interface{}{
+		"jsonrpc": "2.0", "id": 1, "method": "initialize",
+		"params": map[string]interface{}{
+			"protocolVersion": "2025-06-18",
+			"capabilities":    map[string]interface{}{"elicitation": map[string]interface{}{}},
+			"clientInfo":      map[string]interface{}{"name": "test", "version": "1"},
+		},
+	})
+	resp.Body.Close()
+	sessionID := resp.Header.Get("Mcp-Session-Id")
+	if sessionID == "" {
+		t.Fatal("Expected an Mcp-Session-Id header on the initialize response")
+	}
+
+	// deleteWithAnswer calls delete_product and answers the elicitation with the given action
+	deleteWithAnswer := func(id, action string) CallToolResult {
+		resp := post(sessionID, map[string]interface{}{
+			"jsonrpc": "2.0", "id": 2, "method": "tools/call",
+			"params": map[string]interface{}{"name": "delete_product", "arguments": map[string]interface{}{"id": id}},
+		})
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("Expected an SSE response, got %q", ct)
+		}
+		events := bufio.NewScanner(resp.Body)
+		nextEvent := func() []byte {
+			for events.Scan() {
+				if data, ok := strings.CutPrefix(events.Text(), "data: "); ok {
+					return []byte(data)
+				}
+			}
+			t.Fatal("SSE stream ended early")
+			return nil
+		}
+
+		var request JSONRPCRequest
+		json.Unmarshal(nextEvent(), &request)
+		if request.Method != "elicitation/create" {
+			t.Fatalf("Expected elicitation/create, got %q", request.Method)
+		}
+		message, _ := request.Params.(map[string]interface{})["message"].(string)
+		if !strings.Contains(message, "Laptop5") {
+			t.Errorf("Expected the confirmation message to name the product, got %q", message)
+		}
+
+		answer := post(sessionID, map[string]interface{}{
+			"jsonrpc": "2.0", "id": request.ID,
+			"result": map[string]interface{}{"action": action, "content": map[string]interface{}{"confirm": action == "accept"}},
+		})
+		answer.Body.Close()
+		if answer.StatusCode != http.StatusAccepted {
+			t.Errorf("Expected 202 for the client response, got %d", answer.StatusCode)
+		}
+
+		var final struct {
+			Result CallToolResult `json:"result"`
+		}
+		json.Unmarshal(nextEvent(), &final)
+		return final.Result
+	}
+
+	if result := deleteWithAnswer("1", "decline"); !result.IsError || fake.get("1") == nil {
+		t.Errorf("Expected a declined delete to fail without deleting, got %#v", result)
+	}
+	if result := deleteWithAnswer("1", "accept"); result.IsError || fake.get("1") != nil {
+		t.Errorf("Expected an accepted delete to remove the product, got %#v", result)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL, nil)
+	req.Header.Set("Mcp-Session-Id", sessionID)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected 204 when terminating the session, got %v %v", resp, err)
+	}
+
+	// an unknown session (e.g. after a restart) only matters to calls that need to confirm
+	resp = post(sessionID, map[string]interface{}{"jsonrpc": "2.0", "id": 3, "method": "tools/list"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected tools/list to work with an unknown session, got %d", resp.StatusCode)
+	}
+	resp = post(sessionID, map[string]interface{}{
+		"jsonrpc": "2.0", "id": 4, "method": "tools/call",
+		"params": map[string]interface{}{"name": "delete_product", "arguments": map[string]interface{}{"id": "2"}},
+	})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound || fake.get("2") == nil {
+		t.Errorf("Expected 404 and no delete for a call that needs confirmation, got %d", resp.StatusCode)
+	}
+	resp = post(sessionID, map[string]interface{}{"jsonrpc": "2.0", "id": "elicit-9", "result": map[string]interface{}{"action": "accept"}})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for a client response to an unknown session, got %d", resp.StatusCode)
+	}
+}
+
+func TestSessionStoreExpiresAndEvicts(t *testing.T) {
+	store := &sessionStore{sessions: map[string]*session{}}
+	idle := store.create(InitializeParams{}, "2025-06-18")
+	idle.lastSeen = time.Now().Add(-sessionIdleTimeout - time.Minute)
+	if store.get(idle.ID) != nil || len(store.sessions) != 0 {
+		t.Error("Expected an idle session to expire when it is looked up")
+	}
+
+	first := store.create(InitializeParams{}, "2025-06-18")
+	first.lastSeen = time.Now().Add(-time.Hour)
+	for len(store.sessions) < maxSessions {
+		store.create(InitializeParams{}, "2025-06-18")
+	}
+	latest := store.create(InitializeParams{}, "2025-06-18")
+	if len(store.sessions) != maxSessions || store.get(first.ID) != nil || store.get(latest.ID) == nil {
+		t.Errorf("Expected the least recently used session to be evicted at %d sessions, have %d", maxSessions, len(store.sessions))
+	}
+}
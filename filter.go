@@ -0,0 +1,521 @@
+// Package main - filter.go
+//
+// This file parses and evaluates the filter expressions and multi-key sort specs accepted
+// by search_products.
+//
+// Key Responsibilities:
+//   - Tokenize and parse a filter expression into a tree (parseFilter)
+//   - Evaluate the tree against each product returned by the backend
+//   - Parse and apply multi-key sort specs such as "category asc, price desc"
+//   - Report parse errors with the position of the offending token
+//
+// Filter Grammar:
+//
+//	expr       := and ("or" and)*
+//	and        := unary ("and" unary)*
+//	unary      := "not" unary | "(" expr ")" | comparison
+//	comparison := field op value | field ["not"] "in" "(" value ("," value)* ")"
+//	op         := = | != | < | <= | > | >= | ^= (prefix) | *= (contains) | ~ (regex)
+//	value      := "string" | 'string' | number | bareword
+//
+// Semantics:
+//   - Keywords (and, or, not, in) are case-insensitive; "and" binds tighter than "or"
+//   - String comparisons, prefixes, 'in' lists and regexes are case-insensitive
+//   - Numeric fields such as price are compared numerically against number literals
+//   - A comparison against a field the product does not have is false (!= is true)
+//
+// Examples:
+//
+//	price >= 100 and price < 500
+//	category in ("Electronics", "Furniture") and not segment = "Budget"
+//	(name ^= "iphone" or name ~ "^galaxy s[0-9]+") and price <= 1200
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// productFilter is a node of a parsed filter expression
+type productFilter interface {
+	match(p map[string]interface{}) bool
+}
+
+type andFilter []productFilter
+
+func (f andFilter) match(p map[string]interface{}) bool {
+	for _, sub := range f {
+		if !sub.match(p) {
+			return false
+		}
+	}
+	return true
+}
+
+type orFilter []productFilter
+
+func (f orFilter) match(p map[string]interface{}) bool {
+	for _, sub := range f {
+		if sub.match(p) {
+			return true
+		}
+	}
+	return false
+}
+
+type notFilter struct {
+	inner productFilter
+}
+
+func (f notFilter) match(p map[string]interface{}) bool {
+	return !f.inner.match(p)
+}
+
+// filterLiteral is a value written in a filter expression
+type filterLiteral struct {
+	text   string
+	number float64
+	isNum  bool
+}
+
+type comparisonFilter struct {
+	field  string
+	op     string
+	values []filterLiteral
+	regex  *regexp.Regexp
+}
+
+func (f comparisonFilter) match(p map[string]interface{}) bool {
+	value, present := p[f.field]
+	if !present || value == nil {
+		return f.op == "!="
+	}
+	switch f.op {
+	case "in":
+		for _, lit := range f.values {
+			if compareFilterValue(value, lit) == 0 {
+				return true
+			}
+		}
+		return false
+	case "~":
+		return f.regex.MatchString(fmt.Sprint(value))
+	case "^=":
+		return strings.HasPrefix(strings.ToLower(fmt.Sprint(value)), strings.ToLower(f.values[0].text))
+	case "*=":
+		return strings.Contains(strings.ToLower(fmt.Sprint(value)), strings.ToLower(f.values[0].text))
+	}
+
+	cmp := compareFilterValue(value, f.values[0])
+	switch f.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// compareFilterValue compares a product value with a literal: numerically when both are
+// numbers, otherwise as case-insensitive strings
+func compareFilterValue(value interface{}, lit filterLiteral) int {
+	if lit.isNum {
+		switch value.(type) {
+		case float64, float32, int, int64:
+			n := toFloat64(value)
+			switch {
+			case n < lit.number:
+				return -1
+			case n > lit.number:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(strings.ToLower(fmt.Sprint(value)), strings.ToLower(lit.text))
+}
+
+// filterToken is a lexical token of a filter expression
+type filterToken struct {
+	kind string // ident, string, number, op, "(", ")", ",", eof
+	text string
+	pos  int
+}
+
+func tokenizeFilter(input string) ([]filterToken, error) {
+	var tokens []filterToken
+	i := 0
+	for i < len(input) {
+		c := rune(input[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, filterToken{kind: string(c), text: string(c), pos: i})
+			i++
+		case c == '"' || c == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			for i < len(input) && rune(input[i]) != c {
+				if input[i] == '\\' && i+1 < len(input) && (rune(input[i+1]) == c || input[i+1] == '\\') {
+					i++
+				}
+				sb.WriteByte(input[i])
+				i++
+			}
+			if i >= len(input) {
+				return nil, fmt.Errorf("at position %d: unterminated string", start+1)
+			}
+			i++
+			tokens = append(tokens, filterToken{kind: "string", text: sb.String(), pos: start})
+		case strings.ContainsRune("=!<>^*~", c):
+			start := i
+			op := string(c)
+			if i+1 < len(input) && input[i+1] == '=' && c != '=' && c != '~' {
+				op += "="
+			}
+			if op == "!" || op == "^" || op == "*" {
+				return nil, fmt.Errorf("at position %d: unexpected %q", start+1, op)
+			}
+			i += len(op)
+			tokens = append(tokens, filterToken{kind: "op", text: op, pos: start})
+		case c == '-' || c == '.' || unicode.IsDigit(c):
+			start := i
+			i++
+			for i < len(input) && (unicode.IsDigit(rune(input[i])) || input[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: "number", text: input[start:i], pos: start})
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(input) && (input[i] == '_' || input[i] == '-' || unicode.IsLetter(rune(input[i])) || unicode.IsDigit(rune(input[i]))) {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: "ident", text: input[start:i], pos: start})
+		default:
+			return nil, fmt.Errorf("at position %d: unexpected character %q", i+1, c)
+		}
+	}
+	return append(tokens, filterToken{kind: "eof", pos: len(input)}), nil
+}
+
+// filterParser is a recursive descent parser over the token list
+type filterParser struct {
+	tokens []filterToken
+	next   int
+}
+
+// parseFilter parses a filter expression; an empty expression matches every product
+func parseFilter(input string) (productFilter, error) {
+	if strings.TrimSpace(input) == "" {
+		return andFilter{}, nil
+	}
+	tokens, err := tokenizeFilter(input)
+	if err != nil {
+		return nil, err
+	}
+	parser := &filterParser{tokens: tokens}
+	expr, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := parser.peek(); tok.kind != "eof" {
+		return nil, parser.errorf(tok, "expected 'and', 'or' or end of filter")
+	}
+	return expr, nil
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.next]
+}
+
+func (p *filterParser) advance() filterToken {
+	tok := p.tokens[p.next]
+	if tok.kind != "eof" {
+		p.next++
+	}
+	return tok
+}
+
+// keyword reports whether the next token is the given keyword, consuming it if so
+func (p *filterParser) keyword(word string) bool {
+	if tok := p.peek(); tok.kind == "ident" && strings.EqualFold(tok.text, word) {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) errorf(tok filterToken, format string, args ...interface{}) error {
+	found := "end of filter"
+	if tok.kind != "eof" {
+		found = fmt.Sprintf("%q", tok.text)
+	}
+	return fmt.Errorf("at position %d: %s, found %s", tok.pos+1, fmt.Sprintf(format, args...), found)
+}
+
+func (p *filterParser) parseOr() (productFilter, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	terms := orFilter{first}
+	for p.keyword("or") {
+		term, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return terms, nil
+}
+
+func (p *filterParser) parseAnd() (productFilter, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	terms := andFilter{first}
+	for p.keyword("and") {
+		term, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return terms, nil
+}
+
+func (p *filterParser) parseUnary() (productFilter, error) {
+	if p.keyword("not") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notFilter{inner}, nil
+	}
+	if p.peek().kind == "(" {
+		p.advance()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.advance(); tok.kind != ")" {
+			return nil, p.errorf(tok, "expected ')'")
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (productFilter, error) {
+	fieldTok := p.advance()
+	if fieldTok.kind != "ident" || isFilterKeyword(fieldTok.text) {
+		return nil, p.errorf(fieldTok, "expected a field name")
+	}
+	field := strings.ToLower(fieldTok.text)
+
+	negate := p.keyword("not")
+	if p.keyword("in") {
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		var f productFilter = comparisonFilter{field: field, op: "in", values: values}
+		if negate {
+			f = notFilter{f}
+		}
+		return f, nil
+	}
+	if negate {
+		return nil, p.errorf(p.peek(), "expected 'in' after 'not'")
+	}
+
+	opTok := p.advance()
+	if opTok.kind != "op" {
+		return nil, p.errorf(opTok, "expected an operator (=, !=, <, <=, >, >=, ^=, *=, ~ or in) after %q", fieldTok.text)
+	}
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	f := comparisonFilter{field: field, op: opTok.text, values: []filterLiteral{value}}
+	if f.op == "~" {
+		f.regex, err = regexp.Compile("(?i)" + value.text)
+		if err != nil {
+			return nil, fmt.Errorf("at position %d: invalid regular expression: %v", opTok.pos+1, err)
+		}
+	}
+	return f, nil
+}
+
+func (p *filterParser) parseList() ([]filterLiteral, error) {
+	if tok := p.advance(); tok.kind != "(" {
+		return nil, p.errorf(tok, "expected '(' after 'in'")
+	}
+	var values []filterLiteral
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		tok := p.advance()
+		if tok.kind == ")" {
+			return values, nil
+		}
+		if tok.kind != "," {
+			return nil, p.errorf(tok, "expected ',' or ')' in list")
+		}
+	}
+}
+
+func (p *filterParser) parseValue() (filterLiteral, error) {
+	tok := p.advance()
+	switch tok.kind {
+	case "string":
+		return filterLiteral{text: tok.text}, nil
+	case "number":
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return filterLiteral{}, p.errorf(tok, "invalid number")
+		}
+		return filterLiteral{text: tok.text, number: n, isNum: true}, nil
+	case "ident":
+		if !isFilterKeyword(tok.text) {
+			return filterLiteral{text: tok.text}, nil
+		}
+	}
+	return filterLiteral{}, p.errorf(tok, "expected a value")
+}
+
+func isFilterKeyword(word string) bool {
+	switch strings.ToLower(word) {
+	case "and", "or", "not", "in":
+		return true
+	}
+	return false
+}
+
+// applyFilter keeps the products matching the expression
+func applyFilter(products []map[string]interface{}, f productFilter) []map[string]interface{} {
+	var filtered []map[string]interface{}
+	for _, p := range products {
+		if f.match(p) {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// sortKey is one key of a multi-key sort spec
+type sortKey struct {
+	field string
+	desc  bool
+}
+
+// parseSortSpec accepts "category asc, price desc" or an array of such entries; the
+// direction defaults to asc
+func parseSortSpec(v interface{}) ([]sortKey, error) {
+	var entries []string
+	switch spec := v.(type) {
+	case string:
+		entries = strings.Split(spec, ",")
+	case []interface{}:
+		for _, item := range spec {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid 'sort' argument: expected strings such as \"price desc\"")
+			}
+			entries = append(entries, s)
+		}
+	default:
+		return nil, fmt.Errorf("invalid 'sort' argument: expected a string such as \"category asc, price desc\"")
+	}
+
+	var keys []sortKey
+	for _, entry := range entries {
+		parts := strings.Fields(entry)
+		if len(parts) == 0 {
+			continue
+		}
+		key := sortKey{field: strings.ToLower(parts[0])}
+		if len(parts) > 2 {
+			return nil, fmt.Errorf("invalid 'sort' entry %q: expected \"<field> [asc|desc]\"", strings.TrimSpace(entry))
+		}
+		if len(parts) == 2 {
+			switch strings.ToLower(parts[1]) {
+			case "asc":
+			case "desc":
+				key.desc = true
+			default:
+				return nil, fmt.Errorf("invalid 'sort' entry %q: direction must be asc or desc", strings.TrimSpace(entry))
+			}
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("invalid 'sort' argument: no sort keys given")
+	}
+	return keys, nil
+}
+
+// sortProducts orders products by each key in turn; products missing a field sort last
+func sortProducts(products []map[string]interface{}, keys []sortKey) {
+	sort.SliceStable(products, func(i, j int) bool {
+		for _, key := range keys {
+			a, aok := products[i][key.field]
+			b, bok := products[j][key.field]
+			if !aok || !bok {
+				if aok != bok {
+					return aok
+				}
+				continue
+			}
+			cmp := compareSortValues(a, b)
+			if cmp == 0 {
+				continue
+			}
+			if key.desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+}
+
+func compareSortValues(a, b interface{}) int {
+	_, aNum := a.(float64)
+	_, bNum := b.(float64)
+	if aNum && bNum {
+		x, y := a.(float64), b.(float64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(strings.ToLower(fmt.Sprint(a)), strings.ToLower(fmt.Sprint(b)))
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	products := []map[string]interface{}{
+		testProduct("1", "iPhone 15", "Electronics", "Premium", 999),
+		testProduct("2", "Galaxy S24", "Electronics", "Premium", 899),
+		testProduct("3", "Galaxy A15", "Electronics", "Budget", 199),
+		testProduct("4", "Chair", "Furniture", "Budget", 49),
+	}
+
+	cases := []struct {
+		filter string
+		want   []string
+	}{
+		{`price >= 100 and price < 900`, []string{"2", "3"}},
+		{`category in ("furniture", "Toys")`, []string{"4"}},
+		{`category not in (Electronics)`, []string{"4"}},
+		{`not segment = "budget"`, []string{"1", "2"}},
+		{`name ^= "galaxy"`, []string{"2", "3"}},
+		{`name *= 'phone'`, []string{"1"}},
+		{`name ~ "^galaxy s[0-9]+"`, []string{"2"}},
+		{`(name ^= "iphone" or segment = Budget) and price > 100`, []string{"1", "3"}},
+		{`segment = Budget or category = Electronics and price > 950`, []string{"1", "3", "4"}},
+		{`color = "red"`, nil},
+		{``, []string{"1", "2", "3", "4"}},
+	}
+	for _, tc := range cases {
+		f, err := parseFilter(tc.filter)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.filter, err)
+			continue
+		}
+		var got []string
+		for _, p := range applyFilter(products, f) {
+			got = append(got, p["id"].(string))
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%s: expected %v, got %v", tc.filter, tc.want, got)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	cases := map[string]string{
+		`price >`:                  "position 8: expected a value, found end of filter",
+		`price >= 10 and`:          "position 16: expected a field name",
+		`(price > 10`:              "expected ')'",
+		`name = "open`:             "unterminated string",
+		`price 10`:                 `expected an operator`,
+		`category in ("a" "b")`:    "expected ',' or ')' in list",
+		`name ~ "("`:               "invalid regular expression",
+		`price > 10 price < 20`:    "expected 'and', 'or' or end of filter",
+		`category not = "a"`:       "expected 'in' after 'not'",
+		`price > 10 && price < 20`: "unexpected character",
+	}
+	for filter, want := range cases {
+		_, err := parseFilter(filter)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected error containing %q, got %v", filter, want, err)
+		}
+	}
+}
+
+func TestSearchProductsMultiKeySort(t *testing.T) {
+	newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Premium", 999),
+		testProduct("2", "Chair", "Furniture", "Budget", 49),
+		testProduct("3", "Phone", "Electronics", "Budget", 1299),
+		testProduct("4", "Desk", "Furniture", "Premium", 199),
+	)
+
+	result, err := executeToolCall(context.Background(), "search_products", map[string]interface{}{
+		"sort":   "category asc, price desc",
+		"filter": "price > 100",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range result.(productPage).Products {
+		got = append(got, p["id"].(string))
+	}
+	if strings.Join(got, ",") != "3,1,4" {
+		t.Errorf("Expected 3,1,4, got %v", got)
+	}
+
+	if _, err := executeToolCall(context.Background(), "search_products", map[string]interface{}{"filter": "price >"}); err == nil || !strings.Contains(err.Error(), "invalid 'filter' argument") {
+		t.Errorf("Expected a filter parse error, got %v", err)
+	}
+	if _, err := executeToolCall(context.Background(), "search_products", map[string]interface{}{"sort": "price sideways"}); err == nil {
+		t.Error("Expected an error for an invalid sort direction")
+	}
+}
@@ -0,0 +1,228 @@
+// Package main - fuzzy.go
+//
+// This file resolves product names that do not match exactly, for get_product_by_name.
+//
+// Key Responsibilities:
+//   - Score every catalog product name against the requested name
+//   - Return the best match when it is clear, or a ranked "did you mean" list when it is not
+//
+// Resolution order:
+//  1. Exact name: GET /products/{name} on the product service (unchanged behavior)
+//  2. Case-insensitive name over the whole catalog (score 1.0)
+//  3. Fuzzy: the best of edit-distance similarity (typos and transpositions), token
+//     overlap and containment, computed on normalized names (lowercase, punctuation
+//     and spacing removed)
+//
+// A fuzzy match is returned only if it scores at least fuzzyAcceptScore, leads the
+// runner-up by fuzzyLeadMargin and has the same numbers as the query (model numbers such
+// as "Laptop5" and "Laptop7" name different products); otherwise the candidates scoring at
+// least fuzzySuggestScore are returned as suggestions. Queries longer than
+// maxFuzzyQueryLength are not matched, since the edit distance grows with the product of
+// both lengths.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	fuzzyAcceptScore  = 0.85
+	fuzzyLeadMargin   = 0.1
+	fuzzySuggestScore = 0.5
+	maxSuggestions    = 5
+
+	maxFuzzyQueryLength = 100 // characters
+)
+
+// nameSuggestion is one ranked candidate for a product name
+type nameSuggestion struct {
+	ID       interface{} `json:"id"`
+	Name     string      `json:"name"`
+	Category interface{} `json:"category,omitempty"`
+	Score    float64     `json:"score"`
+}
+
+// nameResolution is the result of get_product_by_name when the exact lookup failed
+type nameResolution struct {
+	Query       string                 `json:"query"`
+	Match       string                 `json:"match"` // case_insensitive, fuzzy or ambiguous
+	Score       float64                `json:"score,omitempty"`
+	Product     map[string]interface{} `json:"product,omitempty"`
+	Suggestions []nameSuggestion       `json:"suggestions,omitempty"`
+}
+
+// isNotFound reports whether err is a 404 from the product service
+func isNotFound(err error) bool {
+	var statusErr *backendStatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+// resolveProductName ranks products by how well their name matches query
+func resolveProductName(products []map[string]interface{}, query string) (nameResolution, error) {
+	result := nameResolution{Query: query}
+	if len([]rune(query)) > maxFuzzyQueryLength {
+		return result, fmt.Errorf("no product named %q; names longer than %d characters are not matched approximately", query, maxFuzzyQueryLength)
+	}
+
+	var ranked []nameSuggestion
+	byID := map[string]map[string]interface{}{}
+	for _, p := range products {
+		name, _ := p["name"].(string)
+		if name == "" {
+			continue
+		}
+		score := nameSimilarity(query, name)
+		if strings.EqualFold(strings.TrimSpace(query), strings.TrimSpace(name)) {
+			score = 1
+		}
+		if score < fuzzySuggestScore {
+			continue
+		}
+		ranked = append(ranked, nameSuggestion{ID: p["id"], Name: name, Category: p["category"], Score: score})
+		byID[fmt.Sprint(p["id"])] = p
+	}
+	if len(ranked) == 0 {
+		return result, fmt.Errorf("no product named %q and no similar names found", query)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	if len(ranked) > maxSuggestions {
+		ranked = ranked[:maxSuggestions]
+	}
+
+	best := ranked[0]
+	runnerUp := 0.0
+	if len(ranked) > 1 {
+		runnerUp = ranked[1].Score
+	}
+	switch {
+	case best.Score == 1 && runnerUp < 1:
+		result.Match = "case_insensitive"
+	case best.Score >= fuzzyAcceptScore && best.Score-runnerUp >= fuzzyLeadMargin &&
+		slices.Equal(nameNumbers(query), nameNumbers(best.Name)):
+		result.Match = "fuzzy"
+	default:
+		result.Match = "ambiguous"
+		result.Suggestions = ranked
+		return result, nil
+	}
+	result.Score = best.Score
+	result.Product = byID[fmt.Sprint(best.ID)]
+	result.Suggestions = ranked[1:]
+	return result, nil
+}
+
+// nameSimilarity scores two names between 0 and 1
+func nameSimilarity(a, b string) float64 {
+	ta, tb := nameTokens(a), nameTokens(b)
+	ca, cb := strings.Join(ta, ""), strings.Join(tb, "")
+	if ca == "" || cb == "" {
+		return 0
+	}
+	if ca == cb {
+		// same name apart from case, spacing or punctuation
+		return 0.98
+	}
+
+	score := editSimilarity(ca, cb)
+	if s := tokenOverlap(ta, tb); s > score {
+		score = s
+	}
+	// "laptop" for "Laptop5": containment counts more the more of the name it covers
+	if len(ca) >= 3 && strings.Contains(cb, ca) {
+		if s := 0.6 + 0.35*float64(len(ca))/float64(len(cb)); s > score {
+			score = s
+		}
+	}
+	return roundScore(score)
+}
+
+// nameTokens lowercases a name and splits it into letter/digit runs
+func nameTokens(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// editSimilarity is 1 - editDistance(a, b) / max(len(a), len(b))
+func editSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	return 1 - float64(editDistance(ra, rb))/float64(longest)
+}
+
+// editDistance is the Levenshtein distance with adjacent transpositions counted as one
+// edit (optimal string alignment), so "chiar" is one typo away from "chair"
+func editDistance(a, b []rune) int {
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(a)][len(b)]
+}
+
+// tokenOverlap is the share of tokens that match between two names, where tokens match
+// if they are equal or, for longer words without digits, one edit apart (model numbers
+// such as "laptop5" and "laptop7" must match exactly)
+func tokenOverlap(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	used := make([]bool, len(b))
+	matched := 0
+	for _, ta := range a {
+		for j, tb := range b {
+			if used[j] {
+				continue
+			}
+			if ta == tb || (isFuzzyWord(ta) && isFuzzyWord(tb) && editDistance([]rune(ta), []rune(tb)) <= 1) {
+				used[j] = true
+				matched++
+				break
+			}
+		}
+	}
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+	// slightly below an exact match even when every token matches in a different order
+	return 0.95 * float64(matched) / float64(longest)
+}
+
+func isFuzzyWord(token string) bool {
+	return len(token) >= 4 && !strings.ContainsFunc(token, unicode.IsDigit)
+}
+
+func roundScore(s float64) float64 {
+	return float64(int(s*1000+0.5)) / 1000
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	cases := []struct {
+		query, name string
+		min, max    float64
+	}{
+		{"laptop 5", "Laptop5", 0.98, 0.98},
+		{"Laptpo5", "Laptop5", 0.85, 0.86},
+		{"Laptop7", "Laptop5", 0.85, 0.86},
+		{"office chiar", "Office Chair", 0.9, 0.95},
+		{"iphone", "iPhone 17 Pro", 0.75, 0.9},
+		{"pro iphone 17", "iPhone 17 Pro", 0.95, 0.95},
+		{"chair", "Laptop5", 0, 0.3},
+	}
+	for _, tc := range cases {
+		if got := nameSimilarity(tc.query, tc.name); got < tc.min || got > tc.max {
+			t.Errorf("%q vs %q: expected a score in [%.2f, %.2f], got %.3f", tc.query, tc.name, tc.min, tc.max, got)
+		}
+	}
+}
+
+func TestGetProductByNameResolution(t *testing.T) {
+	newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Laptops", 999),
+		testProduct("2", "Laptop7", "Electronics", "Laptops", 1299),
+		testProduct("3", "Office Chair", "Furniture", "Budget", 149),
+	)
+	lookup := func(name string) interface{} {
+		t.Helper()
+		result, err := executeToolCall(context.Background(), "get_product_by_name", map[string]interface{}{"name": name})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		return result
+	}
+
+	if product, ok := lookup("Laptop5").(map[string]interface{}); !ok || product["id"] != "1" {
+		t.Errorf("Expected the exact match from the product service, got %#v", product)
+	}
+	if res := lookup("LAPTOP5").(nameResolution); res.Match != "case_insensitive" || res.Product["id"] != "1" {
+		t.Errorf("Expected a case-insensitive match, got %#v", res)
+	}
+	if res := lookup("office chiar").(nameResolution); res.Match != "fuzzy" || res.Product["id"] != "3" {
+		t.Errorf("Expected a fuzzy match on Office Chair, got %#v", res)
+	}
+	res := lookup("laptop")
+	if r := res.(nameResolution); r.Match != "ambiguous" || len(r.Suggestions) != 2 || r.Product != nil {
+		t.Errorf("Expected two ranked suggestions, got %#v", res)
+	}
+
+	if _, err := executeToolCall(context.Background(), "get_product_by_name", map[string]interface{}{"name": "Refrigerator"}); err == nil {
+		t.Error("Expected an error when nothing is similar")
+	}
+	if _, err := executeToolCall(context.Background(), "get_product_by_name", map[string]interface{}{"name": strings.Repeat("Laptop", 50)}); err == nil {
+		t.Error("Expected an overlong name not to be matched")
+	}
+}
+
+func TestResolveProductNameKeepsModelNumbers(t *testing.T) {
+	products := []map[string]interface{}{
+		testProduct("2", "Laptop7", "Electronics", "Laptops", 1299),
+		testProduct("3", "Office Chair", "Furniture", "Budget", 149),
+	}
+	res, err := resolveProductName(products, "Laptop5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Match != "ambiguous" || res.Product != nil || len(res.Suggestions) != 1 || res.Suggestions[0].Name != "Laptop7" {
+		t.Errorf("Expected Laptop7 only as a suggestion for Laptop5, got %#v", res)
+	}
+	if res, _ := resolveProductName(products, "laptop 7"); res.Match != "fuzzy" || res.Product["id"] != "2" {
+		t.Errorf("Expected a match with the same model number, got %#v", res)
+	}
+}
@@ -13,6 +13,16 @@
 //   - handleInitialize: Handles 'initialize' method for protocol handshake
 //   - handleToolsList: Handles 'tools/list' method to return available tools
 //   - handleToolCall: Handles 'tools/call' method to execute specific tools
+//   - handleResourcesList / handleResourcesRead: Handle 'resources/list' and 'resources/read' (resources.go)
+//   - handleClientResponse: Delivers client responses (e.g. to elicitation/create) to the waiting tool call
+//
+// Sessions (see sessions.go):
+//   - initialize creates a session and returns it in the Mcp-Session-Id header
+//   - an unknown Mcp-Session-Id (expired, or created by another instance) is ignored, except
+//     for requests that need the session: client responses, DELETE, and tool calls that ask
+//     for confirmation get 404 so the client re-initializes
+//   - DELETE /mcp with Mcp-Session-Id terminates the session
+//   - notifications and client responses are acknowledged with 202 Accepted
 //
 // JSON-RPC Error Codes:
 //   - -32700: Parse error (invalid JSON or request body read failure)
@@ -21,30 +31,96 @@
 //   - -32602: Invalid params (missing or malformed parameters)
 //   - -32603: Internal error (tool execution failure)
 //
+// Metrics:
+//   - mcpHandler records per-method request counts, latency and JSON-RPC error codes
+//   - handleToolCall records per-tool call counts, latency and error kinds (see metrics.go)
+//
+// Tracing:
+//   - mcpHandler continues the trace from an inbound traceparent header and opens an "mcp <method>" span
+//   - handleToolCall opens a "tools/call <tool>" span; backend calls are children of it (see tracing.go)
+//
 // Flow:
-//   1. Validate HTTP method (POST only)
+//   1. Validate HTTP method (POST, or DELETE to end a session) and the session id
 //   2. Read and parse request body
 //   3. Validate JSON-RPC 2.0 format
 //   4. Route to method-specific handler
-//   5. Send formatted response or error
+//   5. Send formatted response or error (as an SSE event if the call asked for confirmation)
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const serverVersion = "1.20.0"
+
+// supportedProtocolVersions lists the MCP protocol versions this server speaks, newest first
+var supportedProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
 // All HTTP handler functions for MCP server
 func mcpHandler(config Config) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	return func(httpWriter http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			http.Error(httpWriter, "Method Not Allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
+		// a session may be unknown because the instance restarted or the request reached
+		// another one; only requests that need the session (see below) are refused
+		var sess *session
+		sessionID := r.Header.Get("Mcp-Session-Id")
+		if sessionID != "" {
+			sess = sessions.get(sessionID)
+		}
+		if r.Method == http.MethodDelete {
+			if sessionID == "" {
+				http.Error(httpWriter, "Missing Mcp-Session-Id header", http.StatusBadRequest)
+				return
+			}
+			if sess == nil {
+				http.Error(httpWriter, "Session not found", http.StatusNotFound)
+				return
+			}
+			sessions.remove(sess.ID)
+			httpWriter.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, "mcp", trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attribute.String("rpc.system", "jsonrpc")),
+		)
+
+		rpcInFlight.Inc()
+		defer rpcInFlight.Dec()
+		start := time.Now()
+		method := "unknown"
+		w := &rpcResponseWriter{ResponseWriter: httpWriter}
+		defer func() {
+			rpcRequests.WithLabelValues(method).Inc()
+			rpcDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+			if w.errorCode != 0 {
+				rpcErrors.WithLabelValues(method, strconv.Itoa(w.errorCode)).Inc()
+				span.SetAttributes(attribute.Int("rpc.jsonrpc.error_code", w.errorCode))
+				span.SetStatus(codes.Error, "JSON-RPC error "+strconv.Itoa(w.errorCode))
+			}
+			span.End()
+		}()
+
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
 			sendJSONRPCError(w, nil, -32700, "Parse error", "Failed to read request body")
@@ -62,15 +138,51 @@ func mcpHandler(config Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("Received JSON-RPC request: method=%s, id=%v", req.Method, req.ID)
+		// a message without a method is the client's response to a server-initiated request
+		if req.Method == "" {
+			method = "response"
+			span.SetName("mcp response")
+			if sess == nil && sessionID != "" {
+				http.Error(w, "Session not found", http.StatusNotFound)
+				return
+			}
+			handleClientResponse(w, body, sess)
+			return
+		}
+
+		slog.Debug("Received JSON-RPC request", "method", req.Method, "id", req.ID)
+		method = rpcMethodLabel(req.Method)
+		span.SetName("mcp " + method)
+		span.SetAttributes(
+			attribute.String("rpc.method", req.Method),
+			attribute.String("rpc.jsonrpc.request_id", fmt.Sprint(req.ID)),
+		)
+
+		if strings.HasPrefix(req.Method, "notifications/") {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		ctx = withSession(ctx, sess)
+		ctx = withElicitor(ctx, &elicitor{
+			w:             w,
+			session:       sess,
+			staleSession:  sess == nil && sessionID != "",
+			acceptsStream: strings.Contains(r.Header.Get("Accept"), "text/event-stream"),
+			timeout:       config.Confirmation.Timeout.Duration,
+		})
 
 		switch req.Method {
 		case "initialize":
 			handleInitialize(w, req)
 		case "tools/list":
-			handleToolsList(w, req)
+			handleToolsList(w, req, config)
 		case "tools/call":
-			handleToolCall(w, req, config)
+			handleToolCall(ctx, w, req, config)
+		case "resources/list":
+			handleResourcesList(w, req)
+		case "resources/read":
+			handleResourcesRead(ctx, w, req)
 		default:
 			sendJSONRPCError(w, req.ID, -32601, "Method not found", fmt.Sprintf("Unknown method: %s", req.Method))
 		}
@@ -87,32 +199,63 @@ func handleInitialize(w http.ResponseWriter, req JSONRPCRequest) {
 		}
 	}
 
+	protocolVersion := supportedProtocolVersions[len(supportedProtocolVersions)-1]
+	for _, v := range supportedProtocolVersions {
+		if v == params.ProtocolVersion {
+			protocolVersion = v
+		}
+	}
+	sess := sessions.create(params, protocolVersion)
+	w.Header().Set("Mcp-Session-Id", sess.ID)
+
 	result := InitializeResult{
-		ProtocolVersion: "2024-11-05",
+		ProtocolVersion: protocolVersion,
 		Capabilities: ServerCapabilities{
-			Tools: map[string]interface{}{},
+			Tools:     map[string]interface{}{},
+			Resources: map[string]interface{}{},
 		},
 		ServerInfo: ServerInfo{
 			Name:    "ravi-mcp-server",
-			Version: "1.0.0",
+			Version: serverVersion,
 		},
 	}
 
 	sendJSONRPCResponse(w, req.ID, result)
-	log.Println("Sent initialize response to client.")
+	slog.Info("Sent initialize response to client", "session", sess.ID, "protocol", protocolVersion)
+}
+
+// handleClientResponse hands a JSON-RPC response from the client to the tool call waiting for it
+func handleClientResponse(w http.ResponseWriter, body []byte, sess *session) {
+	var resp JSONRPCResponse
+	if err := json.Unmarshal(body, &resp); err != nil || (resp.Result == nil && resp.Error == nil) {
+		sendJSONRPCError(w, nil, -32600, "Invalid Request", "Missing method")
+		return
+	}
+	if sess == nil || !sess.deliver(resp) {
+		slog.Warn("Ignoring client response with no pending request", "id", resp.ID)
+	}
+	w.WriteHeader(http.StatusAccepted)
 }
 
-func handleToolsList(w http.ResponseWriter, req JSONRPCRequest) {
-	// Build tool schemas only
-	result := map[string]interface{}{
-		"tools": tools,
+func handleToolsList(w http.ResponseWriter, req JSONRPCRequest, config Config) {
+	// Build tool schemas only, one page at a time (see pagination.go)
+	params, _ := req.Params.(map[string]interface{})
+	enabled := config.enabledTools()
+	start, end, nextCursor, err := listPageBounds("tools/list", len(enabled), params)
+	if err != nil {
+		sendJSONRPCError(w, req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+	result := ToolsListResult{
+		Tools:      enabled[start:end],
+		NextCursor: nextCursor,
 	}
 
 	sendJSONRPCResponse(w, req.ID, result)
-	log.Println("Sent tools list with schemas to client.")
+	slog.Debug("Sent tools list with schemas to client")
 }
 
-func handleToolCall(w http.ResponseWriter, req JSONRPCRequest, config Config) {
+func handleToolCall(ctx context.Context, w http.ResponseWriter, req JSONRPCRequest, config Config) {
 	var params ToolCallParams
 	if req.Params == nil {
 		sendJSONRPCError(w, req.ID, -32602, "Invalid params", "Missing tool call parameters")
@@ -125,16 +268,69 @@ func handleToolCall(w http.ResponseWriter, req JSONRPCRequest, config Config) {
 		return
 	}
 
-	log.Printf("Received tool call: %s", params.Name)
+	caller := callerFromContext(ctx)
+	slog.Info("Received tool call", "tool", params.Name, "caller", caller)
+
+	if !config.toolEnabled(params.Name) {
+		errResult := CallToolResult{
+			Content: []TextContent{{Type: "text", Text: fmt.Sprintf("tool not enabled: %s", params.Name)}},
+			IsError: true,
+		}
+		sendJSONRPCResponse(w, req.ID, errResult)
+		return
+	}
 
 	// pass tool name and arguments only to executeToolCall
 	args, _ := params.Arguments.(map[string]interface{})
-	result, err := executeToolCall(params.Name, args)
+	tool := toolLabel(params.Name)
+	toolCalls.WithLabelValues(tool).Inc()
+	toolInFlight.WithLabelValues(tool).Inc()
+	start := time.Now()
+	ctx, span := startToolSpan(ctx, params.Name)
+	span.SetAttributes(attribute.String("enduser.id", caller))
+	el := elicitorFromContext(ctx)
+	// confirmation and the audit log share one plan of the call (see dryrun.go)
+	ctx = withPlanMemo(ctx)
+	var result interface{}
+	err := confirmMutation(ctx, el, config.Confirmation, params.Name, args)
+	if errors.Is(err, errSessionNotFound) {
+		endSpanWithError(span, err)
+		toolInFlight.WithLabelValues(tool).Dec()
+		toolErrors.WithLabelValues(tool, toolErrorKind(err)).Inc()
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	if err == nil {
+		result, err = executeToolCall(ctx, params.Name, args)
+	}
+	// after an elicitation the response is an SSE stream; the result is its last event
+	w = el.responseWriter(w)
+	endSpanWithError(span, err)
+	toolDuration.WithLabelValues(tool).Observe(time.Since(start).Seconds())
+	toolInFlight.WithLabelValues(tool).Dec()
 	if err != nil {
+		toolErrors.WithLabelValues(tool, toolErrorKind(err)).Inc()
 		errResult := CallToolResult{
 			Content: []TextContent{{Type: "text", Text: err.Error()}},
 			IsError: true,
 		}
+		// conflicts carry the current product state so the agent can decide what to do,
+		// validation errors every broken rule, failed atomic batches what was rolled back and
+		// imports the invalid rows or the chunks that were written
+		var conflict *conflictError
+		var invalid *validationError
+		var rolledBack *rollbackError
+		var importErr *importError
+		switch {
+		case errors.As(err, &conflict):
+			errResult.StructuredContent = conflict
+		case errors.As(err, &invalid):
+			errResult.StructuredContent = invalid
+		case errors.As(err, &rolledBack):
+			errResult.StructuredContent = rolledBack
+		case errors.As(err, &importErr):
+			errResult.StructuredContent = importErr
+		}
 		sendJSONRPCResponse(w, req.ID, errResult)
 		return
 	}
@@ -0,0 +1,333 @@
+// Package main - history.go
+//
+// This file keeps the version history of every product the server writes or reads, for
+// get_product_history and the 'as_of' argument of get_product and list_products.
+//
+// Key Responsibilities:
+//   - Record a version whenever a product read from the product service differs from the
+//     last known version ("observed")
+//   - Record the before and after state of every audited mutation, attributed to the tool
+//     call that caused it (see audit.go)
+//   - Reconstruct a product or the whole catalog as it was at a past time
+//
+// Sources of versions:
+//   - Reads: every product returned by fetchProducts/fetchProduct/get_product from the
+//     product service; responses served from the read cache are not observed, since they
+//     may predate later writes. A read of the full catalog (GET /products) also records
+//     products missing from it as deleted.
+//   - Writes: audited mutations (requires audit.enabled); the version carries the tool,
+//     caller and change id
+//   - Startup: records from the audit log file are replayed, so write history survives
+//     restarts when audit.file is set
+//
+// Point-in-time views:
+//
+//	The state of a product at time T is its latest version at or before T. Changes made
+//	directly against the product service are only known from the next read, so their
+//	version time is when the server noticed them. A catalog view is only available from
+//	the first full catalog read onwards, a product view from the product's first version.
+//	History is kept in memory, up to maxProductVersions versions per product. Products that
+//	exist are bounded by the size of the catalog; of the deleted ones, the latest
+//	maxDeletedProducts are kept. Forgetting an older one moves the start of catalog views
+//	to its deletion, since views before it would miss the product.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxProductVersions bounds the versions kept per product; older ones are dropped
+const maxProductVersions = 100
+
+// maxDeletedProducts bounds the deleted products whose history is kept; the ones deleted
+// longest ago are forgotten first
+const maxDeletedProducts = 1000
+
+// productVersion is one recorded state of a product
+type productVersion struct {
+	Version  int                    `json:"version"` // 1 for the oldest version ever recorded
+	Time     time.Time              `json:"time"`
+	Source   string                 `json:"source"` // "observed" or "tool"
+	Tool     string                 `json:"tool,omitempty"`
+	ChangeID string                 `json:"change_id,omitempty"`
+	Caller   string                 `json:"caller,omitempty"`
+	Deleted  bool                   `json:"deleted,omitempty"`
+	Product  map[string]interface{} `json:"product,omitempty"`
+	Fields   map[string]fieldChange `json:"fields,omitempty"` // differences to the previous version
+}
+
+// productHistory holds the versions of every known product, oldest first
+type productHistory struct {
+	mu       sync.Mutex
+	products map[string][]productVersion
+	dropped  map[string]int       // versions dropped per product beyond maxProductVersions
+	deleted  map[string]time.Time // products whose latest version is a deletion
+	since    time.Time            // first full catalog read
+	now      func() time.Time     // also the clock of audit records (see auditMutation)
+}
+
+var catalogHistory = newProductHistory()
+
+func newProductHistory() *productHistory {
+	return &productHistory{
+		products: map[string][]productVersion{},
+		dropped:  map[string]int{},
+		deleted:  map[string]time.Time{},
+		now:      time.Now,
+	}
+}
+
+// observe records products read from the product service; complete reports whether they
+// are the whole catalog, in which case known products missing from it are marked deleted
+func (h *productHistory) observe(products []map[string]interface{}, complete bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.now().UTC()
+	seen := make(map[string]bool, len(products))
+	for _, p := range products {
+		id := fmt.Sprint(p["id"])
+		if p["id"] == nil {
+			continue
+		}
+		seen[id] = true
+		h.add(id, productVersion{Time: now, Source: "observed", Product: p})
+	}
+	if !complete {
+		return
+	}
+	for id, versions := range h.products {
+		if !seen[id] && !versions[len(versions)-1].Deleted {
+			h.add(id, productVersion{Time: now, Source: "observed", Deleted: true})
+		}
+	}
+	if h.since.IsZero() {
+		h.since = now
+	}
+}
+
+// recordChange records the states before and after an audited mutation
+func (h *productHistory) recordChange(rec *auditRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	done := rec.Time.Add(time.Duration(rec.DurationMs) * time.Millisecond)
+	for _, c := range rec.Changes {
+		if c.ProductID == "" || reflect.DeepEqual(c.Before, c.After) {
+			continue
+		}
+		if c.Before != nil {
+			h.add(c.ProductID, productVersion{Time: rec.Time, Source: "observed", Product: c.Before})
+		}
+		h.add(c.ProductID, productVersion{
+			Time:     done,
+			Source:   "tool",
+			Tool:     rec.Tool,
+			ChangeID: rec.ID,
+			Caller:   rec.Caller,
+			Deleted:  c.After == nil,
+			Product:  c.After,
+		})
+	}
+}
+
+// replay records the changes of audit records read at startup, oldest first
+func (h *productHistory) replay(recs []*auditRecord) {
+	for _, rec := range recs {
+		h.recordChange(rec)
+	}
+}
+
+// add must be called with h.mu held. A version equal to the one before it is dropped, but
+// a tool version replaces the attribution of an equal observed version (the read after a
+// write can be observed before the write is recorded).
+func (h *productHistory) add(id string, v productVersion) {
+	versions := h.products[id]
+	i := sort.Search(len(versions), func(i int) bool { return versions[i].Time.After(v.Time) })
+	if i > 0 {
+		prev := &versions[i-1]
+		if prev.Deleted == v.Deleted && reflect.DeepEqual(prev.Product, v.Product) {
+			if v.Source == "tool" && prev.Source == "observed" {
+				prev.Source, prev.Tool, prev.ChangeID, prev.Caller = v.Source, v.Tool, v.ChangeID, v.Caller
+			}
+			return
+		}
+	} else if v.Deleted {
+		// nothing is known about a product before it was seen
+		return
+	}
+	if i < len(versions) && versions[i].Deleted == v.Deleted && reflect.DeepEqual(versions[i].Product, v.Product) {
+		// the same state was already observed later; move it back to the earlier time
+		versions[i].Time = v.Time
+		if v.Source == "tool" {
+			versions[i].Source, versions[i].Tool, versions[i].ChangeID, versions[i].Caller = v.Source, v.Tool, v.ChangeID, v.Caller
+		}
+		return
+	}
+
+	if v.Product != nil {
+		v.Product = copyParams(v.Product)
+	}
+	versions = append(versions, productVersion{})
+	copy(versions[i+1:], versions[i:])
+	versions[i] = v
+	for j := i; j < len(versions) && j <= i+1; j++ {
+		versions[j].Fields = nil
+		if j > 0 {
+			versions[j].Fields = diffProducts(versions[j-1].Product, versions[j].Product)
+		}
+	}
+	if len(versions) > maxProductVersions {
+		h.dropped[id] += len(versions) - maxProductVersions
+		versions = versions[len(versions)-maxProductVersions:]
+	}
+	for j := range versions {
+		versions[j].Version = h.dropped[id] + j + 1
+	}
+	h.products[id] = versions
+
+	if last := versions[len(versions)-1]; last.Deleted {
+		h.deleted[id] = last.Time
+		if len(h.deleted) > maxDeletedProducts {
+			h.forgetOldestDeleted()
+		}
+	} else {
+		delete(h.deleted, id)
+	}
+}
+
+// forgetOldestDeleted must be called with h.mu held. It drops the history of the product
+// deleted longest ago.
+func (h *productHistory) forgetOldestDeleted() {
+	var oldestID string
+	var oldest time.Time
+	for id, at := range h.deleted {
+		if oldestID == "" || at.Before(oldest) {
+			oldestID, oldest = id, at
+		}
+	}
+	delete(h.products, oldestID)
+	delete(h.dropped, oldestID)
+	delete(h.deleted, oldestID)
+	if !h.since.IsZero() && h.since.Before(oldest) {
+		h.since = oldest
+	}
+}
+
+// diffProducts returns the fields that differ between two product states
+func diffProducts(before, after map[string]interface{}) map[string]fieldChange {
+	fields := map[string]fieldChange{}
+	for k, v := range after {
+		if !reflect.DeepEqual(before[k], v) {
+			fields[k] = fieldChange{From: before[k], To: v}
+		}
+	}
+	for k, v := range before {
+		if _, ok := after[k]; !ok {
+			fields[k] = fieldChange{From: v}
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// versions returns a copy of the versions of a product, oldest first
+func (h *productHistory) versions(id string) []productVersion {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]productVersion(nil), h.products[id]...)
+}
+
+// productAt returns the version of a product at time t
+func (h *productHistory) productAt(id string, t time.Time) (productVersion, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	versions := h.products[id]
+	i := sort.Search(len(versions), func(i int) bool { return versions[i].Time.After(t) })
+	if i == 0 {
+		return productVersion{}, false
+	}
+	return versions[i-1], true
+}
+
+// catalogAt reconstructs the catalog at time t, ordered by product id
+func (h *productHistory) catalogAt(t time.Time) ([]map[string]interface{}, error) {
+	h.mu.Lock()
+	since := h.since
+	ids := make([]string, 0, len(h.products))
+	for id := range h.products {
+		ids = append(ids, id)
+	}
+	h.mu.Unlock()
+
+	if since.IsZero() {
+		return nil, fmt.Errorf("no catalog history recorded yet; 'as_of' is available once the catalog has been read")
+	}
+	if t.Before(since) {
+		return nil, fmt.Errorf("invalid 'as_of' argument: catalog history starts at %s", since.Format(time.RFC3339))
+	}
+	sort.Strings(ids)
+	products := []map[string]interface{}{}
+	for _, id := range ids {
+		if v, ok := h.productAt(id, t); ok && !v.Deleted {
+			products = append(products, v.Product)
+		}
+	}
+	return products, nil
+}
+
+// asOfParam returns the 'as_of' argument, or the zero time if it is absent
+func asOfParam(params map[string]interface{}) (time.Time, error) {
+	v, present := params["as_of"]
+	if !present {
+		return time.Time{}, nil
+	}
+	s, _ := v.(string)
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid 'as_of' argument: must be an RFC 3339 timestamp, e.g. '2026-10-01T00:00:00Z'")
+	}
+	return t, nil
+}
+
+// getProductAsOf returns the version of a product at the requested time
+func getProductAsOf(id string, t time.Time) (interface{}, error) {
+	v, ok := catalogHistory.productAt(id, t)
+	if !ok {
+		return nil, fmt.Errorf("no recorded version of product %s at or before %s; see get_product_history", id, t.Format(time.RFC3339))
+	}
+	if v.Deleted {
+		return nil, fmt.Errorf("product %s was deleted at %s", id, v.Time.Format(time.RFC3339))
+	}
+	return v, nil
+}
+
+// getProductHistory returns every recorded version of a product, newest first
+func getProductHistory(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	id, ok := params["id"].(string)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("missing or invalid product id")
+	}
+	// make sure the current state is part of the history
+	if _, err := fetchProduct(ctx, id); err != nil && !isNotFound(err) {
+		slog.Warn("Failed to read product for its history", "product", id, "error", err)
+	}
+
+	versions := catalogHistory.versions(id)
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no history recorded for product %s", id)
+	}
+	for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+	return map[string]interface{}{
+		"product_id": id,
+		"versions":   versions,
+	}, nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClock is a clock that moves by a millisecond per reading, so the steps of one call
+// stay ordered, and otherwise only when advanced
+type testClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *testClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(time.Millisecond)
+	return c.t
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// withProductHistory replaces the product history for the duration of a test; its clock
+// (also used for audit records) is the returned test clock
+func withProductHistory(t *testing.T) *testClock {
+	t.Helper()
+	clock := &testClock{t: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)}
+	history := newProductHistory()
+	history.now = clock.now
+	swapGlobal(t, &catalogHistory, history)
+	return clock
+}
+
+// between returns a time after the previous step and before the next one
+func (c *testClock) between() string {
+	c.advance(time.Second)
+	defer c.advance(time.Second)
+	return c.now().Format(time.RFC3339Nano)
+}
+
+func TestProductHistoryAndAsOf(t *testing.T) {
+	fake := newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Laptops", 999),
+		testProduct("2", "Office Chair", "Furniture", "Budget", 149),
+	)
+	swapGlobal[auditSink](t, &auditLog, newMemoryAuditSink(100))
+	clock := withProductHistory(t)
+	ctx := context.Background()
+
+	beforeHistory := clock.between()
+	if _, err := executeToolCall(ctx, "list_products", map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	original := clock.between()
+	if _, err := executeToolCall(ctx, "update_product", map[string]interface{}{"id": "1", "price": 899.0}); err != nil {
+		t.Fatal(err)
+	}
+	updated := clock.between()
+	// a change made directly against the product service is noticed on the next read
+	fake.mu.Lock()
+	fake.products["1"]["price"] = 849.0
+	delete(fake.products, "2")
+	fake.mu.Unlock()
+	if _, err := executeToolCall(ctx, "list_products", map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := executeToolCall(ctx, "get_product_history", map[string]interface{}{"id": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := result.(map[string]interface{})["versions"].([]productVersion)
+	if len(versions) != 3 {
+		t.Fatalf("Expected 3 versions, got %+v", versions)
+	}
+	if versions[0].Source != "observed" || versions[0].Product["price"] != 849.0 || versions[0].Version != 3 {
+		t.Errorf("Expected the observed direct change first, got %+v", versions[0])
+	}
+	if v := versions[1]; v.Source != "tool" || v.Tool != "update_product" || v.ChangeID == "" || v.Fields["price"].From != 999.0 {
+		t.Errorf("Expected the update attributed to its tool call, got %+v", v)
+	}
+
+	result, err = executeToolCall(ctx, "get_product", map[string]interface{}{"id": "1", "as_of": original})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := result.(productVersion); v.Product["price"] != 999.0 {
+		t.Errorf("Expected the original price as of %s, got %+v", original, v)
+	}
+
+	result, err = executeToolCall(ctx, "list_products", map[string]interface{}{"as_of": updated})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := result.(productPage)
+	if page.Total != 2 || page.Products[0]["price"] != 899.0 {
+		t.Errorf("Expected both products with the updated price as of %s, got %+v", updated, page.Products)
+	}
+	result, _ = executeToolCall(ctx, "list_products", map[string]interface{}{})
+	if page := result.(productPage); page.Total != 1 {
+		t.Errorf("Expected the deleted chair to be gone now, got %d products", page.Total)
+	}
+	if _, err := executeToolCall(ctx, "get_product", map[string]interface{}{"id": "2", "as_of": clock.between()}); err == nil {
+		t.Error("Expected an error for a product that was deleted at as_of")
+	}
+	if _, err := executeToolCall(ctx, "list_products", map[string]interface{}{"as_of": beforeHistory}); err == nil {
+		t.Error("Expected an error for an as_of before the history starts")
+	}
+	if _, err := executeToolCall(ctx, "list_products", map[string]interface{}{"as_of": "last week"}); err == nil {
+		t.Error("Expected an error for an invalid as_of")
+	}
+}
+
+func TestProductHistoryReplaysAuditLog(t *testing.T) {
+	withProductHistory(t)
+	at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	catalogHistory.replay([]*auditRecord{{
+		ID: "chg_1", Time: at, Tool: "update_product", Caller: "pricing-agent", Outcome: "ok",
+		Changes: []auditChange{{
+			Action:    "update",
+			ProductID: "1",
+			Before:    testProduct("1", "Laptop5", "Electronics", "Laptops", 999),
+			After:     testProduct("1", "Laptop5", "Electronics", "Laptops", 899),
+		}},
+	}})
+
+	versions := catalogHistory.versions("1")
+	if len(versions) != 2 || versions[1].Caller != "pricing-agent" || versions[1].Fields["price"].To != 899.0 {
+		t.Fatalf("Unexpected versions %+v", versions)
+	}
+	if v, ok := catalogHistory.productAt("1", at.Add(-time.Second)); ok {
+		t.Errorf("Expected no version before the first record, got %+v", v)
+	}
+}
+
+func TestProductHistoryForgetsOldDeletions(t *testing.T) {
+	clock := withProductHistory(t)
+	catalogHistory.observe([]map[string]interface{}{testProduct("keep", "Desk", "Furniture", "Office", 199)}, true)
+	for i := 0; i <= maxDeletedProducts; i++ {
+		id := fmt.Sprint(i)
+		clock.advance(time.Second)
+		catalogHistory.observe([]map[string]interface{}{testProduct(id, "Lamp", "Furniture", "Office", 29)}, false)
+		clock.advance(time.Second)
+		catalogHistory.recordChange(&auditRecord{ID: "chg_" + id, Time: clock.now(), Tool: "delete_product", Changes: []auditChange{{
+			Action: "delete", ProductID: id, Before: testProduct(id, "Lamp", "Furniture", "Office", 29),
+		}}})
+	}
+
+	if versions := catalogHistory.versions("0"); len(versions) != 0 {
+		t.Errorf("Expected the first deleted product to be forgotten, got %+v", versions)
+	}
+	if versions := catalogHistory.versions("1"); len(versions) != 2 || !versions[1].Deleted {
+		t.Errorf("Expected later deletions to be kept, got %+v", versions)
+	}
+	if len(catalogHistory.products) != maxDeletedProducts+1 {
+		t.Errorf("Expected %d products in the history, got %d", maxDeletedProducts+1, len(catalogHistory.products))
+	}
+	if _, err := catalogHistory.catalogAt(time.Date(2026, 10, 1, 12, 0, 1, 0, time.UTC)); err == nil {
+		t.Error("Expected catalog views before the forgotten deletion to be refused")
+	}
+}
+
+func TestProductHistoryIgnoresCachedReads(t *testing.T) {
+	fake := newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Laptops", 999),
+		testProduct("2", "Office Chair", "Furniture", "Budget", 149),
+	)
+	withProductHistory(t)
+	withCatalogCache(t, time.Hour)
+	ctx := context.Background()
+	if _, err := executeToolCall(ctx, "list_products", map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executeToolCall(ctx, "get_product", map[string]interface{}{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the product service changes behind the cache, and a fresh read notices it
+	fake.mu.Lock()
+	fake.products["1"]["price"] = 899.0
+	fake.products["3"] = testProduct("3", "Desk", "Furniture", "Budget", 199)
+	fake.mu.Unlock()
+	if _, err := fetchProduct(withFreshReads(ctx), "3"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fetchProduct(withFreshReads(ctx), "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the stale cached responses record neither a reverted price nor a deleted product
+	if _, err := executeToolCall(ctx, "get_product", map[string]interface{}{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executeToolCall(ctx, "list_products", map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if versions := catalogHistory.versions("1"); len(versions) != 2 || versions[1].Product["price"] != 899.0 {
+		t.Errorf("Expected the original and the changed price only, got %+v", versions)
+	}
+	if versions := catalogHistory.versions("3"); len(versions) != 1 || versions[0].Deleted {
+		t.Errorf("Expected the new product not to be marked deleted, got %+v", versions)
+	}
+}
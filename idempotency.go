@@ -0,0 +1,220 @@
+// Package main - idempotency.go
+//
+// This file implements idempotency keys for create_product and create_multiple_products,
+// so that an agent retrying a create after a timeout does not create duplicate products.
+//
+// Key Responsibilities:
+//   - Remember the result of a successful create under its 'idempotency_key' for
+//     idempotency.window (default 24h)
+//   - Return the remembered result for a repeated call with the same key and arguments
+//     without calling the product service again
+//   - Reject a repeated key with different arguments
+//   - Let a retry that arrives while the first call is still running wait for its result
+//
+// Rules:
+//   - Keys are scoped per caller (API key name, see auth.go) and are at most 255 characters
+//   - Arguments are compared as JSON, together with the tool name; dry_run and the key
+//     itself are not part of the comparison
+//   - Failed calls are not remembered, so they can be retried with the same key; a call
+//     is failed if it returned an error or if any of its writes was answered with a
+//     4xx/5xx status, even when the tool returned the product service's answer as result
+//   - Keys are kept in memory and are forgotten on restart; a window of 0 disables them
+//   - Keys are not shared between instances: on Cloud Run with more than one instance a
+//     retry that reaches another instance creates the product again
+//   - An expired key is dropped when it is used again; keys that are never used again are
+//     dropped by a sweep that runs at most once per idempotencySweepInterval
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+
+	// idempotencySweepInterval is how often expired keys are looked for across the store
+	idempotencySweepInterval = time.Minute
+)
+
+// idempotentTools are the tools that accept an 'idempotency_key' argument. import_products
+// is not one of them: an import that stops at a failed chunk has already written the
+// chunks before it, so there is no single result to remember
+var idempotentTools = map[string]bool{
+	"create_product":           true,
+	"create_multiple_products": true,
+}
+
+var errIdempotencyKeyReused = errors.New("idempotency key reused with different arguments")
+
+// idempotencyEntry is a create call remembered under its key
+type idempotencyEntry struct {
+	fingerprint string
+	done        chan struct{} // closed when the call completed
+	result      interface{}
+	failed      bool
+	expires     time.Time
+}
+
+// idempotencyStore holds the remembered calls by caller and key
+type idempotencyStore struct {
+	mu        sync.Mutex
+	window    time.Duration
+	entries   map[string]*idempotencyEntry
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+var idempotentCalls = newIdempotencyStore(24 * time.Hour)
+
+func newIdempotencyStore(window time.Duration) *idempotencyStore {
+	return &idempotencyStore{
+		window:  window,
+		entries: map[string]*idempotencyEntry{},
+		now:     time.Now,
+	}
+}
+
+// configure applies the idempotency settings and forgets every remembered call
+func (s *idempotencyStore) configure(cfg IdempotencyConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.window = cfg.Window.Duration
+	s.entries = map[string]*idempotencyEntry{}
+}
+
+// idempotencyKeyParam removes 'idempotency_key' from the arguments and returns it; an
+// empty key means the call is not idempotent
+func idempotencyKeyParam(params map[string]interface{}) (string, error) {
+	v, present := params["idempotency_key"]
+	if !present {
+		return "", nil
+	}
+	delete(params, "idempotency_key")
+	key, ok := v.(string)
+	if !ok || key == "" || len(key) > maxIdempotencyKeyLength {
+		return "", fmt.Errorf("invalid 'idempotency_key' argument: must be a non-empty string of at most %d characters", maxIdempotencyKeyLength)
+	}
+	return key, nil
+}
+
+// idempotencyFingerprint identifies a tool call by its tool and arguments
+func idempotencyFingerprint(toolName string, params map[string]interface{}) (string, error) {
+	// encoding/json sorts map keys, so equal arguments give equal fingerprints
+	data, err := json.Marshal(map[string]interface{}{"tool": toolName, "arguments": params})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal params: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+type idempotentWritesContextKey struct{}
+
+// idempotentWrites tracks whether a write of an idempotent call failed
+type idempotentWrites struct {
+	failed atomic.Bool
+}
+
+// noteIdempotentWrite marks the idempotent call running in ctx, if any, as failed when the
+// write failed or the product service answered it with 4xx/5xx
+func noteIdempotentWrite(ctx context.Context, resp *backendResponse, err error) {
+	if w, ok := ctx.Value(idempotentWritesContextKey{}).(*idempotentWrites); ok && (err != nil || resp.StatusCode >= 400) {
+		w.failed.Store(true)
+	}
+}
+
+// do runs a tool call once per caller and key: repeated calls with the same arguments get
+// the result of the first successful one
+func (s *idempotencyStore) do(ctx context.Context, key, toolName string, params map[string]interface{}, run func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	fingerprint, err := idempotencyFingerprint(toolName, params)
+	if err != nil {
+		return nil, err
+	}
+	scoped := callerFromContext(ctx) + "\x00" + key
+
+	for {
+		s.mu.Lock()
+		if s.window <= 0 {
+			s.mu.Unlock()
+			return run(ctx)
+		}
+		now := s.now()
+		if now.After(s.nextSweep) {
+			s.sweepLocked(now)
+		}
+		entry, exists := s.entries[scoped]
+		if exists && entry.expired(now) {
+			delete(s.entries, scoped)
+			exists = false
+		}
+		if !exists {
+			entry = &idempotencyEntry{fingerprint: fingerprint, done: make(chan struct{})}
+			s.entries[scoped] = entry
+			s.mu.Unlock()
+			return s.complete(ctx, scoped, entry, run)
+		}
+		s.mu.Unlock()
+
+		if entry.fingerprint != fingerprint {
+			return nil, fmt.Errorf("%w: idempotency_key %q was already used for a different %s call; use a new key for a new request", errIdempotencyKeyReused, key, toolName)
+		}
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if !entry.failed {
+			slog.Info("Returning the remembered result for idempotency key", "tool", toolName, "key", key)
+			return entry.result, nil
+		}
+		// the first call failed and was forgotten; run again
+	}
+}
+
+// complete runs the first call for a key and remembers its result, or forgets the key if
+// the call failed or panicked; either way calls waiting for it are released
+func (s *idempotencyStore) complete(ctx context.Context, scoped string, entry *idempotencyEntry, run func(ctx context.Context) (interface{}, error)) (result interface{}, err error) {
+	succeeded := false
+	defer func() {
+		s.mu.Lock()
+		if succeeded {
+			entry.result = result
+			entry.expires = s.now().Add(s.window)
+		} else {
+			entry.failed = true
+			if s.entries[scoped] == entry {
+				delete(s.entries, scoped)
+			}
+		}
+		s.mu.Unlock()
+		close(entry.done)
+	}()
+	writes := &idempotentWrites{}
+	result, err = run(context.WithValue(ctx, idempotentWritesContextKey{}, writes))
+	succeeded = err == nil && !writes.failed.Load()
+	return result, err
+}
+
+// expired reports whether a remembered call is past its window; running calls never are
+func (e *idempotencyEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
+}
+
+// sweepLocked drops every expired entry; s.mu must be held
+func (s *idempotencyStore) sweepLocked(now time.Time) {
+	for k, e := range s.entries {
+		if e.expired(now) {
+			delete(s.entries, k)
+		}
+	}
+	s.nextSweep = now.Add(idempotencySweepInterval)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCreateProductWithIdempotencyKey(t *testing.T) {
+	fake := newFakeProductService(t)
+	swapGlobal(t, &idempotentCalls, newIdempotencyStore(time.Hour))
+	ctx := context.Background()
+
+	create := func(ctx context.Context, price float64) (interface{}, error) {
+		return executeToolCall(ctx, "create_product", map[string]interface{}{
+			"name": "Laptop5", "category": "Electronics", "price": price, "idempotency_key": "req-1",
+		})
+	}
+	first, err := create(ctx, 999)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry, err := create(ctx, 999)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.writeCount() != 1 {
+		t.Errorf("Expected a single create, got %d writes", fake.writeCount())
+	}
+	if first.(map[string]interface{})["id"] != retry.(map[string]interface{})["id"] {
+		t.Errorf("Expected the retry to return the original product, got %v and %v", first, retry)
+	}
+	if _, sent := fake.get("1")["idempotency_key"]; sent {
+		t.Error("Expected the idempotency key not to be sent to the product service")
+	}
+
+	_, err = create(ctx, 899)
+	if !errors.Is(err, errIdempotencyKeyReused) {
+		t.Errorf("Expected the key to be rejected for different arguments, got %v", err)
+	}
+	if toolErrorKind(err) != "idempotency_key_reused" {
+		t.Errorf("Expected error kind 'idempotency_key_reused', got %s", toolErrorKind(err))
+	}
+
+	// keys are scoped per caller
+	if _, err := create(context.WithValue(ctx, callerContextKey{}, "other-agent"), 899); err != nil {
+		t.Fatal(err)
+	}
+	if fake.writeCount() != 2 {
+		t.Errorf("Expected another caller's key to create a product, got %d writes", fake.writeCount())
+	}
+
+	if _, err := executeToolCall(ctx, "create_product", map[string]interface{}{
+		"name": "Laptop5", "category": "Electronics", "price": 999.0, "idempotency_key": 42.0,
+	}); err == nil {
+		t.Error("Expected an error for a non-string idempotency key")
+	}
+}
+
+func TestCreateProductAnsweredWithAnErrorIsNotRemembered(t *testing.T) {
+	fake := newFakeProductService(t)
+	swapGlobal(t, &idempotentCalls, newIdempotencyStore(time.Hour))
+	params := func() map[string]interface{} {
+		return map[string]interface{}{"name": "Laptop5", "category": "Electronics", "price": 999.0, "idempotency_key": "req-1"}
+	}
+
+	// the product service answers the first create with a 500
+	fake.failWrite(1)
+	executeToolCall(context.Background(), "create_product", params())
+	if fake.writeCount() != 1 || len(fake.matching("name", "Laptop5")) != 0 {
+		t.Fatalf("Expected one failed create, got %d writes and %v", fake.writeCount(), fake.sortedProducts())
+	}
+	result, err := executeToolCall(context.Background(), "create_product", params())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.writeCount() != 2 || len(fake.matching("name", "Laptop5")) != 1 {
+		t.Errorf("Expected the retry to call the product service again, got %d writes and %v", fake.writeCount(), result)
+	}
+}
+
+func TestIdempotencyStoreRetriesFailuresAndExpires(t *testing.T) {
+	store := newIdempotencyStore(time.Hour)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+	params := map[string]interface{}{"name": "Laptop5"}
+
+	runs := 0
+	fail := func(context.Context) (interface{}, error) { runs++; return nil, errors.New("backend timeout") }
+	succeed := func(context.Context) (interface{}, error) { runs++; return runs, nil }
+
+	if _, err := store.do(ctx, "k", "create_product", params, fail); err == nil {
+		t.Fatal("Expected the failure to be returned")
+	}
+	if result, _ := store.do(ctx, "k", "create_product", params, succeed); result != 2 {
+		t.Errorf("Expected a failed call to be retried, got %v", result)
+	}
+	if result, _ := store.do(ctx, "k", "create_product", params, succeed); result != 2 {
+		t.Errorf("Expected the remembered result, got %v", result)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if result, _ := store.do(ctx, "k", "create_product", params, succeed); result != 3 {
+		t.Errorf("Expected the key to expire after the window, got %v", result)
+	}
+}
+
+func TestIdempotencyStoreConcurrentRetryWaits(t *testing.T) {
+	store := newIdempotencyStore(time.Hour)
+	ctx := context.Background()
+	params := map[string]interface{}{"name": "Laptop5"}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	runs := 0
+	run := func(context.Context) (interface{}, error) {
+		runs++
+		close(started)
+		<-release
+		return "created", nil
+	}
+
+	first := make(chan interface{})
+	go func() {
+		result, _ := store.do(ctx, "k", "create_product", params, run)
+		first <- result
+	}()
+	<-started
+
+	// a retry while the first call runs waits for it instead of running again
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := store.do(cancelled, "k", "create_product", params, run); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the retry to wait for the running call, got %v", err)
+	}
+	close(release)
+	if result := <-first; result != "created" {
+		t.Errorf("Expected the first call's result, got %v", result)
+	}
+	if result, _ := store.do(ctx, "k", "create_product", params, run); runs != 1 || result != "created" {
+		t.Errorf("Expected one run shared by both calls, got %d runs and %v", runs, result)
+	}
+}
+
+func TestIdempotencyStoreForgetsPanickingCalls(t *testing.T) {
+	store := newIdempotencyStore(time.Hour)
+	ctx := context.Background()
+	params := map[string]interface{}{"name": "Laptop5"}
+
+	func() {
+		defer func() { recover() }()
+		store.do(ctx, "k", "create_product", params, func(context.Context) (interface{}, error) { panic("backend client bug") })
+	}()
+	result, err := store.do(ctx, "k", "create_product", params, func(context.Context) (interface{}, error) { return "created", nil })
+	if err != nil || result != "created" {
+		t.Errorf("Expected a call after a panic to run again, got %v, %v", result, err)
+	}
+}
+
+func TestIdempotencyStoreSweepsUnusedKeys(t *testing.T) {
+	store := newIdempotencyStore(time.Hour)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+	succeed := func(context.Context) (interface{}, error) { return "created", nil }
+
+	store.do(ctx, "old", "create_product", map[string]interface{}{"name": "Desk"}, succeed)
+	now = now.Add(2 * time.Hour)
+	store.do(ctx, "new", "create_product", map[string]interface{}{"name": "Chair"}, succeed)
+	if len(store.entries) != 1 {
+		t.Errorf("Expected the expired key to be swept, have %d entries", len(store.entries))
+	}
+}
@@ -0,0 +1,863 @@
+// Package main - import.go
+//
+// This file implements the import_products tool, which loads a supplier list in CSV or
+// JSON Lines format into the catalog.
+//
+// Key Responsibilities:
+//   - Read the content inline ('content') or from an import:// resource ('resource_uri',
+//     files in import.directory, see resources.go)
+//   - Map source columns to product fields and coerce the values (prices such as "$1,299.00")
+//   - Validate every row and report row-level errors with the line they came from
+//   - Optionally update existing products with the same name instead of creating them
+//     ('upsert_by_name')
+//   - Submit the rows in chunks to POST /products/create-multiple (and POST /products/update
+//     for upserts)
+//
+// Column Mapping:
+//
+//	Columns (CSV header or JSONL keys) named like a product field (name, category, segment,
+//	price; case-insensitive) are used as is. 'mapping' maps product fields to other column
+//	names, e.g. {"name": "Product Name", "price": "Unit Price"}. Other columns are ignored
+//	and listed in the result. 'defaults' fills fields that are missing or empty in a row
+//	of a product to create; upserts only change the fields the row sets.
+//
+// Price Coercion:
+//
+//	Numbers and numeric strings are accepted. Currency symbols and codes ("$", "€", "EUR")
+//	and thousands separators are removed: "1,299.50", "1.299,50" and "1 299,50" are all
+//	1299.5. Thousands separators must separate groups of three digits ("1.234.567").
+//	A single separator followed by exactly three digits is a decimal one after a leading
+//	0 ("0,999") and otherwise ambiguous ("1,299" or "1.299"): such rows are reported as
+//	errors rather than guessed.
+//
+// Errors:
+//
+//	Rows that cannot be coerced or that break the validation rules (validation.go) are
+//	reported with their line number. By default any invalid row refuses the whole import;
+//	with 'skip_invalid' the valid rows are imported and the invalid ones reported. Chunks
+//	are submitted in order and the import stops at the first failed chunk; the error
+//	reports which chunks were written. CSV content that cannot be parsed (e.g. a broken
+//	quote, after which the reader cannot find the following rows) refuses the whole
+//	import, even with 'skip_invalid'.
+//
+//	Imports take no 'idempotency_key'. Retrying an import after a failed chunk sends the
+//	chunks that were already written again, which creates those rows a second time; retry
+//	with 'upsert_by_name' to update them instead.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultImportMaxBytes  = 10 << 20
+	defaultImportChunkSize = 100
+	maxImportChunkSize     = 500
+	maxImportErrorsShown   = 5
+)
+
+// importSettings is the 'import' config section
+var importSettings = defaultConfig().Import
+
+func configureImport(cfg ImportConfig) {
+	importSettings = cfg
+}
+
+// importFormats maps file extensions to import formats
+var importFormats = map[string]string{".csv": "csv", ".jsonl": "jsonl", ".ndjson": "jsonl"}
+
+// rowError is a problem with one row of an import
+type rowError struct {
+	Row     int         `json:"row"` // line number in the content
+	Field   string      `json:"field,omitempty"`
+	Value   interface{} `json:"value,omitempty"`
+	Message string      `json:"message"`
+}
+
+// importError is returned when rows are invalid or a chunk could not be written
+type importError struct {
+	Message string                 `json:"message"`
+	Errors  []rowError             `json:"errors,omitempty"`
+	Report  map[string]interface{} `json:"report,omitempty"` // what was written before a chunk failed
+	err     error
+}
+
+func (e *importError) Error() string {
+	messages := []string{e.Message}
+	for i, r := range e.Errors {
+		if i == maxImportErrorsShown {
+			messages = append(messages, fmt.Sprintf("and %d more", len(e.Errors)-maxImportErrorsShown))
+			break
+		}
+		messages = append(messages, fmt.Sprintf("row %d: %s", r.Row, r.Message))
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *importError) Unwrap() error { return e.err }
+
+// importOptions are the parsed import_products arguments
+type importOptions struct {
+	content     string
+	format      string
+	delimiter   rune
+	mapping     map[string]string // product field -> source column
+	defaults    map[string]interface{}
+	upsert      bool
+	skipInvalid bool
+	chunkSize   int
+}
+
+// importRecord is one decoded row, keyed by source column
+type importRecord struct {
+	line   int
+	values map[string]interface{}
+}
+
+// importRow is a row resolved to a create or an update
+type importRow struct {
+	line    int
+	product map[string]interface{} // the product to create, or the update with its id
+	before  map[string]interface{} // the existing product for upserts
+	errors  []rowError
+}
+
+func (r importRow) isUpdate() bool { return r.before != nil }
+
+// importPlan is the resolved import
+type importPlan struct {
+	opts    importOptions
+	rows    []importRow
+	ignored []string
+}
+
+// invalid returns the row errors of the plan
+func (p *importPlan) invalid() []rowError {
+	var errs []rowError
+	for _, row := range p.rows {
+		errs = append(errs, row.errors...)
+	}
+	return errs
+}
+
+// matchedIDs returns the ids of the existing products the plan's upserts match
+func (p *importPlan) matchedIDs() []string {
+	var ids []string
+	for _, row := range p.rows {
+		if row.isUpdate() {
+			ids = append(ids, fmt.Sprint(row.product["id"]))
+		}
+	}
+	return ids
+}
+
+// parseImportOptions validates the import_products arguments and reads the content
+func parseImportOptions(params map[string]interface{}) (importOptions, error) {
+	opts := importOptions{chunkSize: importSettings.ChunkSize}
+	content, hasContent := params["content"].(string)
+	uri, hasURI := params["resource_uri"].(string)
+	switch {
+	case hasContent == hasURI:
+		return opts, fmt.Errorf("exactly one of 'content' and 'resource_uri' is required")
+	case hasURI:
+		var err error
+		if content, err = readImportResource(uri); err != nil {
+			return opts, err
+		}
+		opts.format = importFormats[strings.ToLower(filepath.Ext(uri))]
+	case int64(len(content)) > importSettings.MaxBytes:
+		return opts, fmt.Errorf("'content' is larger than %d bytes (import.max_bytes)", importSettings.MaxBytes)
+	}
+	opts.content = strings.TrimPrefix(content, "\ufeff")
+
+	if v, present := params["format"]; present {
+		format, ok := v.(string)
+		if !ok || (format != "csv" && format != "jsonl") {
+			return opts, fmt.Errorf("invalid 'format' argument: must be 'csv' or 'jsonl'")
+		}
+		opts.format = format
+	}
+	if opts.format == "" {
+		opts.format = "csv"
+		if strings.HasPrefix(strings.TrimSpace(opts.content), "{") {
+			opts.format = "jsonl"
+		}
+	}
+	if v, present := params["delimiter"]; present {
+		delimiter, ok := v.(string)
+		if !ok || len([]rune(delimiter)) != 1 || delimiter == "\"" || delimiter == "\n" {
+			return opts, fmt.Errorf("invalid 'delimiter' argument: must be a single character")
+		}
+		opts.delimiter = []rune(delimiter)[0]
+	}
+
+	if v, present := params["mapping"]; present {
+		raw, ok := v.(map[string]interface{})
+		if !ok {
+			return opts, fmt.Errorf("invalid 'mapping' argument: must be an object of product field to column name")
+		}
+		opts.mapping = map[string]string{}
+		for field, col := range raw {
+			column, ok := col.(string)
+			if !slices.Contains(productFields, field) || !ok || column == "" {
+				return opts, fmt.Errorf("invalid 'mapping' entry %q: keys must be one of %s and values column names", field, strings.Join(productFields, ", "))
+			}
+			opts.mapping[field] = column
+		}
+	}
+	if v, present := params["defaults"]; present {
+		raw, ok := v.(map[string]interface{})
+		if !ok {
+			return opts, fmt.Errorf("invalid 'defaults' argument: must be an object of product field values")
+		}
+		opts.defaults = map[string]interface{}{}
+		for field, value := range raw {
+			coerced, err := coerceImportValue(field, value)
+			if !slices.Contains(productFields, field) || err != nil {
+				return opts, fmt.Errorf("invalid 'defaults' entry %q: must be a product field (%s) with a valid value", field, strings.Join(productFields, ", "))
+			}
+			opts.defaults[field] = coerced
+		}
+	}
+	for key, target := range map[string]*bool{"upsert_by_name": &opts.upsert, "skip_invalid": &opts.skipInvalid} {
+		if v, present := params[key]; present {
+			b, ok := v.(bool)
+			if !ok {
+				return opts, fmt.Errorf("invalid '%s' argument: must be a boolean", key)
+			}
+			*target = b
+		}
+	}
+	if v, present := params["chunk_size"]; present {
+		n, ok := v.(float64)
+		if !ok || n < 1 || n > maxImportChunkSize || n != float64(int(n)) {
+			return opts, fmt.Errorf("invalid 'chunk_size' argument: must be an integer between 1 and %d", maxImportChunkSize)
+		}
+		opts.chunkSize = int(n)
+	}
+	return opts, nil
+}
+
+// readImportResource reads an import://<file> resource from import.directory
+func readImportResource(uri string) (string, error) {
+	name, ok := strings.CutPrefix(uri, "import://")
+	if !ok {
+		return "", fmt.Errorf("invalid 'resource_uri' argument: only import:// resources can be imported (see resources/list)")
+	}
+	path, err := importFilePath(name)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("no import resource %s (see resources/list)", uri)
+		}
+		return "", err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, importSettings.MaxBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) > importSettings.MaxBytes {
+		return "", fmt.Errorf("%s is larger than %d bytes (import.max_bytes)", uri, importSettings.MaxBytes)
+	}
+	return string(data), nil
+}
+
+// importFilePath resolves a file name in import.directory; names with path elements, files
+// of other formats and symlinks leading out of the directory are refused
+func importFilePath(name string) (string, error) {
+	if importSettings.Directory == "" {
+		return "", fmt.Errorf("import resources are disabled (import.directory is not set)")
+	}
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") || importFormats[strings.ToLower(filepath.Ext(name))] == "" {
+		return "", fmt.Errorf("invalid import resource name %q", name)
+	}
+	path := filepath.Join(importSettings.Directory, name)
+	resolved, err := filepath.EvalSymlinks(path)
+	if errors.Is(err, os.ErrNotExist) {
+		// reported as a missing resource when it is opened
+		return path, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	root, err := filepath.EvalSymlinks(importSettings.Directory)
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(root, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("import resource %q is outside import.directory", name)
+	}
+	return resolved, nil
+}
+
+// importResources lists the files in import.directory as import:// resources
+func importResources() []resourceDefinition {
+	if importSettings.Directory == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(importSettings.Directory)
+	if err != nil {
+		return nil
+	}
+	var list []resourceDefinition
+	for _, entry := range entries {
+		name := entry.Name()
+		if _, err := importFilePath(name); err != nil || !entry.Type().IsRegular() {
+			continue
+		}
+		uri := "import://" + name
+		mimeType := "text/csv"
+		if importFormats[strings.ToLower(filepath.Ext(name))] == "jsonl" {
+			mimeType = "application/jsonl"
+		}
+		list = append(list, resourceDefinition{
+			Resource: Resource{
+				URI:         uri,
+				Name:        name,
+				Title:       "Import file " + name,
+				Description: "A product list staged for import. Pass this URI as 'resource_uri' to import_products.",
+				MimeType:    mimeType,
+			},
+			read: func(ctx context.Context) (interface{}, error) {
+				return readImportResource(uri)
+			},
+		})
+	}
+	return list
+}
+
+// decodeImport splits the content into records and returns the column names in order
+func decodeImport(opts importOptions) ([]importRecord, []string, error) {
+	if opts.format == "jsonl" {
+		return decodeJSONLines(opts.content)
+	}
+	return decodeCSV(opts.content, opts.delimiter)
+}
+
+func decodeJSONLines(content string) ([]importRecord, []string, error) {
+	var records []importRecord
+	var columns []string
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		rec := importRecord{line: i + 1}
+		dec := json.NewDecoder(strings.NewReader(line))
+		dec.UseNumber()
+		if err := dec.Decode(&rec.values); err != nil || rec.values == nil {
+			rec.values = nil
+		}
+		for key := range rec.values {
+			if !slices.Contains(columns, key) {
+				columns = append(columns, key)
+			}
+		}
+		records = append(records, rec)
+	}
+	sort.Strings(columns)
+	return records, columns, nil
+}
+
+func decodeCSV(content string, delimiter rune) ([]importRecord, []string, error) {
+	r := csv.NewReader(strings.NewReader(content))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comma = delimiter
+	if delimiter == 0 {
+		r.Comma = sniffDelimiter(content)
+	}
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("the content is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CSV header: %v", err)
+	}
+	columns := make([]string, len(header))
+	for i, h := range header {
+		columns[i] = strings.TrimSpace(h)
+	}
+
+	var records []importRecord
+	for {
+		fields, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// a broken quote swallows the rest of the content, so skipping the row would
+			// silently drop every row after it
+			return nil, nil, fmt.Errorf("invalid CSV at line %d: %v; nothing was imported", parseErr.StartLine, parseErr.Err)
+		}
+		line, _ := r.FieldPos(0)
+		rec := importRecord{line: line, values: map[string]interface{}{}}
+		blank := true
+		for i, v := range fields {
+			if i < len(columns) {
+				rec.values[columns[i]] = v
+			}
+			blank = blank && strings.TrimSpace(v) == ""
+		}
+		if !blank {
+			records = append(records, rec)
+		}
+	}
+	return records, columns, nil
+}
+
+// sniffDelimiter picks ',', ';' or tab, whichever is most frequent in the header line
+func sniffDelimiter(content string) rune {
+	header, _, _ := strings.Cut(content, "\n")
+	best, count := ',', strings.Count(header, ",")
+	for _, d := range []rune{';', '\t'} {
+		if n := strings.Count(header, string(d)); n > count {
+			best, count = d, n
+		}
+	}
+	return best
+}
+
+// resolveColumns maps each product field to its source column and returns the ignored
+// columns
+func resolveColumns(columns []string, mapping map[string]string) (map[string]string, []string, error) {
+	source := map[string]string{}
+	for field, column := range mapping {
+		if !slices.Contains(columns, column) {
+			return nil, nil, fmt.Errorf("mapping: column %q for '%s' not found (columns: %s)", column, field, strings.Join(columns, ", "))
+		}
+		source[field] = column
+	}
+	for _, column := range columns {
+		field := strings.ToLower(column)
+		if _, mapped := source[field]; !mapped && slices.Contains(productFields, field) {
+			source[field] = column
+		}
+	}
+	used := map[string]bool{}
+	for _, column := range source {
+		used[column] = true
+	}
+	ignored := []string{}
+	for _, column := range columns {
+		if !used[column] {
+			ignored = append(ignored, column)
+		}
+	}
+	return source, ignored, nil
+}
+
+// coerceRow converts a record to a product
+func coerceRow(rec importRecord, source map[string]string) (map[string]interface{}, []rowError) {
+	if rec.values == nil {
+		return nil, []rowError{{Row: rec.line, Message: "not a valid record (a JSON object per line, or a CSV row with balanced quotes)"}}
+	}
+	product := map[string]interface{}{}
+	var errs []rowError
+	for _, field := range productFields {
+		column, mapped := source[field]
+		raw, present := rec.values[column]
+		if mapped && present && !isBlank(raw) {
+			v, err := coerceImportValue(field, raw)
+			if err != nil {
+				errs = append(errs, rowError{Row: rec.line, Field: field, Value: raw, Message: err.Error()})
+				continue
+			}
+			product[field] = v
+		}
+	}
+	return product, errs
+}
+
+// coerceImportValue converts a CSV or JSON value to the type of a product field
+func coerceImportValue(field string, v interface{}) (interface{}, error) {
+	if field == "price" {
+		switch p := v.(type) {
+		case float64:
+			return p, nil
+		case json.Number:
+			return p.Float64()
+		case string:
+			return parsePrice(p)
+		}
+		return nil, fmt.Errorf("price %v is not a number", v)
+	}
+	switch s := v.(type) {
+	case string:
+		return strings.TrimSpace(s), nil
+	case json.Number, float64:
+		return fmt.Sprint(s), nil
+	}
+	return nil, fmt.Errorf("%s must be text", field)
+}
+
+var (
+	currencyAffix = regexp.MustCompile(`^[A-Z]{3}\s*|\s*[A-Z]{3}$|^[$€£¥]\s*|\s*[$€£¥]$`)
+	priceDigits   = regexp.MustCompile(`^-?[0-9][0-9.,' ]*$`)
+)
+
+// parsePrice parses a price with optional currency and thousands separators; a value whose
+// separators could mean either is refused
+func parsePrice(s string) (float64, error) {
+	value := strings.TrimSpace(s)
+	for {
+		stripped := currencyAffix.ReplaceAllString(value, "")
+		if stripped == value {
+			break
+		}
+		value = stripped
+	}
+	if !priceDigits.MatchString(value) {
+		return 0, fmt.Errorf("price %q is not a number", s)
+	}
+	value = strings.NewReplacer(" ", "", "'", "").Replace(value)
+
+	lastComma, lastDot := strings.LastIndex(value, ","), strings.LastIndex(value, ".")
+	whole, fraction := value, ""
+	switch {
+	case lastComma >= 0 && lastDot >= 0:
+		// the later separator is the decimal one
+		decimal, grouping := max(lastComma, lastDot), ","
+		if lastComma > lastDot {
+			grouping = "."
+		}
+		whole, fraction = value[:decimal], value[decimal+1:]
+		if !thousandsGrouped(whole, grouping) {
+			return 0, fmt.Errorf("price %q is not a number", s)
+		}
+		whole = strings.ReplaceAll(whole, grouping, "")
+	case lastComma >= 0 || lastDot >= 0:
+		separator := ","
+		if lastDot >= 0 {
+			separator = "."
+		}
+		switch parts := strings.Split(value, separator); {
+		case len(parts) > 2:
+			if !thousandsGrouped(value, separator) {
+				return 0, fmt.Errorf("price %q is not a number", s)
+			}
+			whole = strings.ReplaceAll(value, separator, "")
+		case len(parts[1]) != 3 || strings.Trim(parts[0], "-0") == "":
+			whole, fraction = parts[0], parts[1]
+		default:
+			return 0, fmt.Errorf("price %q is ambiguous: %q could be a thousands or a decimal separator", s, separator)
+		}
+	}
+	if fraction != "" {
+		whole += "." + fraction
+	}
+	price, err := strconv.ParseFloat(whole, 64)
+	if err != nil {
+		return 0, fmt.Errorf("price %q is not a number", s)
+	}
+	return price, nil
+}
+
+// thousandsGrouped reports whether separator only splits value into groups of three digits
+// after a leading group that is not 0
+func thousandsGrouped(value, separator string) bool {
+	groups := strings.Split(strings.TrimPrefix(value, "-"), separator)
+	if len(groups[0]) == 0 || len(groups[0]) > 3 || strings.Trim(groups[0], "0") == "" {
+		return false
+	}
+	for _, group := range groups[1:] {
+		if len(group) != 3 || strings.ContainsAny(group, ".,") {
+			return false
+		}
+	}
+	return true
+}
+
+// planImport reads, maps, coerces and validates the import and, for upserts, matches the
+// rows to existing products by name
+func planImport(ctx context.Context, params map[string]interface{}) (*importPlan, error) {
+	opts, err := parseImportOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	records, columns, err := decodeImport(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("the content has no rows to import")
+	}
+	source, ignored, err := resolveColumns(columns, opts.mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	var byName map[string][]map[string]interface{}
+	if opts.upsert {
+		products, err := fetchProducts(withFreshReads(ctx), "/products", productServiceBaseURL+"/products")
+		if err != nil {
+			return nil, err
+		}
+		byName = map[string][]map[string]interface{}{}
+		for _, p := range products {
+			if name, ok := p["name"].(string); ok {
+				key := strings.ToLower(strings.TrimSpace(name))
+				byName[key] = append(byName[key], p)
+			}
+		}
+	}
+
+	plan := &importPlan{opts: opts, ignored: ignored}
+	firstLine := map[string]int{}
+	for _, rec := range records {
+		product, errs := coerceRow(rec, source)
+		row := importRow{line: rec.line, product: product, errors: errs}
+		if rec.values == nil {
+			plan.rows = append(plan.rows, row)
+			continue
+		}
+		name, _ := product["name"].(string)
+		key := strings.ToLower(name)
+		if opts.upsert && key != "" {
+			if line, seen := firstLine[key]; seen {
+				row.errors = append(row.errors, rowError{Row: rec.line, Field: "name", Value: name, Message: fmt.Sprintf("name %q is also on row %d", name, line)})
+			}
+			firstLine[key] = rec.line
+			switch matches := byName[key]; {
+			case len(matches) > 1:
+				row.errors = append(row.errors, rowError{Row: rec.line, Field: "name", Value: name, Message: fmt.Sprintf("%d existing products are named %q", len(matches), name)})
+			case len(matches) == 1:
+				// the name is the match key; the existing spelling is kept
+				row.before = matches[0]
+				row.product["id"] = fmt.Sprint(matches[0]["id"])
+				delete(row.product, "name")
+			}
+		}
+		if !row.isUpdate() {
+			for field, v := range opts.defaults {
+				if _, present := product[field]; !present {
+					product[field] = v
+				}
+			}
+		}
+		if len(row.errors) == 0 {
+			row.errors = rowViolations(rec.line, product, !row.isUpdate())
+		}
+		plan.rows = append(plan.rows, row)
+	}
+	return plan, nil
+}
+
+// rowViolations checks a row against the validation rules when they are enforced
+func rowViolations(line int, product map[string]interface{}, complete bool) []rowError {
+	rules := productRules
+	if !rules.enforce {
+		return nil
+	}
+	var errs []rowError
+	for _, v := range rules.check(product, complete) {
+		errs = append(errs, rowError{Row: line, Field: v.Field, Value: v.Value, Message: v.Message})
+	}
+	return errs
+}
+
+// changes returns the per-product changes of the plan (see dryrun.go); unchanged upserts
+// have no fields
+func (p *importPlan) changes() []plannedChange {
+	changes := make([]plannedChange, 0, len(p.rows))
+	for _, row := range p.rows {
+		change := plannedChange{Action: "create", After: row.product}
+		if row.isUpdate() {
+			change = plannedChange{Action: "update", ID: row.product["id"], Before: row.before, After: copyParams(row.before), Fields: map[string]fieldChange{}}
+			for k, v := range row.product {
+				change.After[k] = v
+				if k != "id" && !sameValue(row.before[k], v) {
+					change.Fields[k] = fieldChange{From: row.before[k], To: v}
+				}
+			}
+		}
+		if len(row.errors) > 0 {
+			messages := make([]string, len(row.errors))
+			for i, e := range row.errors {
+				messages[i] = e.Message
+			}
+			change.Error = fmt.Sprintf("row %d: %s", row.line, strings.Join(messages, "; "))
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// importChunk reports one backend call of an import
+type importChunk struct {
+	Action string `json:"action"` // create or update
+	Rows   []int  `json:"rows"`   // line numbers
+	Status string `json:"status"` // ok, failed or not_sent
+	Error  string `json:"error,omitempty"`
+	items  []interface{}
+}
+
+// importProducts imports the rows of a CSV or JSON Lines product list
+func importProducts(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	plan, err := planImport(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	// lock the matched products and plan again, so the updates are computed from the
+	// state the writes apply to
+	ids := plan.matchedIDs()
+	defer lockProducts(ids)()
+	auditLockedProducts(ctx, ids)
+	if len(ids) > 0 {
+		if plan, err = planImport(ctx, params); err != nil {
+			return nil, err
+		}
+		if !slices.Equal(plan.matchedIDs(), ids) {
+			return nil, &conflictError{
+				Message:   "the products matched by name changed while the import was planned; nothing was imported",
+				Conflicts: []productConflict{},
+			}
+		}
+	}
+	invalid := plan.invalid()
+	if invalid == nil {
+		invalid = []rowError{}
+	}
+	if len(invalid) > 0 && !plan.opts.skipInvalid {
+		return nil, &importError{
+			Message: fmt.Sprintf("%d of %d rows are invalid; nothing was imported (pass skip_invalid to import the valid rows)", countRows(invalid), len(plan.rows)),
+			Errors:  invalid,
+		}
+	}
+
+	var creates, updates []importRow
+	unchanged := 0
+	for i, change := range plan.changes() {
+		row := plan.rows[i]
+		switch {
+		case len(row.errors) > 0:
+		case !row.isUpdate():
+			creates = append(creates, row)
+		case len(change.Fields) == 0:
+			unchanged++
+		default:
+			// only the changed fields are sent, so concurrent changes to others are kept
+			update := map[string]interface{}{"id": row.product["id"]}
+			for k, fc := range change.Fields {
+				update[k] = fc.To
+			}
+			updates = append(updates, importRow{line: row.line, product: update})
+		}
+	}
+
+	chunks := append(importChunks("create", creates, plan.opts.chunkSize), importChunks("update", updates, plan.opts.chunkSize)...)
+	created := []map[string]interface{}{}
+	updated := 0
+	var failure error
+	for i := range chunks {
+		chunk := &chunks[i]
+		if failure != nil {
+			chunk.Status = "not_sent"
+			continue
+		}
+		route := "/products/create-multiple"
+		if chunk.Action == "update" {
+			route = "/products/update"
+		}
+		result, err := postImportChunk(ctx, route, chunk.items)
+		if err != nil {
+			chunk.Status, chunk.Error = "failed", err.Error()
+			failure = err
+			continue
+		}
+		chunk.Status = "ok"
+		if chunk.Action == "update" {
+			updated += len(chunk.items)
+			continue
+		}
+		list, _ := result.([]interface{})
+		for _, item := range list {
+			if p, ok := item.(map[string]interface{}); ok {
+				created = append(created, p)
+			}
+		}
+	}
+
+	report := map[string]interface{}{
+		"format":           plan.opts.format,
+		"rows":             len(plan.rows),
+		"created":          len(created),
+		"updated":          updated,
+		"unchanged":        unchanged,
+		"skipped":          countRows(invalid),
+		"errors":           invalid,
+		"ignored_columns":  plan.ignored,
+		"chunks":           chunks,
+		"created_products": created,
+	}
+	if failure != nil {
+		return report, &importError{
+			Message: fmt.Sprintf("import stopped at a failed chunk: %v; earlier chunks were written", failure),
+			Report:  report,
+			err:     failure,
+		}
+	}
+	return report, nil
+}
+
+// importChunks splits rows into chunks of at most size rows
+func importChunks(action string, rows []importRow, size int) []importChunk {
+	var chunks []importChunk
+	for start := 0; start < len(rows); start += size {
+		end := min(start+size, len(rows))
+		chunk := importChunk{Action: action}
+		for _, row := range rows[start:end] {
+			chunk.Rows = append(chunk.Rows, row.line)
+			chunk.items = append(chunk.items, row.product)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// postImportChunk sends one chunk to a batch endpoint of the product service
+func postImportChunk(ctx context.Context, route string, items []interface{}) (interface{}, error) {
+	body, err := json.Marshal(map[string]interface{}{"products": items})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal params: %v", err)
+	}
+	resp, err := callBackend(ctx, http.MethodPost, route, productServiceBaseURL+route, body, nil)
+	recordBackendWrite(ctx, http.MethodPost, route, resp, err)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, &backendStatusError{StatusCode: resp.StatusCode}
+	}
+	var result interface{}
+	json.NewDecoder(bytes.NewReader(resp.Body)).Decode(&result)
+	return result, nil
+}
+
+// countRows returns the number of distinct rows with errors
+func countRows(errs []rowError) int {
+	rows := map[int]bool{}
+	for _, e := range errs {
+		rows[e.Row] = true
+	}
+	return len(rows)
+}
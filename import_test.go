@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePrice(t *testing.T) {
+	for input, want := range map[string]float64{
+		"999":          999,
+		"$1,299.00":    1299,
+		"1.299,50 €":   1299.5,
+		"1 299,50":     1299.5,
+		"EUR 12,5":     12.5,
+		"1,234,567":    1234567,
+		"1.234.567":    1234567,
+		"CHF 1'299.90": 1299.9,
+		"-5":           -5,
+		"0,999":        0.999,
+		"€0,500":       0.5,
+		"0.250":        0.25,
+		"1,2345":       1.2345,
+	} {
+		got, err := parsePrice(input)
+		if err != nil || got != want {
+			t.Errorf("parsePrice(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "cheap", "12abc", "1.2.3,4,5", "$", "12,34,567", "0,299.00", "1.2345,00"} {
+		if _, err := parsePrice(input); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+	// one separator before three digits could be either
+	for _, input := range []string{"1,299", "1.299", "$10,500", "-1.299"} {
+		if _, err := parsePrice(input); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+			t.Errorf("Expected %q to be refused as ambiguous, got %v", input, err)
+		}
+	}
+}
+
+func TestImportProductsCSV(t *testing.T) {
+	fake := newFakeProductService(t, testProduct("1", "Office Chair", "Furniture", "Budget", 149))
+	ctx := context.Background()
+	content := "Product Name;Category;Unit Price;Supplier SKU\n" +
+		"office chair;Furniture;\"$129.00\";A-1\n" +
+		"Standing Desk;;\"1.299,00\";A-2\n" +
+		"Lamp;Furniture;cheap;A-3\n" +
+		";Furniture;10;A-4\n"
+	params := map[string]interface{}{
+		"content":        content,
+		"mapping":        map[string]interface{}{"name": "Product Name", "price": "Unit Price"},
+		"defaults":       map[string]interface{}{"category": "Office"},
+		"upsert_by_name": true,
+	}
+
+	_, err := executeToolCall(ctx, "import_products", params)
+	var importErr *importError
+	if !errors.As(err, &importErr) || len(importErr.Errors) != 2 || importErr.Errors[0].Row != 4 || importErr.Errors[1].Row != 5 {
+		t.Fatalf("Expected the unparsable price and the missing name to be reported, got %v", err)
+	}
+	if toolErrorKind(err) != "validation" || fake.writeCount() != 0 {
+		t.Fatalf("Expected a validation error and no write, got %s and %d writes", toolErrorKind(err), fake.writeCount())
+	}
+
+	params["skip_invalid"] = true
+	params["dry_run"] = true
+	preview, err := executeToolCall(ctx, "import_products", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan := preview.(mutationPlan); plan.Valid || len(plan.Changes) != 4 || plan.Changes[0].Fields["price"].To != 129.0 {
+		t.Errorf("Unexpected import preview %+v", plan)
+	}
+
+	delete(params, "dry_run")
+	result, err := executeToolCall(ctx, "import_products", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := result.(map[string]interface{})
+	if report["created"] != 1 || report["updated"] != 1 || report["skipped"] != 2 {
+		t.Errorf("Expected one create, one update and two skipped rows, got %v", report)
+	}
+	if ignored := report["ignored_columns"].([]string); len(ignored) != 1 || ignored[0] != "Supplier SKU" {
+		t.Errorf("Expected the SKU column to be ignored, got %v", ignored)
+	}
+	if p := fake.get("1"); p["price"] != 129.0 || p["name"] != "Office Chair" || p["segment"] != "Budget" {
+		t.Errorf("Expected only the price of the existing product to change, got %v", p)
+	}
+	if desks := fake.matching("name", "Standing Desk"); len(desks) != 1 || desks[0]["price"] != 1299.0 || desks[0]["category"] != "Office" {
+		t.Errorf("Expected the desk to be created with the default category, got %v", desks)
+	}
+}
+
+func TestImportProductsJSONLinesFromResource(t *testing.T) {
+	fake := newFakeProductService(t)
+	dir := t.TempDir()
+	cfg := defaultConfig().Import
+	cfg.Directory = dir
+	swapGlobal(t, &importSettings, cfg)
+	lines := `{"name": "Desk", "category": "Furniture", "price": "199.90", "color": "oak"}
+{"name": "Chair", "category": "Furniture", "price": 99}
+
+{"name": "Lamp", "category": "Furniture", "price": 29}
+`
+	if err := os.WriteFile(filepath.Join(dir, "supplier.jsonl"), []byte(lines), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if list := importResources(); len(list) != 1 || list[0].URI != "import://supplier.jsonl" {
+		t.Fatalf("Expected the file to be listed as a resource, got %v", list)
+	}
+	if _, err := executeToolCall(context.Background(), "import_products", map[string]interface{}{"resource_uri": "import://../secret.csv"}); err == nil {
+		t.Error("Expected a path outside the import directory to be refused")
+	}
+	// a symlink in the directory cannot lead out of it
+	outside := filepath.Join(t.TempDir(), "secret.csv")
+	if err := os.WriteFile(outside, []byte("name,price\nSecret,1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "linked.csv")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readImportResource("import://linked.csv"); err == nil || !strings.Contains(err.Error(), "outside import.directory") {
+		t.Errorf("Expected a symlink out of the import directory to be refused, got %v", err)
+	}
+
+	// the second chunk fails: the first was written and the third not sent
+	fake.failWrite(2)
+	result, err := executeToolCall(context.Background(), "import_products", map[string]interface{}{"resource_uri": "import://supplier.jsonl", "chunk_size": 1.0})
+	var importErr *importError
+	if !errors.As(err, &importErr) || toolErrorKind(err) != "backend_5xx" {
+		t.Fatalf("Expected the failed chunk to be reported, got %v", err)
+	}
+	chunks := result.(map[string]interface{})["chunks"].([]importChunk)
+	if len(chunks) != 3 || chunks[0].Status != "ok" || chunks[1].Status != "failed" || chunks[2].Status != "not_sent" || chunks[2].Rows[0] != 4 {
+		t.Errorf("Unexpected chunks %+v", chunks)
+	}
+	if desks := fake.matching("name", "Desk"); len(desks) != 1 || desks[0]["price"] != 199.9 || desks[0]["color"] != nil {
+		t.Errorf("Expected the first chunk to be written with mapped fields only, got %v", fake.sortedProducts())
+	}
+}
+
+func TestImportRefusesBrokenCSV(t *testing.T) {
+	fake := newFakeProductService(t)
+	content := "name,category,price\nDesk,Furniture,199\n\"Chair,Furniture,99\nLamp,Furniture,29\n"
+	_, err := executeToolCall(context.Background(), "import_products", map[string]interface{}{"content": content, "skip_invalid": true})
+	if err == nil || !strings.Contains(err.Error(), "line 3") || fake.writeCount() != 0 {
+		t.Errorf("Expected the broken quote to refuse the import before writing, got %v and %d writes", err, fake.writeCount())
+	}
+}
+
+func TestImportPlansUpdatesUnderTheProductLock(t *testing.T) {
+	fake := newFakeProductService(t, testProduct("1", "Laptop5", "Electronics", "Laptops", 999))
+	unlock := lockProducts([]string{"1"})
+	done := make(chan interface{}, 1)
+	go func() {
+		result, err := executeToolCall(context.Background(), "import_products", map[string]interface{}{
+			"content": "name,price\nLaptop5,899\n", "upsert_by_name": true,
+		})
+		if err != nil {
+			result = err
+		}
+		done <- result
+	}()
+
+	// the product changes while the import waits for the lock
+	time.Sleep(50 * time.Millisecond)
+	fake.mu.Lock()
+	fake.products["1"]["price"] = 899.0
+	fake.mu.Unlock()
+	writes := fake.writeCount()
+	unlock()
+
+	report, ok := (<-done).(map[string]interface{})
+	if !ok || report["unchanged"] != 1 || report["updated"] != 0 || fake.writeCount() != writes {
+		t.Errorf("Expected the import to see the product as it is after the lock, got %v", report)
+	}
+}
@@ -2,102 +2,161 @@
 // tools via JSON-RPC 2.0 protocol.
 //
 // This file serves as the entry point for the MCP server and is responsible for:
-//   - Initializing the HTTP server on a configurable port (default: 8080)
+//   - Loading configuration from a config file, environment variables and flags (see config.go)
+//   - Initializing the HTTP server on a configurable port (default: 8080) with graceful
+//     shutdown on SIGTERM/SIGINT (see server.go)
 //   - Setting up route handlers for MCP protocol endpoints
-//   - Configuring CORS headers for cross-origin requests
-//   - Reading environment configuration (MICROSERVICE_URL, PORT)
+//   - Applying the configured CORS policy to /mcp, /mcp/discover and /health (see cors.go)
+//   - Starting the background search index refresh (see searchindex.go)
 //
 // Available endpoints:
-//   - POST /mcp           - Main JSON-RPC 2.0 endpoint for MCP protocol (initialize, tools/list, tools/call)
+//   - POST /mcp           - Main JSON-RPC 2.0 endpoint for MCP protocol (initialize, tools/*, resources/*)
+//   - DELETE /mcp         - Terminates the MCP session named in the Mcp-Session-Id header
 //   - GET  /mcp/discover  - REST endpoint for discovering available tools (returns tools array)
 //   - GET  /health        - Health check endpoint
+//   - GET  /metrics       - Prometheus metrics (MCP traffic, tool calls, backend calls)
 //
-// Environment Variables:
-//   - MICROSERVICE_URL: URL of the backend product service (optional)
-//   - PORT: Server port (default: 8080)
+// Configuration:
+//   - --config / MCP_CONFIG: YAML or JSON config file (optional)
+//   - MICROSERVICE_URL / --microservice-url: URL of the backend product service
+//   - PORT / --port: Server port (default: 8080)
+//   - --print-config: Print the effective configuration with secrets redacted and exit
+//   - See config.go for every setting, environment variable and flag
 //
 // The server supports the following JSON-RPC 2.0 methods:
 //   - initialize: Handshake and capability negotiation
 //   - tools/list: Returns list of all available tools with schemas
 //   - tools/call: Executes a specific tool with provided arguments
+//   - resources/list, resources/read: Read-only resources such as catalog://taxonomy
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
 	"net/http"
 	"os"
+	"time"
 	"encoding/json"
 )
 
 func main() {
-	microserviceURL := os.Getenv("MICROSERVICE_URL")
-	if microserviceURL != "" {
+	config, printOnly, err := loadConfig(os.Args[1:], os.Getenv)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if printOnly {
+		if err := printConfig(os.Stdout, config); err != nil {
+			log.Fatalf("Failed to print configuration: %v", err)
+		}
+		return
+	}
+
+	if err := configureLogging(config.Logging, os.Stderr); err != nil {
+		log.Fatalf("Failed to configure logging: %v", err)
+	}
+	productServiceBaseURL = config.MicroserviceURL
+	backendHTTPClient.Timeout = config.Timeouts.Backend.Duration
+	catalogCache.configure(config.Cache)
+	idempotentCalls.configure(config.Idempotency)
+	configureImport(config.Import)
+	if err := configureValidation(config.Validation); err != nil {
+		log.Fatalf("Failed to configure validation rules: %v", err)
+	}
+	if err := configureAudit(config.Audit); err != nil {
+		log.Fatalf("Failed to configure audit log: %v", err)
+	}
+	if auditLog != nil {
+		// write history survives restarts through the audit log file (see history.go)
+		if recs, err := auditLog.records(); err != nil {
+			log.Printf("Failed to replay the audit log into the product history: %v", err)
+		} else {
+			catalogHistory.replay(recs)
+		}
+	}
+
+	if config.MicroserviceURL != defaultMicroserviceURL {
 		log.Printf("MICROSERVICE_URL: configured")
 	} else {
-		log.Printf("MICROSERVICE_URL: not configured!!")
+		log.Printf("MICROSERVICE_URL: not configured, using default product service")
 	}
-	log.Printf("PORT: %s", os.Getenv("PORT"))
-
-	config := Config{
-		MicroserviceURL: microserviceURL,
-		Port:            os.Getenv("PORT"),
+	log.Printf("PORT: %s", config.Port)
+	if len(config.Auth.APIKeys) > 0 {
+		log.Printf("API key authentication enabled for %d callers", len(config.Auth.APIKeys))
 	}
-	if config.Port == "" {
-		config.Port = "8080"
+	switch {
+	case !config.Audit.Enabled:
+		log.Printf("Audit log: disabled")
+	case config.Audit.File != "":
+		log.Printf("Audit log: %s", config.Audit.File)
+	default:
+		log.Printf("Audit log: in memory (last %d records)", auditMemoryEntries)
 	}
 
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+	shutdownTracing, err := initTracing(config.Tracing)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
 
-		// needed for CORS support, especially for web-based clients
+	mux := http.NewServeMux()
+	// CORS (including preflight OPTIONS requests) is handled per route by the middleware, see cors.go
+	mux.Handle("/health", newCORS(config.CORS, http.MethodGet, http.MethodHead)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status": "healthy", "service": "ravi-mcp-server"}`))
-	})
-	http.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
-
-		// needed for CORS support, especially for web-based clients
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
+		if serverDraining.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status": "shutting down", "service": "ravi-mcp-server"}`))
 			return
 		}
-		mcpHandler(config)(w, r)
-	})
-	http.HandleFunc("/mcp/discover", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "healthy", "service": "ravi-mcp-server"}`))
+	})))
+	mux.Handle("/mcp", newCORS(config.CORS, http.MethodPost, http.MethodDelete)(requireAPIKey(config.Auth, mcpHandler(config))))
+	mux.Handle("/mcp/discover", newCORS(config.CORS, http.MethodGet)(requireAPIKey(config.Auth, func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 			return
 		}
 		// Import encoding/json and tools from tools.go
-		if err := json.NewEncoder(w).Encode(tools); err != nil {
+		if err := json.NewEncoder(w).Encode(config.enabledTools()); err != nil {
 			http.Error(w, "Failed to encode tools", http.StatusInternalServerError)
 		}
-	})
+	})))
+
+	// per-tool and per-caller counts are not public: /metrics needs an API key like /mcp
+	mux.Handle("/metrics", requireAPIKey(config.Auth, metricsHandler().ServeHTTP))
 
 	log.Printf("Starting MCP server on port %s", config.Port)
 	log.Printf("MCP JSON-RPC 2.0 Protocol supported methods:")
 	log.Printf("  - initialize")
 	log.Printf("  - tools/list")
 	log.Printf("  - tools/call")
+	log.Printf("  - resources/list")
+	log.Printf("  - resources/read")
+
+	indexCtx, stopIndex := context.WithCancel(context.Background())
+	if config.Search.Index {
+		go catalogSearch.run(indexCtx, config.Search.RefreshInterval.Duration)
+	}
+
+	srv := newHTTPServer(config, mux)
+	serveErr := runServer(srv, config.Timeouts.ShutdownGrace.Duration)
+	stopIndex()
 
-	if err := http.ListenAndServe(":"+config.Port, nil); err != nil {
-		log.Fatalf("Server failed: %v", err)
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
+	if serveErr != nil {
+		log.Fatalf("Server failed: %v", serveErr)
 	}
 }
@@ -36,6 +36,12 @@ func TestToolsAvailable(t *testing.T) {
 		"get_products_by_segment",
 		"get_product_by_name",
 		"search_products",
+		"adjust_prices",
+		"catalog_stats",
+		"list_taxonomy",
+		"get_audit_log",
+		"undo_change",
+		"get_product_history", "find_duplicates", "merge_products", "validate_catalog", "import_products",
 	}
 
 	if len(tools) != len(expectedTools) {
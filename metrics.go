@@ -0,0 +1,236 @@
+// Package main - metrics.go
+//
+// This file defines the Prometheus metrics exposed by the MCP server on GET /metrics.
+//
+// Key Responsibilities:
+//   - Register all collectors on a dedicated registry (no global default registry)
+//   - Classify tool errors into a small, bounded set of kinds
+//   - Provide the /metrics HTTP handler
+//
+// Metrics:
+//
+//	MCP traffic:
+//	  - mcp_rpc_requests_total{method}               JSON-RPC requests by method
+//	  - mcp_rpc_errors_total{method,code}            JSON-RPC error responses by method and error code
+//	  - mcp_rpc_duration_seconds{method}             JSON-RPC handling latency
+//	  - mcp_rpc_in_flight                            JSON-RPC requests currently being handled
+//	  - mcp_tool_calls_total{tool}                   tools/call invocations by tool
+//	  - mcp_tool_errors_total{tool,kind}             failed tool calls by tool and error kind
+//	  - mcp_tool_duration_seconds{tool}              tool execution latency
+//	  - mcp_tool_calls_in_flight{tool}               tool calls currently executing
+//
+//	Backend (product service):
+//	  - backend_requests_total{method,route,status}  HTTP requests by route and status ("error" on transport failure)
+//	  - backend_request_duration_seconds{method,route}
+//	  - backend_requests_in_flight
+//	  - backend_retries_total{method,route}          retried attempts
+//	  - backend_circuit_state{state}                 1 for the current circuit breaker state, 0 otherwise
+//	  - backend_circuit_transitions_total{state}     circuit breaker state changes
+//	  - backend_circuit_rejections_total{route}      requests rejected while the circuit was open
+//
+//	Search index:
+//	  - search_index_refreshes_total{result}         index rebuilds ("ok" or "error")
+//	  - search_index_documents                       products in the current index
+//
+// Label Cardinality:
+//
+//	Unknown JSON-RPC methods and tool names are reported as "unknown" so that clients
+//	cannot create arbitrary label values.
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	rpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mcp_rpc_requests_total",
+		Help: "JSON-RPC requests received, by method.",
+	}, []string{"method"})
+	rpcErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mcp_rpc_errors_total",
+		Help: "JSON-RPC error responses sent, by method and error code.",
+	}, []string{"method", "code"})
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mcp_rpc_duration_seconds",
+		Help:    "Time spent handling JSON-RPC requests, by method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+	rpcInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "mcp_rpc_in_flight",
+		Help: "JSON-RPC requests currently being handled.",
+	})
+
+	toolCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mcp_tool_calls_total",
+		Help: "Tool calls received, by tool.",
+	}, []string{"tool"})
+	toolErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mcp_tool_errors_total",
+		Help: "Tool calls that returned an error, by tool and error kind.",
+	}, []string{"tool", "kind"})
+	toolDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mcp_tool_duration_seconds",
+		Help:    "Time spent executing tools, by tool.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"tool"})
+	toolInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mcp_tool_calls_in_flight",
+		Help: "Tool calls currently executing, by tool.",
+	}, []string{"tool"})
+
+	backendRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "backend_requests_total",
+		Help: "HTTP requests sent to the product service, by method, route and status.",
+	}, []string{"method", "route", "status"})
+	backendRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "backend_request_duration_seconds",
+		Help:    "Latency of HTTP requests to the product service, by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	backendInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "backend_requests_in_flight",
+		Help: "HTTP requests to the product service currently in progress.",
+	})
+	backendRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "backend_retries_total",
+		Help: "Retried requests to the product service, by method and route.",
+	}, []string{"method", "route"})
+	backendCircuitState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "backend_circuit_state",
+		Help: "Current circuit breaker state for the product service (1 for the active state).",
+	}, []string{"state"})
+	backendCircuitTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "backend_circuit_transitions_total",
+		Help: "Circuit breaker state transitions, by new state.",
+	}, []string{"state"})
+	backendCircuitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "backend_circuit_rejections_total",
+		Help: "Requests rejected without calling the product service because the circuit was open.",
+	}, []string{"route"})
+
+	backendCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "backend_cache_requests_total",
+		Help: "Cacheable reads from the product service, by route and result (hit, miss, revalidated, coalesced).",
+	}, []string{"route", "result"})
+
+	searchIndexRefreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "search_index_refreshes_total",
+		Help: "Search index rebuilds, by result.",
+	}, []string{"result"})
+	searchIndexDocuments = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "search_index_documents",
+		Help: "Products in the current search index.",
+	})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		rpcRequests, rpcErrors, rpcDuration, rpcInFlight,
+		toolCalls, toolErrors, toolDuration, toolInFlight,
+		backendRequests, backendRequestDuration, backendInFlight, backendRetries,
+		backendCircuitState, backendCircuitTransitions, backendCircuitRejections,
+		backendCacheRequests,
+		searchIndexRefreshes, searchIndexDocuments,
+	)
+	backendCircuitState.WithLabelValues(circuitClosed).Set(1)
+	backendCircuitState.WithLabelValues(circuitOpen).Set(0)
+	backendCircuitState.WithLabelValues(circuitHalfOpen).Set(0)
+}
+
+// metricsHandler serves the registry in the Prometheus text exposition format
+func metricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
+
+// rpcResponseWriter remembers the JSON-RPC error code sent through sendJSONRPCError
+// so that mcpHandler can count errors per method
+type rpcResponseWriter struct {
+	http.ResponseWriter
+	errorCode int
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer (flush, deadlines)
+func (w *rpcResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+var knownRPCMethods = map[string]bool{
+	"initialize": true,
+	"tools/list": true,
+	"tools/call": true,
+
+	"resources/list": true,
+	"resources/read": true,
+
+	"notifications/initialized": true,
+	// JSON-RPC responses posted by the client, e.g. to elicitation/create
+	"response": true,
+}
+
+// rpcMethodLabel bounds the method label to known JSON-RPC methods
+func rpcMethodLabel(method string) string {
+	if knownRPCMethods[method] {
+		return method
+	}
+	return "unknown"
+}
+
+// toolLabel bounds the tool label to tools defined in tools.go
+func toolLabel(name string) string {
+	for _, tool := range tools {
+		if tool.Name == name {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// toolErrorKind classifies a tool error for the mcp_tool_errors_total metric
+func toolErrorKind(err error) string {
+	var statusErr *backendStatusError
+	var unavailableErr *backendUnavailableError
+	var conflict *conflictError
+	var invalid *validationError
+	var rolledBack *rollbackError
+	var importErr *importError
+	switch {
+	case errors.Is(err, errUnknownTool):
+		return "unknown_tool"
+	case errors.Is(err, errConfirmationDeclined):
+		return "declined"
+	case errors.Is(err, errConfirmationUnavailable):
+		return "confirmation_required"
+	case errors.Is(err, errSessionNotFound):
+		return "session_not_found"
+	case errors.As(err, &conflict):
+		return "conflict"
+	case errors.As(err, &invalid):
+		return "validation"
+	case errors.As(err, &importErr) && importErr.err == nil:
+		return "validation"
+	case errors.As(err, &rolledBack):
+		return "rolled_back"
+	case errors.Is(err, errIdempotencyKeyReused):
+		return "idempotency_key_reused"
+	case errors.Is(err, errCircuitOpen):
+		return "circuit_open"
+	case errors.As(err, &unavailableErr):
+		return "backend_unavailable"
+	case errors.As(err, &statusErr):
+		if statusErr.StatusCode >= 500 {
+			return "backend_5xx"
+		}
+		return "backend_4xx"
+	}
+	return "tool"
+}
@@ -20,6 +20,8 @@
 //      - InitializeResult: Server initialization response with capabilities
 //      - ToolSchema: Complete tool definition with schema and metadata
 //      - ToolCallParams: Parameters for executing a tool
+//      - Resource, ReadResourceParams, ResourceContents: Read-only resources (resources.go)
+//      - ElicitRequestParams / ElicitResult: Server-initiated confirmation request and answer
 //
 //   3. Capability Structures:
 //      - ServerCapabilities: Advertised server capabilities
@@ -27,11 +29,13 @@
 //      - ServerInfo: Server identification information
 //
 //   4. Configuration:
-//      - Config: Server configuration (microservice URL, port)
+//      - Config: Server configuration (microservice URL, port, timeouts, auth, CORS,
+//        enabled tools, search, confirmation, logging, transport, tracing); sections are defined in config.go
 //
 // JSON Tags:
-//   - All structs include `json` tags for proper serialization
+//   - All protocol structs include `json` tags for proper serialization
 //   - Optional fields marked with `omitempty`
+//   - Config uses `yaml` tags for the config file and --print-config output
 //
 // Usage:
 //   - handlers.go: Unmarshals requests and marshals responses
@@ -88,7 +92,20 @@ type InitializeResult struct {
 }
 
 type ServerCapabilities struct {
-	Tools interface{} `json:"tools,omitempty"`
+	Tools     interface{} `json:"tools,omitempty"`
+	Resources interface{} `json:"resources,omitempty"`
+}
+
+// ElicitRequestParams are the params of a server-initiated 'elicitation/create' request
+type ElicitRequestParams struct {
+	Message         string      `json:"message"`
+	RequestedSchema interface{} `json:"requestedSchema"`
+}
+
+// ElicitResult is the client's answer: action is accept, decline or cancel
+type ElicitResult struct {
+	Action  string                 `json:"action"`
+	Content map[string]interface{} `json:"content,omitempty"`
 }
 
 type ServerInfo struct {
@@ -97,7 +114,8 @@ type ServerInfo struct {
 }
 
 type ToolsListResult struct {
-	Tools []ToolSchema `json:"tools"`
+	Tools      []ToolSchema `json:"tools"`
+	NextCursor string       `json:"nextCursor,omitempty"`
 }
 
 type ToolCallParams struct {
@@ -111,11 +129,54 @@ type TextContent struct {
 }
 
 type CallToolResult struct {
-	Content []TextContent `json:"content"`
-	IsError bool          `json:"isError"`
+	Content           []TextContent `json:"content"`
+	StructuredContent interface{}   `json:"structuredContent,omitempty"`
+	IsError           bool          `json:"isError"`
+}
+
+// Resource describes a read-only resource returned by resources/list
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+type ResourcesListResult struct {
+	Resources  []Resource `json:"resources"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+type ReadResourceParams struct {
+	URI string `json:"uri"`
+}
+
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+type ReadResourceResult struct {
+	Contents []ResourceContents `json:"contents"`
 }
 
 type Config struct {
-	MicroserviceURL string
-	Port            string
+	MicroserviceURL string             `yaml:"microservice_url"`
+	Port            string             `yaml:"port"`
+	Timeouts        TimeoutsConfig     `yaml:"timeouts"`
+	Auth            AuthConfig         `yaml:"auth"`
+	CORS            CORSConfig         `yaml:"cors"`
+	Tools           ToolsConfig        `yaml:"tools"`
+	Audit           AuditConfig        `yaml:"audit"`
+	Cache           CacheConfig        `yaml:"cache"`
+	Idempotency     IdempotencyConfig  `yaml:"idempotency"`
+	Validation      ValidationConfig   `yaml:"validation"`
+	Import          ImportConfig       `yaml:"import"`
+	Search          SearchConfig       `yaml:"search"`
+	Confirmation    ConfirmationConfig `yaml:"confirmation"`
+	Logging         LoggingConfig      `yaml:"logging"`
+	Transport       TransportConfig    `yaml:"transport"`
+	Tracing         TracingConfig      `yaml:"tracing"`
 }
@@ -0,0 +1,159 @@
+// Package main - pagination.go
+//
+// This file implements opaque cursor pagination for product listings, tools/list and
+// resources/list.
+//
+// Key Responsibilities:
+//   - Read the 'cursor' and 'page_size' arguments and enforce page size limits
+//   - Encode and decode opaque cursors
+//   - Reject cursors that were issued for a different tool or different filters
+//
+// Paginated tools:
+//   - list_products, search_products, get_products_by_category, get_products_by_segment
+//   - They return {"products": [...], "total": N, "nextCursor": "..."}; nextCursor is omitted
+//     on the last page. Pass it back as 'cursor' with the same arguments to get the next page.
+//
+// Paginated JSON-RPC methods:
+//   - tools/list and resources/list accept params.cursor and return nextCursor, as MCP specifies
+//
+// Cursors hold an offset into the result and a fingerprint of the request that produced
+// them; they are not meant to be decoded by clients. The catalog is re-read for every page,
+// so products created or deleted between pages can shift the results.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+
+	// listPageSize is the page size of tools/list and resources/list
+	listPageSize = 50
+)
+
+// productPage is one page of a paginated product listing
+type productPage struct {
+	Products   []map[string]interface{} `json:"products"`
+	Total      int                      `json:"total"`
+	NextCursor string                   `json:"nextCursor,omitempty"`
+}
+
+// pageCursor is the decoded form of an opaque cursor
+type pageCursor struct {
+	Offset      int    `json:"o"`
+	Fingerprint string `json:"f"`
+}
+
+// pageRequest is the validated 'cursor' and 'page_size' of one call
+type pageRequest struct {
+	offset      int
+	size        int
+	fingerprint string
+}
+
+// parsePageRequest reads 'cursor' and 'page_size'; scope identifies the tool or method, and
+// the remaining params are part of the fingerprint so a cursor cannot be replayed with other filters
+func parsePageRequest(scope string, params map[string]interface{}) (pageRequest, error) {
+	req := pageRequest{size: defaultPageSize, fingerprint: requestFingerprint(scope, params)}
+
+	if v, present := params["page_size"]; present {
+		size, ok := v.(float64)
+		if !ok || size < 1 || size != float64(int(size)) {
+			return req, fmt.Errorf("invalid 'page_size' argument: must be a positive integer")
+		}
+		if size > maxPageSize {
+			return req, fmt.Errorf("invalid 'page_size' argument: must not exceed %d", maxPageSize)
+		}
+		req.size = int(size)
+	}
+
+	if v, present := params["cursor"]; present {
+		encoded, ok := v.(string)
+		if !ok {
+			return req, fmt.Errorf("invalid 'cursor' argument: must be a string")
+		}
+		if encoded != "" {
+			offset, err := decodeCursor(encoded, req.fingerprint)
+			if err != nil {
+				return req, err
+			}
+			req.offset = offset
+		}
+	}
+	return req, nil
+}
+
+// paginate returns the requested page of products
+func (req pageRequest) paginate(products []map[string]interface{}) productPage {
+	page := productPage{Products: []map[string]interface{}{}, Total: len(products)}
+	if req.offset >= len(products) {
+		return page
+	}
+	end := req.offset + req.size
+	if end >= len(products) {
+		end = len(products)
+	} else {
+		page.NextCursor = encodeCursor(end, req.fingerprint)
+	}
+	page.Products = products[req.offset:end]
+	return page
+}
+
+// requestFingerprint hashes the scope and every argument except the pagination ones
+func requestFingerprint(scope string, params map[string]interface{}) string {
+	filters := map[string]interface{}{}
+	for key, value := range params {
+		if key != "cursor" && key != "page_size" {
+			filters[key] = value
+		}
+	}
+	// json.Marshal sorts map keys, so equal arguments always hash the same
+	data, _ := json.Marshal(filters)
+	sum := sha256.Sum256(append([]byte(scope+"\x00"), data...))
+	return hex.EncodeToString(sum[:8])
+}
+
+func encodeCursor(offset int, fingerprint string) string {
+	data, _ := json.Marshal(pageCursor{Offset: offset, Fingerprint: fingerprint})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(encoded, fingerprint string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, fmt.Errorf("invalid 'cursor' argument: malformed cursor")
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Offset < 0 {
+		return 0, fmt.Errorf("invalid 'cursor' argument: malformed cursor")
+	}
+	if cursor.Fingerprint != fingerprint {
+		return 0, fmt.Errorf("invalid 'cursor' argument: cursor was issued for different arguments; repeat the original arguments or start without a cursor")
+	}
+	return cursor.Offset, nil
+}
+
+// listPageBounds returns the slice bounds of one page of a tools/list or resources/list
+// listing with count items, and the cursor of the next page
+func listPageBounds(method string, count int, params map[string]interface{}) (start, end int, nextCursor string, err error) {
+	fingerprint := requestFingerprint(method, nil)
+	if encoded, _ := params["cursor"].(string); encoded != "" {
+		if start, err = decodeCursor(encoded, fingerprint); err != nil {
+			return 0, 0, "", err
+		}
+	}
+	if start >= count {
+		return count, count, "", nil
+	}
+	end = start + listPageSize
+	if end >= count {
+		return start, count, "", nil
+	}
+	return start, end, encodeCursor(end, fingerprint), nil
+}
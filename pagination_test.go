@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestListProductsPagination(t *testing.T) {
+	var products []map[string]interface{}
+	for i := 1; i <= 5; i++ {
+		products = append(products, testProduct(fmt.Sprint(i), fmt.Sprintf("Product%d", i), "Electronics", "Budget", float64(i)))
+	}
+	newFakeProductService(t, products...)
+
+	var ids []string
+	params := map[string]interface{}{"page_size": 2.0}
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("Pagination did not terminate")
+		}
+		result, err := executeToolCall(context.Background(), "list_products", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page := result.(productPage)
+		if page.Total != 5 || len(page.Products) > 2 {
+			t.Fatalf("Unexpected page: %#v", page)
+		}
+		for _, p := range page.Products {
+			ids = append(ids, p["id"].(string))
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		params = map[string]interface{}{"page_size": 2.0, "cursor": page.NextCursor}
+	}
+	if strings.Join(ids, ",") != "1,2,3,4,5" {
+		t.Errorf("Expected every product exactly once, got %v", ids)
+	}
+}
+
+func TestPaginationRejectsBadArguments(t *testing.T) {
+	newFakeProductService(t, testProduct("1", "Laptop5", "Electronics", "Premium", 999), testProduct("2", "Phone", "Electronics", "Premium", 599))
+
+	result, err := executeToolCall(context.Background(), "search_products", map[string]interface{}{"category": "Electronics", "page_size": 1.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor := result.(productPage).NextCursor
+
+	cases := []map[string]interface{}{
+		{"page_size": 0.0},
+		{"page_size": 1000.0},
+		{"page_size": 1.5},
+		{"cursor": "not-a-cursor"},
+		// a cursor cannot be reused with different filters or on another tool
+		{"category": "Furniture", "cursor": cursor},
+	}
+	for _, params := range cases {
+		if _, err := executeToolCall(context.Background(), "search_products", params); err == nil {
+			t.Errorf("%v: expected an error", params)
+		}
+	}
+	if _, err := executeToolCall(context.Background(), "list_products", map[string]interface{}{"cursor": cursor}); err == nil {
+		t.Error("Expected a search_products cursor to be rejected by list_products")
+	}
+}
+
+func TestListPageBounds(t *testing.T) {
+	start, end, next, err := listPageBounds("tools/list", 120, nil)
+	if err != nil || start != 0 || end != listPageSize || next == "" {
+		t.Fatalf("Unexpected first page: %d-%d %q %v", start, end, next, err)
+	}
+	start, end, _, _ = listPageBounds("tools/list", 120, map[string]interface{}{"cursor": next})
+	if start != listPageSize || end != 2*listPageSize {
+		t.Errorf("Unexpected second page: %d-%d", start, end)
+	}
+	if _, _, _, err := listPageBounds("resources/list", 120, map[string]interface{}{"cursor": next}); err == nil {
+		t.Error("Expected a tools/list cursor to be rejected by resources/list")
+	}
+	if start, end, next, _ := listPageBounds("tools/list", 3, nil); start != 0 || end != 3 || next != "" {
+		t.Errorf("Expected a single page without nextCursor, got %d-%d %q", start, end, next)
+	}
+}
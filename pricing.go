@@ -0,0 +1,258 @@
+// Package main - pricing.go
+//
+// This file implements the adjust_prices tool, which computes new prices server-side and
+// applies them through the batch update endpoint.
+//
+// Key Responsibilities:
+//   - Select products with the same filters as search_products plus a price range
+//   - Compute new prices from an operation, optional rounding and floor/ceiling clamps
+//   - Send only the changed prices to POST /products/update in one batch
+//   - Return the before/after price of every matched product
+//
+// Operations:
+//   - percent: price * (1 + value/100)      e.g. value 3 raises prices by 3%, -10 lowers them by 10%
+//   - delta:   price + value                e.g. value -5 takes 5 off every price
+//   - set:     value                        every matched product gets the same price
+//   - round:   rounding only (round_to required)
+//
+// Selection:
+//   - at least one filter (category, segment, name, min_price, max_price) is required
+//   - all=true reprices the whole catalog
+//
+// Order of evaluation:
+//  1. operation
+//  2. rounding to a multiple of round_to (mode: nearest, up, down), if round_to is given
+//  3. floor / ceiling clamps
+//  4. result rounded to cents; a negative result is an error
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// priceAdjustment describes how adjust_prices transforms a single price
+type priceAdjustment struct {
+	Operation string
+	Value     float64
+	RoundTo   float64
+	RoundMode string
+	Floor     *float64
+	Ceiling   *float64
+}
+
+// priceChange is the before/after price of one matched product
+type priceChange struct {
+	ID       interface{} `json:"id"`
+	Name     interface{} `json:"name"`
+	OldPrice float64     `json:"old_price"`
+	NewPrice float64     `json:"new_price"`
+	Changed  bool        `json:"changed"`
+}
+
+func parsePriceAdjustment(params map[string]interface{}) (priceAdjustment, error) {
+	adj := priceAdjustment{RoundMode: "nearest"}
+
+	op, _ := params["operation"].(string)
+	switch op {
+	case "percent", "delta", "set", "round":
+		adj.Operation = op
+	default:
+		return adj, fmt.Errorf("missing or invalid 'operation' argument (expected percent, delta, set or round)")
+	}
+
+	if op != "round" {
+		value, ok := params["value"].(float64)
+		if !ok {
+			return adj, fmt.Errorf("missing or invalid 'value' argument for operation '%s'", op)
+		}
+		adj.Value = value
+	}
+
+	if v, present := params["round_to"]; present {
+		roundTo, ok := v.(float64)
+		if !ok || roundTo <= 0 {
+			return adj, fmt.Errorf("invalid 'round_to' argument: must be a positive number")
+		}
+		adj.RoundTo = roundTo
+	} else if op == "round" {
+		return adj, fmt.Errorf("missing 'round_to' argument for operation 'round'")
+	}
+
+	if mode, ok := params["round_mode"].(string); ok && mode != "" {
+		switch mode {
+		case "nearest", "up", "down":
+			adj.RoundMode = mode
+		default:
+			return adj, fmt.Errorf("invalid 'round_mode' argument (expected nearest, up or down)")
+		}
+	}
+
+	for _, clamp := range []struct {
+		key  string
+		dest **float64
+	}{{"floor", &adj.Floor}, {"ceiling", &adj.Ceiling}} {
+		if v, present := params[clamp.key]; present {
+			f, ok := v.(float64)
+			if !ok {
+				return adj, fmt.Errorf("invalid '%s' argument: must be a number", clamp.key)
+			}
+			*clamp.dest = &f
+		}
+	}
+	if adj.Floor != nil && adj.Ceiling != nil && *adj.Floor > *adj.Ceiling {
+		return adj, fmt.Errorf("'floor' must not be greater than 'ceiling'")
+	}
+
+	all, ok := params["all"].(bool)
+	if v, present := params["all"]; present && v != nil && !ok {
+		return adj, fmt.Errorf("invalid 'all' argument: must be a boolean")
+	}
+	if !all && !hasPriceFilter(params) {
+		return adj, fmt.Errorf("no filter given: pass category, segment, name, min_price or max_price, or 'all': true to adjust every product")
+	}
+
+	return adj, nil
+}
+
+// hasPriceFilter reports whether params select products by any of the adjust_prices filters
+func hasPriceFilter(params map[string]interface{}) bool {
+	for _, key := range []string{"category", "segment", "name"} {
+		if v, ok := params[key].(string); ok && v != "" {
+			return true
+		}
+	}
+	for _, key := range []string{"min_price", "max_price"} {
+		if _, ok := params[key].(float64); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// apply computes the adjusted price
+func (adj priceAdjustment) apply(price float64) (float64, error) {
+	result := price
+	switch adj.Operation {
+	case "percent":
+		result = price * (1 + adj.Value/100)
+	case "delta":
+		result = price + adj.Value
+	case "set":
+		result = adj.Value
+	}
+
+	if adj.RoundTo > 0 {
+		steps := result / adj.RoundTo
+		switch adj.RoundMode {
+		case "up":
+			steps = math.Ceil(steps - 1e-9)
+		case "down":
+			steps = math.Floor(steps + 1e-9)
+		default:
+			steps = math.Round(steps)
+		}
+		result = steps * adj.RoundTo
+	}
+
+	if adj.Floor != nil && result < *adj.Floor {
+		result = *adj.Floor
+	}
+	if adj.Ceiling != nil && result > *adj.Ceiling {
+		result = *adj.Ceiling
+	}
+
+	result = roundCents(result)
+	if result < 0 {
+		return 0, fmt.Errorf("adjusted price %.2f is negative (use 'floor' to clamp)", result)
+	}
+	return result, nil
+}
+
+// roundCents removes floating point noise such as 102.99999999999999
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// Adjusts the price of every product matching the filters and applies the result in one batch
+func adjustPrices(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	changes, updates, err := planPriceAdjustment(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"matched": len(changes),
+		"updated": len(updates),
+		"changes": changes,
+	}
+	if len(updates) == 0 {
+		return result, nil
+	}
+
+	items := make([]interface{}, len(updates))
+	for i, update := range updates {
+		items[i] = update
+	}
+	batch := map[string]interface{}{"products": items}
+	if atomic, present := params["atomic"]; present {
+		batch["atomic"] = atomic
+	}
+	backendResult, err := updateProducts(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	result["result"] = backendResult
+	return result, nil
+}
+
+// planPriceAdjustment computes the price change of every matched product and the
+// update_products payload for the ones that actually change
+func planPriceAdjustment(ctx context.Context, params map[string]interface{}) ([]priceChange, []map[string]interface{}, error) {
+	adj, err := parsePriceAdjustment(params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	products, err := fetchProducts(ctx, "/products", productServiceBaseURL+"/products")
+	if err != nil {
+		return nil, nil, err
+	}
+	products = filterProducts(products, params)
+	products = filterPriceRange(products, params)
+
+	changes := make([]priceChange, 0, len(products))
+	var updates []map[string]interface{}
+	for _, p := range products {
+		oldPrice := toFloat64(p["price"])
+		newPrice, err := adj.apply(oldPrice)
+		if err != nil {
+			return nil, nil, fmt.Errorf("product %v (%v): %v", p["id"], p["name"], err)
+		}
+		change := priceChange{ID: p["id"], Name: p["name"], OldPrice: oldPrice, NewPrice: newPrice, Changed: newPrice != oldPrice}
+		changes = append(changes, change)
+		if change.Changed {
+			updates = append(updates, map[string]interface{}{"id": p["id"], "price": newPrice})
+		}
+	}
+	return changes, updates, nil
+}
+
+// filterPriceRange keeps products whose price is within the optional min_price/max_price bounds
+func filterPriceRange(products []map[string]interface{}, params map[string]interface{}) []map[string]interface{} {
+	minPrice, hasMin := params["min_price"].(float64)
+	maxPrice, hasMax := params["max_price"].(float64)
+	if !hasMin && !hasMax {
+		return products
+	}
+	var filtered []map[string]interface{}
+	for _, p := range products {
+		price := toFloat64(p["price"])
+		if (hasMin && price < minPrice) || (hasMax && price > maxPrice) {
+			continue
+		}
+		filtered = append(filtered, p)
+	}
+	return filtered
+}
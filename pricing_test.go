@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPriceAdjustmentApply(t *testing.T) {
+	cases := []struct {
+		name   string
+		params map[string]interface{}
+		price  float64
+		want   float64
+	}{
+		{"percent increase", map[string]interface{}{"operation": "percent", "value": 3.0}, 1000, 1030},
+		{"percent decrease", map[string]interface{}{"operation": "percent", "value": -10.0}, 19.99, 17.99},
+		{"delta", map[string]interface{}{"operation": "delta", "value": -5.0}, 20, 15},
+		{"set", map[string]interface{}{"operation": "set", "value": 9.99}, 20, 9.99},
+		{"round nearest", map[string]interface{}{"operation": "round", "round_to": 100.0}, 1049, 1000},
+		{"round up", map[string]interface{}{"operation": "round", "round_to": 100.0, "round_mode": "up"}, 1001, 1100},
+		{"round up exact", map[string]interface{}{"operation": "round", "round_to": 100.0, "round_mode": "up"}, 1100, 1100},
+		{"round down", map[string]interface{}{"operation": "round", "round_to": 0.05, "round_mode": "down"}, 1.99, 1.95},
+		{"percent then round", map[string]interface{}{"operation": "percent", "value": 2.0, "round_to": 1.0}, 999, 1019},
+		{"floor", map[string]interface{}{"operation": "delta", "value": -50.0, "floor": 10.0}, 30, 10},
+		{"ceiling", map[string]interface{}{"operation": "percent", "value": 50.0, "ceiling": 120.0}, 100, 120},
+	}
+
+	for _, tc := range cases {
+		tc.params["all"] = true
+		adj, err := parsePriceAdjustment(tc.params)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		got, err := adj.apply(tc.price)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: expected %.2f, got %.2f", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestPriceAdjustmentErrors(t *testing.T) {
+	invalid := []map[string]interface{}{
+		{},
+		{"operation": "multiply", "value": 2.0},
+		{"operation": "percent"},
+		{"operation": "round"},
+		{"operation": "round", "round_to": -1.0},
+		{"operation": "delta", "value": 1.0, "floor": 10.0, "ceiling": 5.0, "all": true},
+		{"operation": "percent", "value": 1.0},
+		{"operation": "percent", "value": 1.0, "all": false},
+		{"operation": "percent", "value": 1.0, "all": "yes"},
+	}
+	for _, params := range invalid {
+		if _, err := parsePriceAdjustment(params); err == nil {
+			t.Errorf("Expected error for params %v", params)
+		}
+	}
+
+	adj, _ := parsePriceAdjustment(map[string]interface{}{"operation": "delta", "value": -50.0, "max_price": 100.0})
+	if _, err := adj.apply(20); err == nil {
+		t.Errorf("Expected error for negative resulting price")
+	}
+}
+
+func TestAdjustPricesAppliesBatchUpdate(t *testing.T) {
+	fake := newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Laptops", 1000),
+		testProduct("2", "Office Chair", "Furniture", "Budget", 150),
+	)
+	result, err := executeToolCall(context.Background(), "adjust_prices", map[string]interface{}{
+		"category": "Electronics", "operation": "percent", "value": 10.0,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated := result.(map[string]interface{})["updated"]; updated != 1 {
+		t.Errorf("Expected one updated product, got %v", updated)
+	}
+	if p := fake.get("1"); p["price"] != 1100.0 {
+		t.Errorf("Expected the laptop price to be raised to 1100, got %v", p["price"])
+	}
+	if p := fake.get("2"); p["price"] != 150.0 {
+		t.Errorf("Expected the chair to be unchanged, got %v", p["price"])
+	}
+}
+
+func TestAdjustPricesRequiresFilterOrAll(t *testing.T) {
+	fake := newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Laptops", 1000),
+		testProduct("2", "Office Chair", "Furniture", "Budget", 150),
+	)
+	ctx := context.Background()
+	if _, err := executeToolCall(ctx, "adjust_prices", map[string]interface{}{"operation": "percent", "value": 10.0}); err == nil || fake.writeCount() != 0 {
+		t.Fatalf("Expected a call without a filter to be refused before writing, got %v", err)
+	}
+
+	result, err := executeToolCall(ctx, "adjust_prices", map[string]interface{}{"operation": "percent", "value": 10.0, "all": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated := result.(map[string]interface{})["updated"]; updated != 2 {
+		t.Errorf("Expected every product to be updated, got %v", updated)
+	}
+	if fake.get("2")["price"] != 165.0 {
+		t.Errorf("Expected the chair price to be raised to 165, got %v", fake.get("2")["price"])
+	}
+}
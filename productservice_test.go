@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeProductService is an in-memory stand-in for the backend product service
+type fakeProductService struct {
+	mu       sync.Mutex
+	products map[string]map[string]interface{}
+	nextID   int
+	writes   int
+	reads    int
+	failAt   int // the write (1-based) that fails with 500; 0 for none
+}
+
+// newFakeProductService starts a fake backend seeded with products and points
+// productServiceBaseURL at it for the duration of the test
+func newFakeProductService(t *testing.T, products ...map[string]interface{}) *fakeProductService {
+	t.Helper()
+	f := &fakeProductService{products: map[string]map[string]interface{}{}, nextID: 1000}
+	for _, p := range products {
+		f.products[p["id"].(string)] = p
+	}
+	srv := httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(srv.Close)
+	swapGlobal(t, &productServiceBaseURL, srv.URL)
+	return f
+}
+
+// swapGlobal sets a package-level variable for the duration of a test
+func swapGlobal[T any](t *testing.T, target *T, value T) {
+	t.Helper()
+	previous := *target
+	*target = value
+	t.Cleanup(func() { *target = previous })
+}
+
+func (f *fakeProductService) writeCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.writes
+}
+
+// readCount reports how many reads reached the service
+func (f *fakeProductService) readCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reads
+}
+
+// failWrite makes the n-th write from now fail
+func (f *fakeProductService) failWrite(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failAt = f.writes + n
+}
+
+func (f *fakeProductService) get(id string) map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.products[id]
+}
+
+func (f *fakeProductService) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var body interface{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	obj, _ := body.(map[string]interface{})
+	path := strings.TrimPrefix(r.URL.Path, "/products")
+	if r.Method == http.MethodGet {
+		f.reads++
+	} else {
+		f.writes++
+		if f.writes == f.failAt {
+			fakeWriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "injected failure"})
+			return
+		}
+	}
+
+	switch {
+	case r.Method == http.MethodGet && path == "":
+		list := []map[string]interface{}{}
+		for _, p := range f.sortedProducts() {
+			list = append(list, p)
+		}
+		fakeWriteJSON(w, http.StatusOK, list)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/category/"):
+		fakeWriteJSON(w, http.StatusOK, f.matching("category", strings.TrimPrefix(path, "/category/")))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/segment/"):
+		fakeWriteJSON(w, http.StatusOK, f.matching("segment", strings.TrimPrefix(path, "/segment/")))
+	case r.Method == http.MethodGet:
+		key := strings.TrimPrefix(path, "/")
+		if p, ok := f.products[key]; ok {
+			fakeWriteJSON(w, http.StatusOK, p)
+			return
+		}
+		for _, p := range f.products {
+			if p["name"] == key {
+				fakeWriteJSON(w, http.StatusOK, p)
+				return
+			}
+		}
+		fakeWriteJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	case r.Method == http.MethodPost && path == "":
+		fakeWriteJSON(w, http.StatusCreated, f.create(obj))
+	case r.Method == http.MethodPost && path == "/create-multiple":
+		var created []map[string]interface{}
+		for _, item := range obj["products"].([]interface{}) {
+			created = append(created, f.create(item.(map[string]interface{})))
+		}
+		fakeWriteJSON(w, http.StatusCreated, created)
+	case r.Method == http.MethodPost && path == "/update":
+		for _, item := range obj["products"].([]interface{}) {
+			update := item.(map[string]interface{})
+			if p, ok := f.products[fmt.Sprint(update["id"])]; ok {
+				for k, v := range update {
+					p[k] = v
+				}
+			}
+		}
+		fakeWriteJSON(w, http.StatusOK, map[string]string{"message": "products updated"})
+	case r.Method == http.MethodPost && path == "/delete":
+		for _, id := range obj["ids"].([]interface{}) {
+			delete(f.products, fmt.Sprint(id))
+		}
+		fakeWriteJSON(w, http.StatusOK, map[string]string{"message": "products deleted"})
+	case r.Method == http.MethodPut:
+		id := strings.TrimPrefix(path, "/")
+		p, ok := f.products[id]
+		if !ok {
+			fakeWriteJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+			return
+		}
+		replaced := map[string]interface{}{}
+		for k, v := range obj {
+			replaced[k] = v
+		}
+		for k, v := range p {
+			if _, sent := replaced[k]; !sent {
+				replaced[k] = v
+			}
+		}
+		f.products[id] = replaced
+		fakeWriteJSON(w, http.StatusOK, replaced)
+	case r.Method == http.MethodDelete:
+		id := strings.TrimPrefix(path, "/")
+		if _, ok := f.products[id]; !ok {
+			fakeWriteJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+			return
+		}
+		delete(f.products, id)
+		fakeWriteJSON(w, http.StatusOK, map[string]string{"message": "product deleted"})
+	default:
+		fakeWriteJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "unsupported"})
+	}
+}
+
+func (f *fakeProductService) create(product map[string]interface{}) map[string]interface{} {
+	f.nextID++
+	created := map[string]interface{}{"id": fmt.Sprint(f.nextID)}
+	for k, v := range product {
+		created[k] = v
+	}
+	f.products[created["id"].(string)] = created
+	return created
+}
+
+func (f *fakeProductService) matching(field, value string) []map[string]interface{} {
+	list := []map[string]interface{}{}
+	for _, p := range f.sortedProducts() {
+		if p[field] == value {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+func (f *fakeProductService) sortedProducts() []map[string]interface{} {
+	ids := make([]string, 0, len(f.products))
+	for id := range f.products {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	list := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, f.products[id])
+	}
+	return list
+}
+
+func fakeWriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func testProduct(id, name, category, segment string, price float64) map[string]interface{} {
+	return map[string]interface{}{"id": id, "name": name, "category": category, "segment": segment, "price": price}
+}
@@ -0,0 +1,107 @@
+// Package main - resources.go
+//
+// This file exposes read-only MCP resources alongside the tools.
+//
+// Key Responsibilities:
+//   - Define the global 'resources' list returned by resources/list (paginated, see pagination.go)
+//   - Read a resource by URI for resources/read
+//
+// Resources:
+//   - catalog://taxonomy: Distinct categories and segments with product counts and
+//     spelling variants (same data as the list_taxonomy tool, see taxonomy.go)
+//   - import://<file>: The CSV and JSON Lines files in import.directory, read as text, for
+//     the import_products tool (see import.go)
+//
+// JSON-RPC Error Codes:
+//   - -32602: Invalid params (missing uri, invalid cursor)
+//   - -32002: Resource not found
+//   - -32603: Internal error (backend failure while reading)
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// resourceDefinition pairs a resource's metadata with the function producing its contents
+type resourceDefinition struct {
+	Resource
+	read func(ctx context.Context) (interface{}, error)
+}
+
+var resources = []resourceDefinition{
+	{
+		Resource: Resource{
+			URI:         "catalog://taxonomy",
+			Name:        "taxonomy",
+			Title:       "Catalog categories and segments",
+			Description: "Distinct product categories and segments with product counts and the spellings found in the data. Use these values for get_products_by_category, get_products_by_segment and search_products.",
+			MimeType:    "application/json",
+		},
+		read: func(ctx context.Context) (interface{}, error) {
+			return listTaxonomy(ctx, map[string]interface{}{})
+		},
+	},
+}
+
+// allResources returns the static resources followed by the import files
+func allResources() []resourceDefinition {
+	return append(resources[:len(resources):len(resources)], importResources()...)
+}
+
+func handleResourcesList(w http.ResponseWriter, req JSONRPCRequest) {
+	params, _ := req.Params.(map[string]interface{})
+	resources := allResources()
+	start, end, nextCursor, err := listPageBounds("resources/list", len(resources), params)
+	if err != nil {
+		sendJSONRPCError(w, req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+	list := make([]Resource, 0, end-start)
+	for _, r := range resources[start:end] {
+		list = append(list, r.Resource)
+	}
+	sendJSONRPCResponse(w, req.ID, ResourcesListResult{Resources: list, NextCursor: nextCursor})
+	slog.Debug("Sent resources list to client")
+}
+
+func handleResourcesRead(ctx context.Context, w http.ResponseWriter, req JSONRPCRequest) {
+	var params ReadResourceParams
+	if req.Params != nil {
+		paramBytes, _ := json.Marshal(req.Params)
+		json.Unmarshal(paramBytes, &params)
+	}
+	if params.URI == "" {
+		sendJSONRPCError(w, req.ID, -32602, "Invalid params", "Missing resource uri")
+		return
+	}
+
+	for _, r := range allResources() {
+		if r.URI != params.URI {
+			continue
+		}
+		content, err := r.read(ctx)
+		if err != nil {
+			sendJSONRPCError(w, req.ID, -32603, "Internal error", err.Error())
+			return
+		}
+		// text resources are returned as is, everything else as JSON
+		text, isText := content.(string)
+		if !isText {
+			data, err := json.Marshal(content)
+			if err != nil {
+				sendJSONRPCError(w, req.ID, -32603, "Internal error", "failed to serialize resource")
+				return
+			}
+			text = string(data)
+		}
+		sendJSONRPCResponse(w, req.ID, ReadResourceResult{
+			Contents: []ResourceContents{{URI: r.URI, MimeType: r.MimeType, Text: text}},
+		})
+		return
+	}
+	sendJSONRPCError(w, req.ID, -32002, "Resource not found", fmt.Sprintf("Unknown resource: %s", params.URI))
+}
@@ -0,0 +1,352 @@
+// Package main - searchindex.go
+//
+// This file maintains an in-process full-text index over the product catalog for
+// search_products.
+//
+// Key Responsibilities:
+//   - Build an inverted index over product names, categories and segments
+//   - Tokenize, lowercase and stem both products and queries the same way
+//   - Rank matches (BM25 with per-field weights) and highlight matched words
+//   - Rebuild the index at startup, after writes made through this server and on an interval
+//
+// Query semantics:
+//   - Every query word must match (AND); "gaming laptops" matches "Gaming Laptop 15"
+//   - Words are stemmed, so "laptops" matches "laptop" and "batteries" matches "battery"
+//   - A query word of 3+ letters also matches as a prefix ("lapt" matches "laptop") at half weight
+//   - Field weights: name 3, category 1.5, segment 1
+//   - Highlights wrap matched words in **double asterisks**
+//
+// Freshness:
+//   - Mutating tools invalidate the index; until the rebuild finishes, search_products reads
+//     the catalog from the product service as before
+//   - Writes made directly against the product service are picked up on the next periodic
+//     refresh (search.refresh_interval)
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+// searchFieldWeights are the indexed product fields and their ranking weights
+var searchFieldWeights = map[string]float64{
+	"name":     3,
+	"category": 1.5,
+	"segment":  1,
+}
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// prefixMatchWeight scales the score of a query word that only matched as a prefix
+	prefixMatchWeight = 0.5
+	minPrefixLength   = 3
+)
+
+// searchToken is a word of an indexed or query text and its position in that text
+type searchToken struct {
+	term       string
+	start, end int
+}
+
+// fieldPosting records how often a term occurs in one field of one product
+type fieldPosting struct {
+	doc   int
+	field string
+	tf    int
+}
+
+// searchIndex is an immutable inverted index over a catalog snapshot
+type searchIndex struct {
+	products    []map[string]interface{}
+	postings    map[string][]fieldPosting
+	terms       []string         // the keys of postings, sorted for prefix lookups
+	fieldLength map[string][]int // field -> token count per product
+	avgLength   map[string]float64
+	generation  int64
+	builtAt     time.Time
+}
+
+// buildSearchIndex indexes a list of products
+func buildSearchIndex(products []map[string]interface{}) *searchIndex {
+	idx := &searchIndex{
+		products:    products,
+		postings:    map[string][]fieldPosting{},
+		fieldLength: map[string][]int{},
+		avgLength:   map[string]float64{},
+		builtAt:     time.Now(),
+	}
+	for field := range searchFieldWeights {
+		idx.fieldLength[field] = make([]int, len(products))
+		total := 0
+		for doc, p := range products {
+			text, _ := p[field].(string)
+			tokens := tokenizeSearchText(text)
+			idx.fieldLength[field][doc] = len(tokens)
+			total += len(tokens)
+
+			counts := map[string]int{}
+			for _, tok := range tokens {
+				counts[tok.term]++
+			}
+			for term, tf := range counts {
+				idx.postings[term] = append(idx.postings[term], fieldPosting{doc: doc, field: field, tf: tf})
+			}
+		}
+		if len(products) > 0 {
+			idx.avgLength[field] = float64(total) / float64(len(products))
+		}
+	}
+	idx.terms = make([]string, 0, len(idx.postings))
+	for term := range idx.postings {
+		idx.terms = append(idx.terms, term)
+	}
+	sort.Strings(idx.terms)
+	return idx
+}
+
+// matchingTerms returns the indexed terms a query word matches: the word itself and, for
+// words of minPrefixLength or more, the terms it is a prefix of
+func (idx *searchIndex) matchingTerms(word string) []string {
+	if len(word) < minPrefixLength {
+		if _, ok := idx.postings[word]; ok {
+			return []string{word}
+		}
+		return nil
+	}
+	var terms []string
+	for i := sort.SearchStrings(idx.terms, word); i < len(idx.terms) && strings.HasPrefix(idx.terms[i], word); i++ {
+		terms = append(terms, idx.terms[i])
+	}
+	return terms
+}
+
+// allProducts returns a copy of the indexed product list that callers may reorder
+func (idx *searchIndex) allProducts() []map[string]interface{} {
+	return append([]map[string]interface{}(nil), idx.products...)
+}
+
+// search returns copies of the matching products, best first, each with a "search" entry
+// holding its score and highlighted fields
+func (idx *searchIndex) search(query string) []map[string]interface{} {
+	queryTokens := tokenizeSearchText(query)
+	if len(queryTokens) == 0 {
+		return nil
+	}
+
+	scores := map[int]float64{}
+	matchedTerms := map[int]map[string]bool{}
+	for i, qt := range queryTokens {
+		termScores := map[int]float64{}
+		for _, term := range idx.matchingTerms(qt.term) {
+			postings := idx.postings[term]
+			weight := 1.0
+			if term != qt.term {
+				weight = prefixMatchWeight
+			}
+			idf := math.Log(1 + (float64(len(idx.products))-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+			for _, posting := range postings {
+				termScores[posting.doc] += weight * idf * idx.fieldScore(posting)
+				if matchedTerms[posting.doc] == nil {
+					matchedTerms[posting.doc] = map[string]bool{}
+				}
+				matchedTerms[posting.doc][term] = true
+			}
+		}
+		// every query word must match
+		for doc := range termScores {
+			if i == 0 {
+				scores[doc] = termScores[doc]
+			} else if _, ok := scores[doc]; ok {
+				scores[doc] += termScores[doc]
+			}
+		}
+		for doc := range scores {
+			if _, ok := termScores[doc]; !ok {
+				delete(scores, doc)
+			}
+		}
+	}
+
+	docs := make([]int, 0, len(scores))
+	for doc := range scores {
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if scores[docs[i]] != scores[docs[j]] {
+			return scores[docs[i]] > scores[docs[j]]
+		}
+		return docs[i] < docs[j]
+	})
+
+	hits := make([]map[string]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		hit := make(map[string]interface{}, len(idx.products[doc])+1)
+		for k, v := range idx.products[doc] {
+			hit[k] = v
+		}
+		highlights := map[string]string{}
+		for field := range searchFieldWeights {
+			text, _ := hit[field].(string)
+			if marked, ok := highlightSearchText(text, matchedTerms[doc]); ok {
+				highlights[field] = marked
+			}
+		}
+		hit["search"] = map[string]interface{}{
+			"score":      math.Round(scores[doc]*1000) / 1000,
+			"highlights": highlights,
+		}
+		hits = append(hits, hit)
+	}
+	return hits
+}
+
+// fieldScore is the weighted BM25 term frequency component of one posting
+func (idx *searchIndex) fieldScore(p fieldPosting) float64 {
+	length := float64(idx.fieldLength[p.field][p.doc])
+	avg := idx.avgLength[p.field]
+	if avg == 0 {
+		avg = 1
+	}
+	tf := float64(p.tf)
+	return searchFieldWeights[p.field] * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*length/avg))
+}
+
+// highlightSearchText wraps the words of text whose terms are in matched with **
+func highlightSearchText(text string, matched map[string]bool) (string, bool) {
+	var sb strings.Builder
+	last := 0
+	found := false
+	for _, tok := range tokenizeSearchText(text) {
+		if !matched[tok.term] {
+			continue
+		}
+		sb.WriteString(text[last:tok.start])
+		sb.WriteString("**" + text[tok.start:tok.end] + "**")
+		last = tok.end
+		found = true
+	}
+	sb.WriteString(text[last:])
+	return sb.String(), found
+}
+
+// tokenizeSearchText splits text into lowercase, stemmed words of letters and digits
+func tokenizeSearchText(text string) []searchToken {
+	var tokens []searchToken
+	start := -1
+	for i, r := range text + " " {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		}
+		if !isWord && start >= 0 {
+			tokens = append(tokens, searchToken{term: stemWord(strings.ToLower(text[start:i])), start: start, end: i})
+			start = -1
+		}
+	}
+	return tokens
+}
+
+// stemWord strips common English inflections so that plural and singular forms match;
+// it only needs to be consistent between indexing and querying
+func stemWord(w string) string {
+	if len(w) <= 3 || strings.ContainsFunc(w, unicode.IsDigit) {
+		return w
+	}
+	switch {
+	case strings.HasSuffix(w, "ies") && len(w) > 4:
+		return w[:len(w)-3] + "y"
+	case strings.HasSuffix(w, "sses"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "ches") || strings.HasSuffix(w, "shes") || strings.HasSuffix(w, "xes") || strings.HasSuffix(w, "zes"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "ss") || strings.HasSuffix(w, "us"):
+		return w
+	case strings.HasSuffix(w, "s"):
+		return w[:len(w)-1]
+	case strings.HasSuffix(w, "ing") && len(w) > 5:
+		return undouble(w[:len(w)-3])
+	case strings.HasSuffix(w, "ed") && len(w) > 4:
+		return undouble(w[:len(w)-2])
+	}
+	return w
+}
+
+// undouble turns "runn" (from "running") back into "run"
+func undouble(w string) string {
+	if n := len(w); n >= 2 && w[n-1] == w[n-2] && !strings.ContainsRune("aeiouls", rune(w[n-1])) {
+		return w[:n-1]
+	}
+	return w
+}
+
+// catalogIndexer keeps the current search index and rebuilds it in the background
+type catalogIndexer struct {
+	current    atomic.Pointer[searchIndex]
+	generation atomic.Int64
+	refresh    chan struct{}
+}
+
+var catalogSearch = &catalogIndexer{refresh: make(chan struct{}, 1)}
+
+// run builds the index, then rebuilds it after invalidate() and every interval
+// (0 disables periodic refreshes) until ctx is done
+func (c *catalogIndexer) run(ctx context.Context, interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		c.rebuild(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case <-c.refresh:
+		}
+	}
+}
+
+func (c *catalogIndexer) rebuild(ctx context.Context) {
+	generation := c.generation.Load()
+	start := time.Now()
+	products, err := fetchProducts(ctx, "/products", productServiceBaseURL+"/products")
+	if err != nil {
+		searchIndexRefreshes.WithLabelValues("error").Inc()
+		slog.Warn("Failed to refresh search index", "error", err)
+		return
+	}
+	idx := buildSearchIndex(products)
+	idx.generation = generation
+	c.current.Store(idx)
+	searchIndexRefreshes.WithLabelValues("ok").Inc()
+	searchIndexDocuments.Set(float64(len(products)))
+	slog.Debug("Search index refreshed", "products", len(products), "took", time.Since(start).Round(time.Millisecond))
+}
+
+// invalidate marks the index stale and schedules a rebuild
+func (c *catalogIndexer) invalidate() {
+	c.generation.Add(1)
+	select {
+	case c.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// snapshot returns the current index, or nil if it has not been built yet or is stale
+func (c *catalogIndexer) snapshot() *searchIndex {
+	idx := c.current.Load()
+	if idx == nil || idx.generation != c.generation.Load() {
+		return nil
+	}
+	return idx
+}
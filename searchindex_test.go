@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStemWord(t *testing.T) {
+	cases := map[string]string{
+		"laptops":   "laptop",
+		"batteries": "battery",
+		"boxes":     "box",
+		"glasses":   "glass",
+		"running":   "run",
+		"status":    "status",
+		"s24":       "s24",
+	}
+	for word, want := range cases {
+		if got := stemWord(word); got != want {
+			t.Errorf("stemWord(%q): expected %q, got %q", word, want, got)
+		}
+	}
+}
+
+func TestSearchIndexRanking(t *testing.T) {
+	idx := buildSearchIndex([]map[string]interface{}{
+		testProduct("1", "Gaming Laptop 15", "Electronics", "Laptops", 1499),
+		testProduct("2", "Laptop Sleeve", "Accessories", "Budget", 29),
+		testProduct("3", "Office Chair", "Furniture", "Budget", 149),
+		testProduct("4", "Laptop5", "Electronics", "Laptops", 999),
+	})
+
+	hits := idx.search("gaming laptops")
+	if len(hits) != 1 || hits[0]["id"] != "1" {
+		t.Fatalf("Expected only the gaming laptop, got %v", hits)
+	}
+	search := hits[0]["search"].(map[string]interface{})
+	if highlights := search["highlights"].(map[string]string); highlights["name"] != "**Gaming** **Laptop** 15" || highlights["segment"] != "**Laptops**" {
+		t.Errorf("Unexpected highlights: %v", highlights)
+	}
+
+	hits = idx.search("laptop")
+	if len(hits) != 3 || hits[2]["id"] != "2" {
+		t.Errorf("Expected the Laptops segment products ranked above the sleeve, got %v", hits)
+	}
+	if hits := idx.search("lapt"); len(hits) != 3 {
+		t.Errorf("Expected prefix matches, got %d", len(hits))
+	}
+	if terms := idx.matchingTerms("lapt"); len(terms) != 2 || terms[0] != "laptop" || terms[1] != "laptop5" {
+		t.Errorf("Expected the sorted terms starting with 'lapt', got %v", terms)
+	}
+	if terms := idx.matchingTerms("of"); len(terms) != 0 {
+		t.Errorf("Expected short words to match exact terms only, got %v", terms)
+	}
+	if hits := idx.search("budget chairs"); len(hits) != 1 || hits[0]["id"] != "3" {
+		t.Errorf("Expected the office chair, got %v", hits)
+	}
+	if _, ok := idx.products[0]["search"]; ok {
+		t.Error("Expected search results to be copies of the indexed products")
+	}
+}
+
+func TestSearchProductsUsesIndex(t *testing.T) {
+	fake := newFakeProductService(t,
+		testProduct("1", "Gaming Laptop 15", "Electronics", "Laptops", 1499),
+		testProduct("2", "Laptop Sleeve", "Accessories", "Budget", 29),
+	)
+	catalogSearch.rebuild(context.Background())
+	defer catalogSearch.invalidate()
+
+	result, err := executeToolCall(context.Background(), "search_products", map[string]interface{}{"query": "laptop", "category": "accessories"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page := result.(productPage); len(page.Products) != 1 || page.Products[0]["id"] != "2" {
+		t.Errorf("Expected the sleeve, got %v", page.Products)
+	}
+
+	// a write through this server invalidates the index so the new product is found
+	if _, err := executeToolCall(context.Background(), "create_product", map[string]interface{}{"name": "Laptop Stand", "category": "Accessories", "price": 49.0}); err != nil {
+		t.Fatal(err)
+	}
+	if catalogSearch.snapshot() != nil {
+		t.Fatal("Expected the index to be stale after a write")
+	}
+	result, _ = executeToolCall(context.Background(), "search_products", map[string]interface{}{"query": "laptop stand"})
+	if page := result.(productPage); len(page.Products) != 1 || fake.writeCount() != 1 {
+		t.Errorf("Expected the new product from the product service, got %v", page.Products)
+	}
+}
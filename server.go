@@ -0,0 +1,95 @@
+// Package main - server.go
+//
+// This file builds the HTTP server and runs it until the process is asked to stop.
+//
+// Key Responsibilities:
+//   - Create an explicit http.Server with read, write and idle timeouts and a header size limit
+//   - Handle SIGTERM (sent by Cloud Run before stopping an instance) and SIGINT
+//   - Drain in-flight JSON-RPC calls and close long-lived streams within the grace period
+//
+// Shutdown Sequence:
+//  1. A signal is received; /health starts answering 503 so load balancers stop routing
+//  2. The listener is closed and idle keep-alive connections are dropped
+//  3. serverStopping is closed so streaming handlers (SSE) end their responses
+//  4. In-flight requests are allowed to finish until timeouts.shutdown_grace elapses
+//  5. Remaining connections are closed forcibly
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// serverStopping is closed when shutdown begins. Handlers that hold a response open
+// (for example SSE streams) must select on it and return promptly.
+var serverStopping = make(chan struct{})
+
+// serverDraining is true once shutdown has begun
+var serverDraining atomic.Bool
+
+// newHTTPServer creates the HTTP server with the configured limits
+func newHTTPServer(config Config, handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Addr:              config.Transport.Host + ":" + config.Port,
+		Handler:           handler,
+		ReadTimeout:       config.Timeouts.Read.Duration,
+		ReadHeaderTimeout: config.Timeouts.ReadHeader.Duration,
+		WriteTimeout:      config.Timeouts.Write.Duration,
+		IdleTimeout:       config.Timeouts.Idle.Duration,
+		MaxHeaderBytes:    config.Transport.MaxHeaderBytes,
+	}
+	srv.RegisterOnShutdown(func() {
+		close(serverStopping)
+	})
+	return srv
+}
+
+// runServer serves until SIGTERM/SIGINT, then shuts down gracefully within grace
+func runServer(srv *http.Server, grace time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	return serveUntil(ctx, srv, ln, grace)
+}
+
+// serveUntil serves on ln until ctx is done, then shuts down gracefully within grace
+func serveUntil(ctx context.Context, srv *http.Server, ln net.Listener, grace time.Duration) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutdown signal received, draining in-flight requests", "grace_period", grace)
+	serverDraining.Store(true)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Grace period elapsed, closing remaining connections", "error", err)
+		srv.Close()
+	}
+
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	slog.Info("Server stopped")
+	return nil
+}
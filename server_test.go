@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// withFreshShutdownState resets the process-wide shutdown signals after a test that
+// shuts a server down
+func withFreshShutdownState(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		serverStopping = make(chan struct{})
+		serverDraining.Store(false)
+	})
+}
+
+func TestServeUntilDrainsInFlightRequests(t *testing.T) {
+	withFreshShutdownState(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-serverStopping
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newHTTPServer(defaultConfig(), mux)
+	ctx, stop := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- serveUntil(ctx, srv, ln, 5*time.Second) }()
+
+	base := "http://" + ln.Addr().String()
+	stream, err := http.Get(base + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+	type result struct {
+		body string
+		err  error
+	}
+	slow := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(base + "/slow")
+		if err != nil {
+			slow <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		slow <- result{string(body), err}
+	}()
+	<-started
+
+	stop()
+	select {
+	case <-serverStopping:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected shutdown to signal the streaming handlers")
+	}
+	if !serverDraining.Load() {
+		t.Error("Expected the server to report that it is draining")
+	}
+	select {
+	case err := <-stopped:
+		t.Fatalf("Expected shutdown to wait for the in-flight request, returned %v", err)
+	default:
+	}
+
+	close(release)
+	if r := <-slow; r.err != nil || r.body != "done" {
+		t.Errorf("Expected the in-flight request to finish, got %q, %v", r.body, r.err)
+	}
+	if err := <-stopped; err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+	if _, err := http.Get(base + "/slow"); err == nil {
+		t.Error("Expected new connections to be refused after shutdown")
+	}
+}
+
+func TestServeUntilClosesRequestsAfterGracePeriod(t *testing.T) {
+	withFreshShutdownState(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stuck", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- serveUntil(ctx, newHTTPServer(defaultConfig(), mux), ln, 50*time.Millisecond) }()
+
+	failed := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/stuck")
+		if err == nil {
+			resp.Body.Close()
+		}
+		failed <- err
+	}()
+	<-started
+	stop()
+	if err := <-stopped; err != nil {
+		t.Errorf("Expected shutdown to finish after the grace period, got %v", err)
+	}
+	if err := <-failed; err == nil {
+		t.Error("Expected the stuck request to be cut off")
+	}
+	<-serverStopping
+}
@@ -0,0 +1,177 @@
+// Package main - sessions.go
+//
+// This file tracks MCP sessions for the Streamable HTTP transport.
+//
+// Key Responsibilities:
+//   - Create a session on 'initialize' and return its id in the Mcp-Session-Id header
+//   - Remember what the client advertised (protocol version, capabilities such as elicitation)
+//   - Route JSON-RPC responses sent by the client (e.g. to elicitation/create) to the
+//     tool call that is waiting for them
+//   - Expire idle sessions and terminate sessions on DELETE /mcp
+//   - Keep at most maxSessions sessions, evicting the least recently used one
+//
+// Clients that never send Mcp-Session-Id keep working as before; they simply cannot take
+// part in server-initiated requests such as elicitation. Sessions live in the memory of one
+// instance: after a restart, or on another instance, the session id is unknown and only
+// requests that need the session are refused (see mcpHandler).
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const sessionIdleTimeout = 24 * time.Hour
+
+// maxSessions bounds the session store; creating a session beyond it evicts expired
+// sessions, then the least recently used one
+const maxSessions = 10000
+
+// session is the server-side state of one MCP client connection
+type session struct {
+	ID              string
+	ProtocolVersion string
+	ClientInfo      ClientInfo
+	Capabilities    map[string]interface{}
+
+	mu       sync.Mutex
+	lastSeen time.Time
+	nextID   int
+	pending  map[string]chan JSONRPCResponse
+}
+
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+var sessions = &sessionStore{sessions: map[string]*session{}}
+
+// create registers a new session for an initialize request
+func (s *sessionStore) create(params InitializeParams, protocolVersion string) *session {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	sess := &session{
+		ID:              hex.EncodeToString(buf),
+		ProtocolVersion: protocolVersion,
+		ClientInfo:      params.ClientInfo,
+		lastSeen:        time.Now(),
+		pending:         map[string]chan JSONRPCResponse{},
+	}
+	if caps, ok := params.Capabilities.(map[string]interface{}); ok {
+		sess.Capabilities = caps
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.sessions) >= maxSessions {
+		s.pruneLocked()
+	}
+	s.sessions[sess.ID] = sess
+	return sess
+}
+
+// get returns the session with the given id, or nil if it is unknown or expired
+func (s *sessionStore) get(id string) *session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	now := time.Now()
+	if now.Sub(sess.lastSeen) > sessionIdleTimeout {
+		delete(s.sessions, id)
+		return nil
+	}
+	sess.lastSeen = now
+	return sess
+}
+
+// remove terminates a session
+func (s *sessionStore) remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.sessions[id]
+	delete(s.sessions, id)
+	return ok
+}
+
+// pruneLocked removes expired sessions and, if the store is still full, the least
+// recently used one
+func (s *sessionStore) pruneLocked() {
+	var oldestID string
+	var oldest time.Time
+	for id, sess := range s.sessions {
+		sess.mu.Lock()
+		lastSeen := sess.lastSeen
+		sess.mu.Unlock()
+		if time.Since(lastSeen) > sessionIdleTimeout {
+			delete(s.sessions, id)
+			continue
+		}
+		if oldestID == "" || lastSeen.Before(oldest) {
+			oldestID, oldest = id, lastSeen
+		}
+	}
+	if len(s.sessions) >= maxSessions {
+		delete(s.sessions, oldestID)
+	}
+}
+
+// supports reports whether the client advertised a capability at initialize
+func (sess *session) supports(capability string) bool {
+	_, ok := sess.Capabilities[capability]
+	return ok
+}
+
+// expectResponse allocates an id for a server-initiated request and returns the
+// channel on which the client's response will be delivered
+func (sess *session) expectResponse(prefix string) (string, chan JSONRPCResponse) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.nextID++
+	id := fmt.Sprintf("%s-%d", prefix, sess.nextID)
+	ch := make(chan JSONRPCResponse, 1)
+	sess.pending[id] = ch
+	return id, ch
+}
+
+// forget stops waiting for a response
+func (sess *session) forget(id string) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	delete(sess.pending, id)
+}
+
+// deliver hands a client response to the waiting request; it reports false if
+// nothing is waiting for that id
+func (sess *session) deliver(resp JSONRPCResponse) bool {
+	id := fmt.Sprint(resp.ID)
+	sess.mu.Lock()
+	ch, ok := sess.pending[id]
+	delete(sess.pending, id)
+	sess.mu.Unlock()
+	if ok {
+		ch <- resp
+	}
+	return ok
+}
+
+type sessionContextKey struct{}
+
+func withSession(ctx context.Context, sess *session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, sess)
+}
+
+// sessionFromContext returns the MCP session of the request, or nil
+func sessionFromContext(ctx context.Context) *session {
+	sess, _ := ctx.Value(sessionContextKey{}).(*session)
+	return sess
+}
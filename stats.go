@@ -0,0 +1,194 @@
+// Package main - stats.go
+//
+// This file implements the catalog_stats tool, which aggregates product prices server-side
+// so agents do not have to pull the whole catalog into their context.
+//
+// Key Responsibilities:
+//   - Select products with the same filters as adjust_prices (category, segment, name, price range)
+//   - Group them by category, segment, both, or not at all
+//   - Compute count, sum, min, max, mean, median and requested percentiles of price per group
+//   - Return one structured row per group plus a total row over every matched product
+//
+// Percentiles use linear interpolation between closest ranks, so p50 equals the median
+// and p0/p100 equal min/max. Monetary results are rounded to cents.
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// defaultPercentiles are reported when the call does not ask for specific ones
+var defaultPercentiles = []float64{25, 75, 90}
+
+// noGroupValue labels products that have no value for a group_by field
+const noGroupValue = "(none)"
+
+// statsRow holds the price statistics of one group
+type statsRow struct {
+	Category    string             `json:"category,omitempty"`
+	Segment     string             `json:"segment,omitempty"`
+	Count       int                `json:"count"`
+	Sum         float64            `json:"sum"`
+	Min         float64            `json:"min"`
+	Max         float64            `json:"max"`
+	Mean        float64            `json:"mean"`
+	Median      float64            `json:"median"`
+	Percentiles map[string]float64 `json:"percentiles"`
+}
+
+// Computes price statistics per category and/or segment for the products matching the filters
+func catalogStats(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	groupBy, err := parseGroupBy(params)
+	if err != nil {
+		return nil, err
+	}
+	percentiles, err := parsePercentiles(params)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := fetchProducts(ctx, "/products", productServiceBaseURL+"/products")
+	if err != nil {
+		return nil, err
+	}
+	products = filterProducts(products, params)
+	products = filterPriceRange(products, params)
+
+	type groupKey struct{ category, segment string }
+	groups := map[groupKey][]float64{}
+	var all []float64
+	for _, p := range products {
+		var key groupKey
+		for _, field := range groupBy {
+			value, _ := p[field].(string)
+			if value == "" {
+				value = noGroupValue
+			}
+			if field == "category" {
+				key.category = value
+			} else {
+				key.segment = value
+			}
+		}
+		price := toFloat64(p["price"])
+		groups[key] = append(groups[key], price)
+		all = append(all, price)
+	}
+
+	rows := make([]statsRow, 0, len(groups))
+	if len(groupBy) > 0 {
+		for key, prices := range groups {
+			row := computeStats(prices, percentiles)
+			row.Category, row.Segment = key.category, key.segment
+			rows = append(rows, row)
+		}
+		sort.Slice(rows, func(i, j int) bool {
+			if rows[i].Category != rows[j].Category {
+				return rows[i].Category < rows[j].Category
+			}
+			return rows[i].Segment < rows[j].Segment
+		})
+	}
+
+	return map[string]interface{}{
+		"group_by": groupBy,
+		"rows":     rows,
+		"total":    computeStats(all, percentiles),
+	}, nil
+}
+
+// parseGroupBy accepts "category", "segment", "none", or an array of category/segment
+func parseGroupBy(params map[string]interface{}) ([]string, error) {
+	var fields []string
+	switch v := params["group_by"].(type) {
+	case nil:
+		return []string{"category"}, nil
+	case string:
+		if v != "none" && v != "" {
+			fields = []string{v}
+		}
+	case []interface{}:
+		for _, item := range v {
+			field, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid 'group_by' argument: expected strings")
+			}
+			fields = append(fields, field)
+		}
+	default:
+		return nil, fmt.Errorf("invalid 'group_by' argument: expected a string or an array of strings")
+	}
+
+	seen := map[string]bool{}
+	var result []string
+	for _, field := range fields {
+		if field != "category" && field != "segment" {
+			return nil, fmt.Errorf("invalid 'group_by' value %q (expected category, segment or none)", field)
+		}
+		if !seen[field] {
+			seen[field] = true
+			result = append(result, field)
+		}
+	}
+	// keep a stable category, segment order regardless of how they were given
+	sort.Strings(result)
+	return result, nil
+}
+
+func parsePercentiles(params map[string]interface{}) ([]float64, error) {
+	v, present := params["percentiles"]
+	if !present {
+		return defaultPercentiles, nil
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid 'percentiles' argument: expected an array of numbers")
+	}
+	percentiles := make([]float64, 0, len(items))
+	for _, item := range items {
+		p, ok := item.(float64)
+		if !ok || p < 0 || p > 100 {
+			return nil, fmt.Errorf("invalid 'percentiles' value %v: must be a number between 0 and 100", item)
+		}
+		percentiles = append(percentiles, p)
+	}
+	return percentiles, nil
+}
+
+// computeStats summarizes a list of prices; an empty list yields a row with count 0
+func computeStats(prices []float64, percentiles []float64) statsRow {
+	row := statsRow{Count: len(prices), Percentiles: map[string]float64{}}
+	if len(prices) == 0 {
+		return row
+	}
+	sorted := append([]float64(nil), prices...)
+	sort.Float64s(sorted)
+
+	for _, p := range sorted {
+		row.Sum += p
+	}
+	row.Min = sorted[0]
+	row.Max = sorted[len(sorted)-1]
+	row.Mean = roundCents(row.Sum / float64(len(sorted)))
+	row.Sum = roundCents(row.Sum)
+	row.Median = roundCents(percentile(sorted, 50))
+	for _, p := range percentiles {
+		row.Percentiles["p"+strconv.FormatFloat(p, 'f', -1, 64)] = roundCents(percentile(sorted, p))
+	}
+	return row
+}
+
+// percentile interpolates linearly between the closest ranks of a sorted slice
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
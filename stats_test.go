@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestComputeStats(t *testing.T) {
+	row := computeStats([]float64{40, 10, 30, 20}, []float64{0, 25, 100})
+	if row.Count != 4 || row.Sum != 100 || row.Min != 10 || row.Max != 40 || row.Mean != 25 || row.Median != 25 {
+		t.Errorf("Unexpected stats: %#v", row)
+	}
+	want := map[string]float64{"p0": 10, "p25": 17.5, "p100": 40}
+	for key, value := range want {
+		if row.Percentiles[key] != value {
+			t.Errorf("Expected %s = %.2f, got %.2f", key, value, row.Percentiles[key])
+		}
+	}
+
+	if empty := computeStats(nil, defaultPercentiles); empty.Count != 0 || len(empty.Percentiles) != 0 {
+		t.Errorf("Expected an empty row for no prices, got %#v", empty)
+	}
+}
+
+func TestCatalogStatsGrouping(t *testing.T) {
+	newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Premium", 1000),
+		testProduct("2", "Phone", "Electronics", "Budget", 200),
+		testProduct("3", "Tablet", "Electronics", "Premium", 600),
+		testProduct("4", "Chair", "Furniture", "Budget", 50),
+	)
+
+	result, err := executeToolCall(context.Background(), "catalog_stats", map[string]interface{}{
+		"group_by": []interface{}{"segment", "category"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := result.(map[string]interface{})
+	rows := stats["rows"].([]statsRow)
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 category/segment groups, got %#v", rows)
+	}
+	if first := rows[0]; first.Category != "Electronics" || first.Segment != "Budget" || first.Count != 1 {
+		t.Errorf("Expected rows sorted by category then segment, got %#v", first)
+	}
+	if premium := rows[1]; premium.Count != 2 || premium.Mean != 800 {
+		t.Errorf("Expected 2 Electronics/Premium products with mean 800, got %#v", premium)
+	}
+	if total := stats["total"].(statsRow); total.Count != 4 || total.Max != 1000 {
+		t.Errorf("Unexpected total row: %#v", total)
+	}
+
+	result, err = executeToolCall(context.Background(), "catalog_stats", map[string]interface{}{
+		"group_by": "none", "segment": "premium", "min_price": 700.0,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats = result.(map[string]interface{})
+	if len(stats["rows"].([]statsRow)) != 0 || stats["total"].(statsRow).Count != 1 {
+		t.Errorf("Expected only a total row over 1 filtered product, got %#v", stats)
+	}
+
+	if _, err := executeToolCall(context.Background(), "catalog_stats", map[string]interface{}{"group_by": "brand"}); err == nil {
+		t.Error("Expected an error for an unknown group_by field")
+	}
+}
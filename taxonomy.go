@@ -0,0 +1,101 @@
+// Package main - taxonomy.go
+//
+// This file implements the list_taxonomy tool and the catalog://taxonomy resource, which
+// report the categories and segments that actually exist in the catalog.
+//
+// Key Responsibilities:
+//   - Collect distinct category and segment values, compared case-insensitively
+//   - Count the products in each one
+//   - Report every spelling found in the data (e.g. "Electronics" and "electronics")
+//     so agents can pick valid values and spot inconsistent data
+//
+// Each entry is named after its most common spelling (ties broken alphabetically).
+// Products without a category or segment are counted separately.
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// taxonomyEntry is one distinct category or segment
+type taxonomyEntry struct {
+	Name     string   `json:"name"`
+	Count    int      `json:"count"`
+	Variants []string `json:"variants"`
+}
+
+// taxonomy is the result of list_taxonomy
+type taxonomy struct {
+	Categories      []taxonomyEntry `json:"categories,omitempty"`
+	Segments        []taxonomyEntry `json:"segments,omitempty"`
+	Products        int             `json:"products"`
+	WithoutCategory int             `json:"without_category,omitempty"`
+	WithoutSegment  int             `json:"without_segment,omitempty"`
+}
+
+// Lists the distinct categories and/or segments of the products matching the optional filters
+func listTaxonomy(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	field, _ := params["field"].(string)
+	switch field {
+	case "", "both", "category", "segment":
+	default:
+		return nil, fmt.Errorf("invalid 'field' argument %q (expected category, segment or both)", field)
+	}
+
+	products, err := fetchProducts(ctx, "/products", productServiceBaseURL+"/products")
+	if err != nil {
+		return nil, err
+	}
+	products = filterProducts(products, params)
+
+	result := taxonomy{Products: len(products)}
+	if field != "segment" {
+		result.Categories, result.WithoutCategory = distinctValues(products, "category")
+	}
+	if field != "category" {
+		result.Segments, result.WithoutSegment = distinctValues(products, "segment")
+	}
+	return result, nil
+}
+
+// distinctValues groups the values of a product field case-insensitively and returns the
+// groups sorted by name, plus the number of products without a value
+func distinctValues(products []map[string]interface{}, field string) ([]taxonomyEntry, int) {
+	spellings := map[string]map[string]int{}
+	missing := 0
+	for _, p := range products {
+		value, _ := p[field].(string)
+		value = strings.TrimSpace(value)
+		if value == "" {
+			missing++
+			continue
+		}
+		key := strings.ToLower(value)
+		if spellings[key] == nil {
+			spellings[key] = map[string]int{}
+		}
+		spellings[key][value]++
+	}
+
+	entries := make([]taxonomyEntry, 0, len(spellings))
+	for _, variants := range spellings {
+		entry := taxonomyEntry{}
+		best := 0
+		for variant, count := range variants {
+			entry.Count += count
+			entry.Variants = append(entry.Variants, variant)
+			if count > best || (count == best && variant < entry.Name) {
+				entry.Name, best = variant, count
+			}
+		}
+		sort.Strings(entry.Variants)
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return strings.ToLower(entries[i].Name) < strings.ToLower(entries[j].Name)
+	})
+	return entries, missing
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestListTaxonomy(t *testing.T) {
+	newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Premium", 1000),
+		testProduct("2", "Phone", "electronics", "Budget", 200),
+		testProduct("3", "Tablet", "Electronics", "Premium", 600),
+		testProduct("4", "Chair", "Furniture", "", 50),
+	)
+
+	result, err := executeToolCall(context.Background(), "list_taxonomy", map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tax := result.(taxonomy)
+	if len(tax.Categories) != 2 || tax.Products != 4 || tax.WithoutSegment != 1 {
+		t.Fatalf("Unexpected taxonomy: %#v", tax)
+	}
+	electronics := tax.Categories[0]
+	if electronics.Name != "Electronics" || electronics.Count != 3 || len(electronics.Variants) != 2 {
+		t.Errorf("Expected Electronics with 3 products and 2 spellings, got %#v", electronics)
+	}
+
+	result, err = executeToolCall(context.Background(), "list_taxonomy", map[string]interface{}{
+		"field": "segment", "category": "ELECTRONICS",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tax = result.(taxonomy)
+	if len(tax.Categories) != 0 || len(tax.Segments) != 2 || tax.Segments[1].Name != "Premium" || tax.Segments[1].Count != 2 {
+		t.Errorf("Expected the Budget and Premium segments of Electronics, got %#v", tax)
+	}
+}
+
+func TestTaxonomyResource(t *testing.T) {
+	newFakeProductService(t, testProduct("1", "Laptop5", "Electronics", "Premium", 1000))
+	handler := mcpHandler(defaultConfig())
+
+	call := func(body string) JSONRPCResponse {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body)))
+		var resp JSONRPCResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	list := call(`{"jsonrpc":"2.0","id":1,"method":"resources/list"}`)
+	if !strings.Contains(toJSON(list.Result), "catalog://taxonomy") {
+		t.Errorf("Expected catalog://taxonomy in resources/list, got %v", list.Result)
+	}
+	read := call(`{"jsonrpc":"2.0","id":2,"method":"resources/read","params":{"uri":"catalog://taxonomy"}}`)
+	if read.Error != nil || !strings.Contains(toJSON(read.Result), "Electronics") {
+		t.Errorf("Expected the taxonomy contents, got %v %v", read.Result, read.Error)
+	}
+	missing := call(`{"jsonrpc":"2.0","id":3,"method":"resources/read","params":{"uri":"catalog://nope"}}`)
+	if missing.Error == nil || missing.Error.Code != -32002 {
+		t.Errorf("Expected a -32002 error for an unknown resource, got %v", missing.Error)
+	}
+}
+
+func toJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -9,26 +9,49 @@
 //   - Describe tool capabilities and requirements
 //
 // Tool Categories:
-//   1. Service Health Tools:
-//      - welcome_message: Get welcome message
-//      - health_check: Check service health
 //
-//   2. Single Product Operations:
-//      - create_product: Create a new product
-//      - get_product: Retrieve product by ID
-//      - update_product: Update existing product
-//      - delete_product: Delete product by ID
-//      - list_products: List all products
+//  1. Service Health Tools:
+//     - welcome_message: Get welcome message
+//     - health_check: Check service health
 //
-//   3. Batch Operations:
-//      - create_multiple_products: Batch create products
-//      - update_products: Batch update products
-//      - delete_products: Batch delete products
+//  2. Single Product Operations:
+//     - create_product: Create a new product
+//     - get_product: Retrieve product by ID
+//     - update_product: Update existing product
+//     - delete_product: Delete product by ID
+//     - list_products: List all products
 //
-//   4. Query/Filter Operations:
-//      - get_products_by_category: Filter by category
-//      - get_products_by_segment: Filter by segment
-//      - get_product_by_name: Search by name
+//  3. Batch Operations:
+//     - create_multiple_products: Batch create products
+//     - update_products: Batch update products
+//     - delete_products: Batch delete products
+//     - import_products: Import a CSV or JSON Lines product list
+//
+//  4. Query/Filter Operations:
+//     - get_products_by_category: Filter by category
+//     - get_products_by_segment: Filter by segment
+//     - get_product_by_name: Search by name
+//     - search_products: Filter, sort and limit products
+//     - list_taxonomy: Distinct categories and segments with counts
+//
+//  5. Price Operations:
+//     - adjust_prices: Bulk percentage/delta/set/rounding price changes
+//
+//  6. Analytics:
+//     - catalog_stats: Price statistics grouped by category and/or segment
+//
+//  7. Audit:
+//     - get_audit_log: Who changed what, when (read-only)
+//     - undo_change: Revert a recorded change by its id
+//     - get_product_history: Recorded versions of a product (read-only)
+//
+//  8. Data Quality:
+//     - find_duplicates: Clusters of near-duplicate products (read-only)
+//     - merge_products: Keep one product of a cluster and delete the others
+//     - validate_catalog: Violations of the catalog validation rules (read-only)
+//
+// Mutating tools (create, update, delete, batch variants, adjust_prices and undo_change) accept an optional
+// 'dry_run' argument, see dryrun.go.
 //
 // Tool Schema Structure:
 //   - Name: Unique identifier for the tool
@@ -43,6 +66,54 @@
 //   - executeToolCall() in business.go (validates tool existence)
 package main
 
+// dryRunProperty is the schema of the dry_run argument accepted by every mutating tool
+var dryRunProperty = map[string]interface{}{
+	"type":        "boolean",
+	"description": "If true, validate the request and return the changes it would make without applying them",
+}
+
+// idempotencyKeyProperty is the retry-safety argument of the create tools (see idempotency.go)
+var idempotencyKeyProperty = map[string]interface{}{
+	"type":        "string",
+	"description": "A unique key for this request, e.g. a UUID. Repeating the call with the same key and arguments returns the original result instead of creating the products again; reusing the key with different arguments is an error.",
+}
+
+// atomicProperty is the all-or-nothing argument of the batch tools (see atomic.go)
+var atomicProperty = map[string]interface{}{
+	"type":        "boolean",
+	"description": "If true, apply the batch in full or not at all: if any product fails, the products already written are restored (created ones deleted, updated ones reverted) and the error lists what was rolled back. Slower, since every product is written separately.",
+}
+
+// expectedProperty and expectedVersionProperty are the optimistic concurrency arguments of
+// update_product and update_products items (see concurrency.go)
+var expectedProperty = map[string]interface{}{
+	"type":        "object",
+	"description": "Field values as previously read, e.g. {\"price\": 999}. The update is refused with a conflict error listing the current values if any of them changed since.",
+}
+
+var expectedVersionProperty = map[string]interface{}{
+	"description": "The product 'version' as previously read; only for product services that report one. The update is refused with a conflict error if the version changed since.",
+}
+
+// asOfProperty is the point-in-time argument of get_product and list_products (see history.go)
+var asOfProperty = map[string]interface{}{
+	"type":        "string",
+	"description": "Return the state at this past time (RFC 3339, e.g. '2026-10-01T00:00:00Z'), reconstructed from the change history recorded by this server",
+}
+
+// cursorProperty and pageSizeProperty are the pagination arguments of the product listing tools
+var cursorProperty = map[string]interface{}{
+	"type":        "string",
+	"description": "Opaque cursor from the previous page's nextCursor. Repeat the other arguments unchanged",
+}
+
+var pageSizeProperty = map[string]interface{}{
+	"type":        "integer",
+	"description": "Products per page (default 50, maximum 200)",
+	"minimum":     1,
+	"maximum":     200,
+}
+
 // MCP tools definition with expected request payloads
 var tools = []ToolSchema{
 	{
@@ -55,10 +126,10 @@ var tools = []ToolSchema{
 		Schema: map[string]interface{}{},
 		SampleRequest: map[string]interface{}{
 			"jsonrpc": "2.0",
-			"id": "<id>",
-			"method": "tools/call",
+			"id":      "<id>",
+			"method":  "tools/call",
 			"params": map[string]interface{}{
-				"name": "welcome_message",
+				"name":      "welcome_message",
 				"arguments": map[string]interface{}{},
 			},
 		},
@@ -73,65 +144,71 @@ var tools = []ToolSchema{
 		Schema: map[string]interface{}{},
 		SampleRequest: map[string]interface{}{
 			"jsonrpc": "2.0",
-			"id": "<id>",
-			"method": "tools/call",
+			"id":      "<id>",
+			"method":  "tools/call",
 			"params": map[string]interface{}{
-				"name": "health_check",
+				"name":      "health_check",
 				"arguments": map[string]interface{}{},
 			},
 		},
 	},
 	{
 		Name:        "create_product",
-		Description: "Use this tool to create a single new product in the catalog. Requires name, category, and price. Optionally accepts a segment. Returns the created product with its generated ID.",
+		Description: "Use this tool to create a single new product in the catalog. Requires name, category, and price. Optionally accepts a segment. Returns the created product with its generated ID. Pass an 'idempotency_key' so that retrying after a timeout does not create a duplicate.",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"name":     map[string]string{"type": "string"},
-				"category": map[string]string{"type": "string"},
-				"segment":  map[string]string{"type": "string"},
-				"price":    map[string]string{"type": "number"},
+				"name":            map[string]string{"type": "string"},
+				"category":        map[string]string{"type": "string"},
+				"segment":         map[string]string{"type": "string"},
+				"price":           map[string]string{"type": "number"},
+				"idempotency_key": idempotencyKeyProperty,
+				"dry_run":         dryRunProperty,
 			},
 			"required": []string{"name", "category", "price"},
 		},
 		Schema: map[string]interface{}{
-			"name":     "string",
-			"category": "string",
-			"segment":  "string",
-			"price":    "number",
+			"name":            "string",
+			"category":        "string",
+			"segment":         "string",
+			"price":           "number",
+			"idempotency_key": "string (optional)",
+			"dry_run":         "boolean (optional)",
 		},
 		SampleRequest: map[string]interface{}{
 			"jsonrpc": "2.0",
-			"id": "<id>",
-			"method": "tools/call",
+			"id":      "<id>",
+			"method":  "tools/call",
 			"params": map[string]interface{}{
 				"name": "create_product",
 				"arguments": map[string]interface{}{
-					"name": "<product name>",
+					"name":     "<product name>",
 					"category": "<category name>",
-					"segment": "<segment name>",
-					"price": "<price>",
+					"segment":  "<segment name>",
+					"price":    "<price>",
 				},
 			},
 		},
 	},
 	{
 		Name:        "get_product",
-		Description: "Use this tool to retrieve a single product by its unique ID. Returns full product details including name, category, segment, and price.",
+		Description: "Use this tool to retrieve a single product by its unique ID. Returns full product details including name, category, segment, and price. With 'as_of', returns the recorded version of the product at that time instead (with the tool call that produced it).",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"id": map[string]string{"type": "string"},
+				"id":    map[string]string{"type": "string"},
+				"as_of": asOfProperty,
 			},
 			"required": []string{"id"},
 		},
 		Schema: map[string]interface{}{
-			"id": "string",
+			"id":    "string",
+			"as_of": "string (optional, RFC 3339)",
 		},
 		SampleRequest: map[string]interface{}{
 			"jsonrpc": "2.0",
-			"id": "<id>",
-			"method": "tools/call",
+			"id":      "<id>",
+			"method":  "tools/call",
 			"params": map[string]interface{}{
 				"name": "get_product",
 				"arguments": map[string]interface{}{
@@ -142,55 +219,65 @@ var tools = []ToolSchema{
 	},
 	{
 		Name:        "update_product",
-		Description: "Use this tool to update a single existing product by its ID. Only the provided fields (name, price, category) are modified; omitted fields remain unchanged. ID is required, all other fields are optional.",
+		Description: "Use this tool to update a single existing product by its ID. The arguments are a JSON Merge Patch: only the provided fields (name, category, segment, price) are modified, omitted fields remain unchanged, and null clears a field. Returns the updated product and the list of fields that actually changed. ID is required, all other fields are optional. To avoid overwriting a concurrent change, pass the values you read before as 'expected' (or the product's 'expected_version'); the update is then refused with a conflict error listing the current values if the product changed since.",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"id":    map[string]string{"type": "string"},
-				"name":  map[string]string{"type": "string"},
-				"price": map[string]string{"type": "number"},
-				"category": map[string]string{"type": "string"},
+				"id":               map[string]string{"type": "string"},
+				"name":             map[string]interface{}{"type": []string{"string", "null"}},
+				"price":            map[string]interface{}{"type": []string{"number", "null"}},
+				"category":         map[string]interface{}{"type": []string{"string", "null"}},
+				"segment":          map[string]interface{}{"type": []string{"string", "null"}},
+				"expected":         expectedProperty,
+				"expected_version": expectedVersionProperty,
+				"dry_run":          dryRunProperty,
 			},
 			"required": []string{"id"},
 		},
 		Schema: map[string]interface{}{
-			"id": "string",
-			"name": "string",
-			"price": "number",
-			"category": "string",
+			"id":               "string",
+			"name":             "string or null",
+			"price":            "number or null",
+			"category":         "string or null",
+			"segment":          "string or null",
+			"expected":         "object of previously read field values (optional)",
+			"expected_version": "previously read product version (optional)",
+			"dry_run":          "boolean (optional)",
 		},
 		SampleRequest: map[string]interface{}{
 			"jsonrpc": "2.0",
-			"id": "<id>",
-			"method": "tools/call",
+			"id":      "<id>",
+			"method":  "tools/call",
 			"params": map[string]interface{}{
 				"name": "update_product",
 				"arguments": map[string]interface{}{
-					"id": "12345",
-					"name": "Laptop5",
+					"id":       "12345",
+					"name":     "Laptop5",
 					"category": "<category name>",
-					"price": 1099,
+					"price":    1099,
 				},
 			},
 		},
 	},
 	{
 		Name:        "delete_product",
-		Description: "Use this tool to permanently delete a single product from the catalog by its ID. This action cannot be undone.",
+		Description: "Use this tool to permanently delete a single product from the catalog by its ID. The deletion can be reverted with undo_change using the change id from get_audit_log.",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"id": map[string]string{"type": "string"},
+				"id":      map[string]string{"type": "string"},
+				"dry_run": dryRunProperty,
 			},
 			"required": []string{"id"},
 		},
 		Schema: map[string]interface{}{
-			"id": "string",
+			"id":      "string",
+			"dry_run": "boolean (optional)",
 		},
 		SampleRequest: map[string]interface{}{
 			"jsonrpc": "2.0",
-			"id": "<id>",
-			"method": "tools/call",
+			"id":      "<id>",
+			"method":  "tools/call",
 			"params": map[string]interface{}{
 				"name": "delete_product",
 				"arguments": map[string]interface{}{
@@ -201,54 +288,68 @@ var tools = []ToolSchema{
 	},
 	{
 		Name:        "list_products",
-		Description: "Use this tool to list all products in the catalog. Returns an array of every product with full details including ID, name, category, segment, and price. Takes no arguments. Also useful for answering comparative questions like 'most expensive product', 'cheapest product', or 'how many products exist'. For follow-up questions about a specific product's price, category, or details, use get_product_by_name instead of calling this again. For filtered comparisons (e.g., 'most expensive laptop'), prefer search_products or get_products_by_category.",
+		Description: "Use this tool to list all products in the catalog. Returns one page of products with full details including ID, name, category, segment, and price, the total count, and a nextCursor to pass as 'cursor' for the next page (absent on the last page). Also useful for answering 'how many products exist' (see 'total'). For comparative questions like 'most expensive product' or 'cheapest product', prefer search_products with 'limit', and for aggregates prefer catalog_stats. For follow-up questions about a specific product's price, category, or details, use get_product_by_name instead of calling this again. For filtered comparisons (e.g., 'most expensive laptop'), prefer search_products or get_products_by_category. With 'as_of', lists the catalog as it was at that time.",
 		InputSchema: map[string]interface{}{
-			"type":       "object",
-			"properties": map[string]interface{}{},
+			"type": "object",
+			"properties": map[string]interface{}{
+				"as_of":     asOfProperty,
+				"cursor":    cursorProperty,
+				"page_size": pageSizeProperty,
+			},
+		},
+		Schema: map[string]interface{}{
+			"as_of":     "string (optional, RFC 3339)",
+			"cursor":    "string (optional)",
+			"page_size": "integer (optional, default 50, max 200)",
 		},
-		Schema: map[string]interface{}{},
 		SampleRequest: map[string]interface{}{
 			"jsonrpc": "2.0",
-			"id": "<id>",
-			"method": "tools/call",
+			"id":      "<id>",
+			"method":  "tools/call",
 			"params": map[string]interface{}{
-				"name": "list_products",
+				"name":      "list_products",
 				"arguments": map[string]interface{}{},
 			},
 		},
 	},
 	{
 		Name:        "create_multiple_products",
-		Description: "Use this tool to create multiple products in a single batch operation. Accepts an array of product objects, each with name, category, segment, and price. Prefer this over repeated create_product calls.",
+		Description: "Use this tool to create multiple products in a single batch operation. Accepts an array of product objects, each with name, category, segment, and price. Prefer this over repeated create_product calls. Pass an 'idempotency_key' so that retrying after a timeout does not create duplicates. Pass 'atomic': true to have the whole batch rolled back if any product fails.",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"products": map[string]interface{}{"type": "array"},
+				"products":        map[string]interface{}{"type": "array"},
+				"idempotency_key": idempotencyKeyProperty,
+				"atomic":          atomicProperty,
+				"dry_run":         dryRunProperty,
 			},
 			"required": []string{"products"},
 		},
 		Schema: map[string]interface{}{
-			"products": "array of product objects",
+			"products":        "array of product objects",
+			"idempotency_key": "string (optional)",
+			"atomic":          "boolean (optional)",
+			"dry_run":         "boolean (optional)",
 		},
 		SampleRequest: map[string]interface{}{
 			"jsonrpc": "2.0",
-			"id": "<id>",
-			"method": "tools/call",
+			"id":      "<id>",
+			"method":  "tools/call",
 			"params": map[string]interface{}{
 				"name": "create_multiple_products",
 				"arguments": map[string]interface{}{
 					"products": []map[string]interface{}{
 						{
-							"name": "<product name>",
+							"name":     "<product name>",
 							"category": "<category name>",
-							"segment": "<segment>",
-							"price": "<price>",
+							"segment":  "<segment>",
+							"price":    "<price>",
 						},
 						{
-							"name": "<product name>",
+							"name":     "<product name>",
 							"category": "<category name>",
-							"segment": "<segment>",
-							"price": "<price>",
+							"segment":  "<segment>",
+							"price":    "<price>",
 						},
 					},
 				},
@@ -257,36 +358,40 @@ var tools = []ToolSchema{
 	},
 	{
 		Name:        "update_products",
-		Description: "Use this tool to update multiple products in a single batch operation. Accepts an array of product objects, each identified by its ID with the fields to update. Prefer this over repeated update_product calls.",
+		Description: "Use this tool to update multiple products in a single batch operation. Accepts an array of product objects, each identified by its ID with the fields to update. Prefer this over repeated update_product calls. Each object may carry 'expected' (previously read field values) or 'expected_version'; if any product changed since it was read, the whole batch is refused with a conflict error listing the current values. Pass 'atomic': true to have the whole batch rolled back if any update fails.",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
 				"products": map[string]interface{}{"type": "array"},
+				"atomic":   atomicProperty,
+				"dry_run":  dryRunProperty,
 			},
 			"required": []string{"products"},
 		},
 		Schema: map[string]interface{}{
-			"products": "array of product update objects",
+			"products": "array of product update objects (each with optional 'expected' / 'expected_version')",
+			"atomic":   "boolean (optional)",
+			"dry_run":  "boolean (optional)",
 		},
 		SampleRequest: map[string]interface{}{
 			"jsonrpc": "2.0",
-			"id": "<id>",
-			"method": "tools/call",
+			"id":      "<id>",
+			"method":  "tools/call",
 			"params": map[string]interface{}{
 				"name": "update_products",
 				"arguments": map[string]interface{}{
 					"products": []map[string]interface{}{
 						{
-							"id": "<product id>",
+							"id":       "<product id>",
 							"category": "<category name>",
-							"segment": "<segment>",
-							"price": "<price>",
+							"segment":  "<segment>",
+							"price":    "<price>",
 						},
 						{
-							"id": "<product id>",
+							"id":       "<product id>",
 							"category": "<category name>",
-							"segment": "<segment>",
-							"price": "<price>",
+							"segment":  "<segment>",
+							"price":    "<price>",
 						},
 					},
 				},
@@ -295,21 +400,23 @@ var tools = []ToolSchema{
 	},
 	{
 		Name:        "delete_products",
-		Description: "Use this tool to permanently delete multiple products in a single batch operation. Accepts an array of product IDs. This action cannot be undone. Prefer this over repeated delete_product calls.",
+		Description: "Use this tool to permanently delete multiple products in a single batch operation. Accepts an array of product IDs. The deletion can be reverted with undo_change using the change id from get_audit_log. Prefer this over repeated delete_product calls. Not atomic: if the backend fails part-way, some products may already be deleted.",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"ids": map[string]interface{}{"type": "array"},
+				"ids":     map[string]interface{}{"type": "array"},
+				"dry_run": dryRunProperty,
 			},
 			"required": []string{"ids"},
 		},
 		Schema: map[string]interface{}{
-			"ids": "array of product ids",
+			"ids":     "array of product ids",
+			"dry_run": "boolean (optional)",
 		},
 		SampleRequest: map[string]interface{}{
 			"jsonrpc": "2.0",
-			"id": "<id>",
-			"method": "tools/call",
+			"id":      "<id>",
+			"method":  "tools/call",
 			"params": map[string]interface{}{
 				"name": "delete_products",
 				"arguments": map[string]interface{}{
@@ -320,21 +427,25 @@ var tools = []ToolSchema{
 	},
 	{
 		Name:        "get_products_by_category",
-		Description: "Use this tool to filter products by category (e.g., Electronics, Clothing, Food). Returns an array of all products belonging to the specified category with full details. Use this to answer comparative questions within a category, such as 'most expensive electronic', 'cheapest phone', or 'how many products are in Electronics'.",
+		Description: "Use this tool to filter products by category (e.g., Electronics, Clothing, Food; call list_taxonomy to see which categories exist). Returns one page of the products belonging to the specified category with full details, the total count, and a nextCursor for the next page. Use this to answer comparative questions within a category, such as 'most expensive electronic', 'cheapest phone', or 'how many products are in Electronics'.",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"category": map[string]string{"type": "string"},
+				"category":  map[string]string{"type": "string"},
+				"cursor":    cursorProperty,
+				"page_size": pageSizeProperty,
 			},
 			"required": []string{"category"},
 		},
 		Schema: map[string]interface{}{
-			"category": "string",
+			"category":  "string",
+			"cursor":    "string (optional)",
+			"page_size": "integer (optional, default 50, max 200)",
 		},
 		SampleRequest: map[string]interface{}{
 			"jsonrpc": "2.0",
-			"id": "<id>",
-			"method": "tools/call",
+			"id":      "<id>",
+			"method":  "tools/call",
 			"params": map[string]interface{}{
 				"name": "get_products_by_category",
 				"arguments": map[string]interface{}{
@@ -345,21 +456,25 @@ var tools = []ToolSchema{
 	},
 	{
 		Name:        "get_products_by_segment",
-		Description: "Use this tool to filter products by market segment (e.g., Premium, Budget, Enterprise). Returns an array of all products belonging to the specified segment with full details. Use this to answer comparative questions within a segment, such as 'most expensive premium product', 'cheapest budget item', or 'how many products are in the Enterprise segment'.",
+		Description: "Use this tool to filter products by market segment (e.g., Premium, Budget, Enterprise; call list_taxonomy to see which segments exist). Returns one page of the products belonging to the specified segment with full details, the total count, and a nextCursor for the next page. Use this to answer comparative questions within a segment, such as 'most expensive premium product', 'cheapest budget item', or 'how many products are in the Enterprise segment'.",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"segment": map[string]string{"type": "string"},
+				"segment":   map[string]string{"type": "string"},
+				"cursor":    cursorProperty,
+				"page_size": pageSizeProperty,
 			},
 			"required": []string{"segment"},
 		},
 		Schema: map[string]interface{}{
-			"segment": "string",
+			"segment":   "string",
+			"cursor":    "string (optional)",
+			"page_size": "integer (optional, default 50, max 200)",
 		},
 		SampleRequest: map[string]interface{}{
 			"jsonrpc": "2.0",
-			"id": "<id>",
-			"method": "tools/call",
+			"id":      "<id>",
+			"method":  "tools/call",
 			"params": map[string]interface{}{
 				"name": "get_products_by_segment",
 				"arguments": map[string]interface{}{
@@ -370,11 +485,14 @@ var tools = []ToolSchema{
 	},
 	{
 		Name:        "get_product_by_name",
-		Description: "Use this tool to look up a specific product by its name and get its full details including price, category, and segment. Call this when the user asks about a specific product's price, availability, or details. For example: 'What is the price of iPhone 17?' or 'Tell me about Laptop5'.",
+		Description: "Use this tool to look up a specific product by its name and get its full details including price, category, and segment. Call this when the user asks about a specific product's price, availability, or details. For example: 'What is the price of iPhone 17?' or 'Tell me about Laptop5'. The name does not have to be exact: if no product has exactly that name, the catalog is searched case-insensitively and then for similar names (typos, spacing, word order). The result then has 'match' ('case_insensitive', 'fuzzy' or 'ambiguous'), 'product' with the best match and a scored 'suggestions' list; when 'match' is 'ambiguous' there is no product, so ask the user which suggestion they meant.",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"name": map[string]string{"type": "string"},
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "Product name; case, spacing and small typos are tolerated",
+				},
 			},
 			"required": []string{"name"},
 		},
@@ -383,8 +501,8 @@ var tools = []ToolSchema{
 		},
 		SampleRequest: map[string]interface{}{
 			"jsonrpc": "2.0",
-			"id": "<id>",
-			"method": "tools/call",
+			"id":      "<id>",
+			"method":  "tools/call",
 			"params": map[string]interface{}{
 				"name": "get_product_by_name",
 				"arguments": map[string]interface{}{
@@ -395,17 +513,21 @@ var tools = []ToolSchema{
 	},
 	{
 		Name:        "search_products",
-		Description: "Use this tool to search, filter, and sort products. Supports filtering by category, segment, or name, and sorting by price or name in ascending or descending order. Use this to answer comparative questions like 'What is the most expensive iPhone?', 'What is the cheapest product in Electronics?', 'Show me the top 3 premium products by price', or 'What is the most expensive product in the laptops segment?'. Use the 'limit' parameter to return only the top N results.",
+		Description: "Use this tool to search, filter, and sort products. Pass 'query' for full-text search over names, categories and segments (e.g. 'gaming laptops'): every word must match, plurals and prefixes are matched, results are ranked by relevance and each carries a 'search' entry with its score and **highlighted** matches. Supports filtering by category, segment, or name, and sorting by price or name in ascending or descending order. Use this to answer comparative questions like 'What is the most expensive iPhone?', 'What is the cheapest product in Electronics?', 'Show me the top 3 premium products by price', or 'What is the most expensive product in the laptops segment?'. Use the 'limit' parameter to return only the top N results. For anything richer, pass a 'filter' expression, e.g. 'price >= 100 and price < 500', 'category in (\"Electronics\", \"Furniture\") and not segment = \"Budget\"' or '(name ^= \"iphone\" or name ~ \"^galaxy s[0-9]+\") and price <= 1200', and a multi-key 'sort' such as 'category asc, price desc'. Results are paginated: pass the returned nextCursor as 'cursor' to get the next page.",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "Full-text search over name, category and segment. Results are ranked by relevance unless sort_by or sort is given",
+				},
 				"category": map[string]interface{}{
 					"type":        "string",
-					"description": "Filter by product category (e.g., Electronics, Clothing, Food)",
+					"description": "Filter by product category (e.g., Electronics, Clothing, Food; see list_taxonomy for valid values)",
 				},
 				"segment": map[string]interface{}{
 					"type":        "string",
-					"description": "Filter by market segment (e.g., Premium, Budget, Enterprise)",
+					"description": "Filter by market segment (e.g., Premium, Budget, Enterprise; see list_taxonomy for valid values)",
 				},
 				"name": map[string]interface{}{
 					"type":        "string",
@@ -425,15 +547,33 @@ var tools = []ToolSchema{
 					"type":        "number",
 					"description": "Maximum number of results to return. Use 1 to get the single most/least expensive product",
 				},
+				"filter": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter expression over product fields (name, category, segment, price, id). Operators: = != < <= > >= (numeric for price), ^= (prefix), *= (contains), ~ (regex), in (...), not in (...). Combine with and, or, not and parentheses. String matching is case-insensitive. Applied together with the category/segment/name arguments",
+				},
+				"sort": map[string]interface{}{
+					"description": "Multi-key sort, e.g. 'category asc, price desc' or ['category asc', 'price desc']. Overrides sort_by/order",
+					"oneOf": []interface{}{
+						map[string]interface{}{"type": "string"},
+						map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+					},
+				},
+				"cursor":    cursorProperty,
+				"page_size": pageSizeProperty,
 			},
 		},
 		Schema: map[string]interface{}{
-			"category": "string (optional)",
-			"segment":  "string (optional)",
-			"name":     "string (optional)",
-			"sort_by":  "string (optional, 'price' or 'name')",
-			"order":    "string (optional, 'asc' or 'desc')",
-			"limit":    "number (optional)",
+			"query":     "string (optional, full-text search)",
+			"category":  "string (optional)",
+			"segment":   "string (optional)",
+			"name":      "string (optional)",
+			"sort_by":   "string (optional, 'price' or 'name')",
+			"order":     "string (optional, 'asc' or 'desc')",
+			"limit":     "number (optional)",
+			"filter":    "string (optional, filter expression)",
+			"sort":      "string or array (optional, e.g. 'category asc, price desc')",
+			"cursor":    "string (optional)",
+			"page_size": "integer (optional, default 50, max 200)",
 		},
 		SampleRequest: map[string]interface{}{
 			"jsonrpc": "2.0",
@@ -450,4 +590,522 @@ var tools = []ToolSchema{
 			},
 		},
 	},
+	{
+		Name:        "adjust_prices",
+		Description: "Use this tool to change the price of many products at once. The server selects products by category, segment, name and price range (at least one filter is required; pass 'all': true to reprice the whole catalog), computes each new price, applies all changes in one batch update and returns the before/after price of every matched product. Operations: 'percent' (e.g. value 3 raises prices by 3%, -10 lowers them by 10%), 'delta' (add a fixed amount), 'set' (same price for all) and 'round' (rounding only). Optional 'round_to' with 'round_mode' (nearest, up, down) rounds the result to a multiple such as 100 or 0.05, and 'floor'/'ceiling' clamp it. Use this instead of listing products and calling update_products yourself, e.g. 'increase Electronics prices by 3%' or 'round Laptops segment prices up to the nearest 100'.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"category": map[string]interface{}{
+					"type":        "string",
+					"description": "Only adjust products in this category (case-insensitive)",
+				},
+				"segment": map[string]interface{}{
+					"type":        "string",
+					"description": "Only adjust products in this segment (case-insensitive)",
+				},
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "Only adjust products whose name contains this text (case-insensitive)",
+				},
+				"min_price": map[string]interface{}{
+					"type":        "number",
+					"description": "Only adjust products priced at or above this value",
+				},
+				"max_price": map[string]interface{}{
+					"type":        "number",
+					"description": "Only adjust products priced at or below this value",
+				},
+				"operation": map[string]interface{}{
+					"type":        "string",
+					"description": "How to change the price: 'percent', 'delta', 'set' or 'round'",
+					"enum":        []string{"percent", "delta", "set", "round"},
+				},
+				"value": map[string]interface{}{
+					"type":        "number",
+					"description": "Percentage for 'percent', amount for 'delta', new price for 'set'. Not used by 'round'",
+				},
+				"round_to": map[string]interface{}{
+					"type":        "number",
+					"description": "Round the result to a multiple of this value (e.g. 100, 1, 0.05). Required for 'round'",
+				},
+				"round_mode": map[string]interface{}{
+					"type":        "string",
+					"description": "Rounding direction: 'nearest' (default), 'up' or 'down'",
+					"enum":        []string{"nearest", "up", "down"},
+				},
+				"floor": map[string]interface{}{
+					"type":        "number",
+					"description": "Minimum resulting price",
+				},
+				"ceiling": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum resulting price",
+				},
+				"all": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Set to true to adjust every product in the catalog. Required when no filter is given",
+				},
+				"atomic":  atomicProperty,
+				"dry_run": dryRunProperty,
+			},
+			"required": []string{"operation"},
+		},
+		Schema: map[string]interface{}{
+			"category":   "string (optional)",
+			"segment":    "string (optional)",
+			"name":       "string (optional)",
+			"min_price":  "number (optional)",
+			"max_price":  "number (optional)",
+			"operation":  "string ('percent', 'delta', 'set' or 'round')",
+			"value":      "number (required unless operation is 'round')",
+			"round_to":   "number (optional, required for 'round')",
+			"round_mode": "string (optional, 'nearest', 'up' or 'down')",
+			"floor":      "number (optional)",
+			"ceiling":    "number (optional)",
+			"all":        "boolean (required when no filter is given)",
+			"atomic":     "boolean (optional)",
+			"dry_run":    "boolean (optional)",
+		},
+		SampleRequest: map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      "<id>",
+			"method":  "tools/call",
+			"params": map[string]interface{}{
+				"name": "adjust_prices",
+				"arguments": map[string]interface{}{
+					"segment":    "Laptops",
+					"operation":  "round",
+					"round_to":   100,
+					"round_mode": "up",
+				},
+			},
+		},
+	},
+	{
+		Name:        "catalog_stats",
+		Description: "Use this tool to answer aggregate questions about the catalog without listing every product, e.g. 'What is the average price per category?', 'How many Premium products are there?' or 'What is the median laptop price?'. Returns one row per group with count, sum, min, max, mean, median and percentiles of price, plus a total row. Group by 'category' (default), 'segment', both (['category', 'segment']) or 'none', and optionally filter by category, segment, name and price range first.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"group_by": map[string]interface{}{
+					"description": "Grouping: 'category' (default), 'segment', 'none', or an array such as ['category', 'segment']",
+					"oneOf": []interface{}{
+						map[string]interface{}{"type": "string", "enum": []string{"category", "segment", "none"}},
+						map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string", "enum": []string{"category", "segment"}}},
+					},
+				},
+				"percentiles": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "number", "minimum": 0, "maximum": 100},
+					"description": "Percentiles of price to report (0-100). Defaults to [25, 75, 90]",
+				},
+				"category": map[string]interface{}{
+					"type":        "string",
+					"description": "Only include products in this category (case-insensitive)",
+				},
+				"segment": map[string]interface{}{
+					"type":        "string",
+					"description": "Only include products in this segment (case-insensitive)",
+				},
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "Only include products whose name contains this text (case-insensitive)",
+				},
+				"min_price": map[string]interface{}{
+					"type":        "number",
+					"description": "Only include products priced at or above this value",
+				},
+				"max_price": map[string]interface{}{
+					"type":        "number",
+					"description": "Only include products priced at or below this value",
+				},
+			},
+		},
+		Schema: map[string]interface{}{
+			"group_by":    "string or array (optional, 'category', 'segment', 'none' or ['category', 'segment'])",
+			"percentiles": "array of numbers (optional)",
+			"category":    "string (optional)",
+			"segment":     "string (optional)",
+			"name":        "string (optional)",
+			"min_price":   "number (optional)",
+			"max_price":   "number (optional)",
+		},
+		SampleRequest: map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      "<id>",
+			"method":  "tools/call",
+			"params": map[string]interface{}{
+				"name": "catalog_stats",
+				"arguments": map[string]interface{}{
+					"group_by":    []string{"category", "segment"},
+					"percentiles": []int{50, 90},
+				},
+			},
+		},
+	},
+	{
+		Name:        "list_taxonomy",
+		Description: "Use this tool to learn which product categories and segments exist before filtering, e.g. 'What categories do you have?' or 'Which segments exist in Electronics?'. Returns each distinct category and segment (compared case-insensitively) with its product count and every spelling found in the data. Use the returned names as values for get_products_by_category, get_products_by_segment, search_products and catalog_stats instead of guessing.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"field": map[string]interface{}{
+					"type":        "string",
+					"description": "Which values to list: 'category', 'segment' or 'both' (default)",
+					"enum":        []string{"category", "segment", "both"},
+				},
+				"category": map[string]interface{}{
+					"type":        "string",
+					"description": "Only consider products in this category (case-insensitive), e.g. to list its segments",
+				},
+				"segment": map[string]interface{}{
+					"type":        "string",
+					"description": "Only consider products in this segment (case-insensitive)",
+				},
+			},
+		},
+		Schema: map[string]interface{}{
+			"field":    "string (optional, 'category', 'segment' or 'both')",
+			"category": "string (optional)",
+			"segment":  "string (optional)",
+		},
+		SampleRequest: map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      "<id>",
+			"method":  "tools/call",
+			"params": map[string]interface{}{
+				"name": "list_taxonomy",
+				"arguments": map[string]interface{}{
+					"field":    "segment",
+					"category": "Electronics",
+				},
+			},
+		},
+	},
+	{
+		Name:        "get_audit_log",
+		Description: "Use this tool to find out who changed the catalog and when, e.g. 'Who deleted Laptop5 yesterday?' or 'What did the pricing agent change today?'. Returns recorded create, update, delete, batch and adjust_prices calls, newest first, with the caller, session, arguments, the state of each affected product before and after, and the outcome. Read-only.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"product": map[string]interface{}{
+					"type":        "string",
+					"description": "Only changes to this product, by id or name (case-insensitive)",
+				},
+				"tool": map[string]interface{}{
+					"type":        "string",
+					"description": "Only calls of this tool, e.g. 'delete_product'",
+				},
+				"caller": map[string]interface{}{
+					"type":        "string",
+					"description": "Only calls made with this API key name",
+				},
+				"session": map[string]interface{}{
+					"type":        "string",
+					"description": "Only calls made in this MCP session",
+				},
+				"outcome": map[string]interface{}{
+					"type":        "string",
+					"description": "Only calls with this outcome: 'ok' or an error kind such as 'backend_4xx'",
+				},
+				"change_id": map[string]interface{}{
+					"type":        "string",
+					"description": "A single record by its id",
+				},
+				"since": map[string]interface{}{
+					"type":        "string",
+					"description": "Only calls at or after this time (RFC 3339, e.g. '2026-10-18T00:00:00Z')",
+				},
+				"until": map[string]interface{}{
+					"type":        "string",
+					"description": "Only calls at or before this time (RFC 3339)",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of records to return (default 50, max 500)",
+				},
+			},
+		},
+		Schema: map[string]interface{}{
+			"product":   "string (optional, id or name)",
+			"tool":      "string (optional)",
+			"caller":    "string (optional)",
+			"session":   "string (optional)",
+			"outcome":   "string (optional)",
+			"change_id": "string (optional)",
+			"since":     "string (optional, RFC 3339)",
+			"until":     "string (optional, RFC 3339)",
+			"limit":     "integer (optional, default 50)",
+		},
+		SampleRequest: map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      "<id>",
+			"method":  "tools/call",
+			"params": map[string]interface{}{
+				"name": "get_audit_log",
+				"arguments": map[string]interface{}{
+					"product": "Laptop5",
+					"tool":    "delete_product",
+					"since":   "2026-10-17T00:00:00Z",
+				},
+			},
+		},
+	},
+	{
+		Name:        "undo_change",
+		Description: "Use this tool to revert a change made through this server when it was a mistake, e.g. 'Undo that delete' or 'Restore the prices from before the adjustment'. Find the change id with get_audit_log. A recorded create is reverted by deleting the product, an update by restoring the previous fields, and a delete by recreating the product. If any affected product was modified since, nothing is reverted and a conflict error lists the expected and current state of each product.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"change_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Id of the audit log record to revert, e.g. 'chg_5f0c2a9e1b7d4c33'",
+				},
+				"dry_run": dryRunProperty,
+			},
+			"required": []string{"change_id"},
+		},
+		Schema: map[string]interface{}{
+			"change_id": "string (required)",
+			"dry_run":   "boolean (optional)",
+		},
+		SampleRequest: map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      "<id>",
+			"method":  "tools/call",
+			"params": map[string]interface{}{
+				"name": "undo_change",
+				"arguments": map[string]interface{}{
+					"change_id": "chg_5f0c2a9e1b7d4c33",
+				},
+			},
+		},
+	},
+	{
+		Name:        "get_product_history",
+		Description: "Use this tool to see how a product changed over time, e.g. 'How did the price of Laptop5 evolve?' or 'When was this product renamed?'. Returns every recorded version of the product, newest first, with its timestamp, the changed fields, and for changes made through this server the tool, caller and change id (usable with undo_change). Versions with source 'observed' are changes noticed when reading the catalog. To see a product or the catalog at a past time, use get_product or list_products with 'as_of'.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"id": map[string]interface{}{
+					"type":        "string",
+					"description": "Product ID (use get_product_by_name to find it)",
+				},
+			},
+			"required": []string{"id"},
+		},
+		Schema: map[string]interface{}{
+			"id": "string (required)",
+		},
+		SampleRequest: map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      "<id>",
+			"method":  "tools/call",
+			"params": map[string]interface{}{
+				"name": "get_product_history",
+				"arguments": map[string]interface{}{
+					"id": "12345",
+				},
+			},
+		},
+	},
+	{
+		Name:        "find_duplicates",
+		Description: "Use this tool to find near-duplicate products created by different agents, e.g. 'iPhone 17', 'iphone17' and 'IPhone 17 '. Products are clustered by normalized name similarity, category and price proximity; each cluster has a confidence between 0 and 1, a suggested product to keep and the field values its products disagree on. Review the clusters, then merge them with merge_products. Optionally restrict the search to a category or segment.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"min_score": map[string]interface{}{
+					"type":        "number",
+					"description": "Lowest confidence for two products to be clustered (default 0.85, at most 1)",
+				},
+				"category": map[string]interface{}{
+					"type":        "string",
+					"description": "Only compare products in this category (case-insensitive)",
+				},
+				"segment": map[string]interface{}{
+					"type":        "string",
+					"description": "Only compare products in this segment (case-insensitive)",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of clusters to return (default 50, max 200)",
+				},
+			},
+		},
+		Schema: map[string]interface{}{
+			"min_score": "number (optional, default 0.85)",
+			"category":  "string (optional)",
+			"segment":   "string (optional)",
+			"limit":     "integer (optional, default 50)",
+		},
+		SampleRequest: map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      "<id>",
+			"method":  "tools/call",
+			"params": map[string]interface{}{
+				"name": "find_duplicates",
+				"arguments": map[string]interface{}{
+					"category": "Electronics",
+				},
+			},
+		},
+	},
+	{
+		Name:        "merge_products",
+		Description: "Use this tool to merge duplicate products found with find_duplicates into one. Keeps the product 'keep_id', sets the chosen field values on it ('fields' with explicit values, or 'take_from' to copy a field from one of the merged products), then deletes every product in 'merge_ids', all in one operation. The merge asks for confirmation and can be reverted with undo_change.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"keep_id": map[string]interface{}{
+					"type":        "string",
+					"description": "ID of the product to keep",
+				},
+				"merge_ids": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]string{"type": "string"},
+					"description": "IDs of the duplicates to delete",
+				},
+				"fields": map[string]interface{}{
+					"type":        "object",
+					"description": "Values to set on the kept product: name, category, segment and/or price",
+				},
+				"take_from": map[string]interface{}{
+					"type":        "object",
+					"description": "Fields to copy from another product of the merge, e.g. {\"price\": \"<merged id>\"}",
+				},
+				"dry_run": dryRunProperty,
+			},
+			"required": []string{"keep_id", "merge_ids"},
+		},
+		Schema: map[string]interface{}{
+			"keep_id":   "string (required)",
+			"merge_ids": "array of strings (required)",
+			"fields":    "object (optional)",
+			"take_from": "object of field -> product id (optional)",
+			"dry_run":   "boolean (optional)",
+		},
+		SampleRequest: map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      "<id>",
+			"method":  "tools/call",
+			"params": map[string]interface{}{
+				"name": "merge_products",
+				"arguments": map[string]interface{}{
+					"keep_id":   "12345",
+					"merge_ids": []string{"12346", "12377"},
+					"fields":    map[string]interface{}{"name": "iPhone 17"},
+					"take_from": map[string]interface{}{"price": "12346"},
+				},
+			},
+		},
+	},
+	{
+		Name:        "validate_catalog",
+		Description: "Use this tool to check the data quality of the catalog, e.g. 'Are there products with negative prices?' or 'Which categories are misspelled?'. Scans every product against the configured rules (required fields, allowed categories and segments, price bounds, name pattern) and reports products whose category or segment differs only in case from the common spelling. Returns every violation grouped by rule with the product id, field, value and message. Create and update tools refuse writes that break the same rules.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"category": map[string]interface{}{
+					"type":        "string",
+					"description": "Only check products in this category (case-insensitive)",
+				},
+				"segment": map[string]interface{}{
+					"type":        "string",
+					"description": "Only check products in this segment (case-insensitive)",
+				},
+				"limit_per_rule": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of violations listed per rule (default 100); counts are always complete",
+				},
+			},
+		},
+		Schema: map[string]interface{}{
+			"category":       "string (optional)",
+			"segment":        "string (optional)",
+			"limit_per_rule": "integer (optional, default 100)",
+		},
+		SampleRequest: map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      "<id>",
+			"method":  "tools/call",
+			"params": map[string]interface{}{
+				"name":      "validate_catalog",
+				"arguments": map[string]interface{}{},
+			},
+		},
+	},
+	{
+		Name:        "import_products",
+		Description: "Use this tool to onboard a product list such as a supplier file instead of building a large create_multiple_products payload. Accepts CSV (with a header row) or JSON Lines, inline as 'content' or as an import:// resource from resources/list. Columns named name, category, segment and price are used directly; 'mapping' maps other column names, e.g. {\"name\": \"Product Name\", \"price\": \"Unit Price\"}. Prices like \"$1,299.00\" or \"1.299,00 EUR\" are converted to numbers. Every row is validated and errors are reported with their line number; by default any invalid row refuses the whole import, 'skip_invalid' imports the valid rows. With 'upsert_by_name', rows whose name matches an existing product update it instead of creating a duplicate. Rows are sent to the product service in chunks; call with dry_run first to review the rows. If a chunk fails, the chunks before it stay written: retrying the import creates those rows again unless 'upsert_by_name' is set.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"content": map[string]interface{}{
+					"type":        "string",
+					"description": "The CSV or JSON Lines text. Use either this or resource_uri",
+				},
+				"resource_uri": map[string]interface{}{
+					"type":        "string",
+					"description": "An import:// resource listed by resources/list",
+				},
+				"format": map[string]interface{}{
+					"type":        "string",
+					"description": "'csv' or 'jsonl'; detected from the file extension or the content if omitted",
+					"enum":        []string{"csv", "jsonl"},
+				},
+				"delimiter": map[string]interface{}{
+					"type":        "string",
+					"description": "CSV field delimiter; detected from the header (',', ';' or tab) if omitted",
+				},
+				"mapping": map[string]interface{}{
+					"type":        "object",
+					"description": "Product field to source column name, for columns not named like the field",
+				},
+				"defaults": map[string]interface{}{
+					"type":        "object",
+					"description": "Field values for created products whose row has no value, e.g. {\"category\": \"Furniture\"}",
+				},
+				"upsert_by_name": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Update the existing product with the same name (case-insensitive) instead of creating another one",
+				},
+				"skip_invalid": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Import the valid rows and report the invalid ones instead of refusing the whole import",
+				},
+				"chunk_size": map[string]interface{}{
+					"type":        "integer",
+					"description": "Products per request to the product service (default from import.chunk_size, max 500)",
+				},
+				"dry_run": dryRunProperty,
+			},
+		},
+		Schema: map[string]interface{}{
+			"content":        "string (content or resource_uri)",
+			"resource_uri":   "string (content or resource_uri)",
+			"format":         "string (optional, 'csv' or 'jsonl')",
+			"delimiter":      "string (optional)",
+			"mapping":        "object (optional)",
+			"defaults":       "object (optional)",
+			"upsert_by_name": "boolean (optional)",
+			"skip_invalid":   "boolean (optional)",
+			"chunk_size":     "integer (optional)",
+			"dry_run":        "boolean (optional)",
+		},
+		SampleRequest: map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      "<id>",
+			"method":  "tools/call",
+			"params": map[string]interface{}{
+				"name": "import_products",
+				"arguments": map[string]interface{}{
+					"content":        "Product Name,Category,Unit Price\nOffice Chair,Furniture,\"$149.00\"\n",
+					"mapping":        map[string]interface{}{"name": "Product Name", "price": "Unit Price"},
+					"upsert_by_name": true,
+				},
+			},
+		},
+	},
 }
@@ -0,0 +1,126 @@
+// Package main - tracing.go
+//
+// This file sets up OpenTelemetry tracing for the MCP server.
+//
+// Key Responsibilities:
+//   - Create the tracer provider and the configured span exporter
+//   - Install the W3C Trace Context (traceparent/tracestate) and Baggage propagators
+//   - Provide helpers used by handlers.go and backend.go to start spans
+//
+// Spans:
+//   - "mcp <method>"          one per JSON-RPC request (server span, parent taken from inbound traceparent)
+//   - "tools/call <tool>"     one per tool execution
+//   - "<METHOD> <route>"      one per outbound product-service request attempt (client span,
+//     traceparent injected into the outbound request)
+//
+// Exporters (tracing.exporter in the config file, or OTEL_TRACES_EXPORTER):
+//   - none:   tracing disabled (default); propagation still forwards inbound traceparent headers
+//   - otlp:   OTLP over HTTP, configured with the standard OTEL_EXPORTER_OTLP_* variables
+//   - stdout: pretty-printed spans on stdout, for local debugging
+//   - file:   JSON spans appended to tracing.file (default: traces.json)
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "ravi-mcp-server"
+
+// TracingConfig selects the span exporter
+type TracingConfig struct {
+	Exporter string `yaml:"exporter"`
+	File     string `yaml:"file"`
+}
+
+var tracer = otel.Tracer(tracerName)
+
+// initTracing installs the global tracer provider and propagators.
+// The returned function flushes and stops the exporter.
+func initTracing(cfg TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var closer io.Closer
+	var err error
+	switch cfg.Exporter {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(context.Background())
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "file":
+		path := cfg.File
+		if path == "" {
+			path = "traces.json"
+		}
+		var f *os.File
+		f, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %v", err)
+		}
+		closer = f
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q (expected none, otlp, stdout or file)", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %v", cfg.Exporter, err)
+	}
+
+	resource, err := sdkresource.Merge(sdkresource.Default(), sdkresource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName("ravi-mcp-server"),
+		semconv.ServiceVersion(serverVersion),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %v", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			closer.Close()
+		}
+		return err
+	}, nil
+}
+
+// startToolSpan starts the span wrapping a single tool execution
+func startToolSpan(ctx context.Context, toolName string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "tools/call "+toolLabel(toolName),
+		trace.WithAttributes(attribute.String("mcp.tool.name", toolName)),
+	)
+}
+
+// endSpanWithError marks the span as failed when err is non-nil and ends it
+func endSpanWithError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+// withTracerProvider restores the global tracer provider after a test
+func withTracerProvider(t *testing.T) {
+	t.Helper()
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+}
+
+func TestInitTracingFileExporter(t *testing.T) {
+	withTracerProvider(t)
+	path := filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err := initTracing(TracingConfig{Exporter: "file", File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := otel.GetTracerProvider().Tracer(tracerName).Start(context.Background(), "tools/call get_product")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "tools/call get_product") {
+		t.Errorf("Expected the span in the trace file, got %q", data)
+	}
+}
+
+func TestInitTracingExporters(t *testing.T) {
+	withTracerProvider(t)
+	// the OTLP exporter connects lazily; nothing is listening here
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://127.0.0.1:1")
+	for _, exporter := range []string{"none", "otlp", "stdout"} {
+		shutdown, err := initTracing(TracingConfig{Exporter: exporter})
+		if err != nil {
+			t.Errorf("Expected exporter %s to be created, got %v", exporter, err)
+			continue
+		}
+		if err := shutdown(context.Background()); err != nil {
+			t.Errorf("Expected exporter %s to shut down cleanly, got %v", exporter, err)
+		}
+	}
+
+	if _, err := initTracing(TracingConfig{Exporter: "jaeger"}); err == nil || !strings.Contains(err.Error(), "unknown trace exporter") {
+		t.Errorf("Expected an unknown exporter to be refused, got %v", err)
+	}
+	if _, err := initTracing(TracingConfig{Exporter: "file", File: filepath.Join(t.TempDir(), "missing", "traces.json")}); err == nil {
+		t.Error("Expected an unwritable trace file to be refused")
+	}
+	_, _, err := loadConfig([]string{"--trace-exporter", "jaeger"}, envFrom(nil))
+	if err == nil || !strings.Contains(err.Error(), "tracing.exporter") {
+		t.Errorf("Expected config validation to report the exporter, got %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+// Package main - undo.go
+//
+// This file implements the undo_change tool, which reverts a mutation recorded in the
+// audit log (see audit.go) by its change id.
+//
+// Key Responsibilities:
+//   - Look up the audit record of a change and derive the inverse of each product change
+//   - Refuse with a conflict error if a product changed since the recorded change
+//   - Apply the inverse operations through the product service
+//
+// Inverse operations:
+//   - create -> delete the created product
+//   - update -> PUT the recorded before state back
+//   - delete -> recreate the product from its recorded before state (POST /products)
+//
+// Conflicts:
+//
+//	Every affected product must still be in the state recorded after the change (for a
+//	deleted product: still absent). Otherwise nothing is reverted and the error lists, per
+//	product, the expected and the current state. A change can be undone once; the undo is
+//	itself recorded in the audit log and can be undone in turn.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// productConflict is a product whose current state differs from the expected one
+type productConflict struct {
+	ProductID string                 `json:"product_id"`
+	Expected  map[string]interface{} `json:"expected"`
+	Current   map[string]interface{} `json:"current"` // null if the product does not exist
+	Fields    []string               `json:"fields,omitempty"`
+}
+
+// conflictError is returned when products changed since the state a tool call relies on
+type conflictError struct {
+	Message   string            `json:"message"`
+	Conflicts []productConflict `json:"conflicts"`
+}
+
+func (e *conflictError) Error() string {
+	details, _ := json.Marshal(e.Conflicts)
+	return fmt.Sprintf("conflict: %s %s", e.Message, details)
+}
+
+// undoChange reverts a recorded change
+func undoChange(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	rec, effective, err := undoRecord(params)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(effective))
+	for _, c := range effective {
+		ids = append(ids, c.ProductID)
+	}
+	// the conflict check and the writes must not interleave with other writes
+	defer lockProducts(ids)()
+	auditLockedProducts(ctx, ids)
+	changes, err := planUndoSteps(ctx, effective)
+	if err != nil {
+		return nil, err
+	}
+	conflict := &conflictError{Message: fmt.Sprintf("products changed since %s; nothing was reverted", rec.ID)}
+	for _, c := range changes {
+		if c.Error != "" {
+			conflict.Conflicts = append(conflict.Conflicts, productConflict{ProductID: fmt.Sprint(c.ID), Expected: c.expected, Current: c.Before})
+		}
+	}
+	if len(conflict.Conflicts) > 0 {
+		return nil, conflict
+	}
+
+	reverted := make([]map[string]interface{}, 0, len(changes))
+	created := []map[string]interface{}{}
+	for i, c := range changes {
+		var product map[string]interface{}
+		switch c.Action {
+		case "delete":
+			_, err = sendWrite(ctx, http.MethodDelete, "/products/{id}", productServiceBaseURL+"/products/"+fmt.Sprint(c.ID), nil)
+		case "update":
+			product, err = sendWrite(ctx, http.MethodPut, "/products/{id}", productServiceBaseURL+"/products/"+fmt.Sprint(c.ID), c.After)
+		case "create":
+			product, err = sendWrite(ctx, http.MethodPost, "/products", productServiceBaseURL+"/products", c.After)
+			if err == nil {
+				created = append(created, product)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("undo of %s stopped after %d of %d steps: %w", rec.ID, i, len(changes), err)
+		}
+		step := map[string]interface{}{"action": c.Action, "product_id": c.ID}
+		if product != nil {
+			step["product"] = product
+		}
+		reverted = append(reverted, step)
+	}
+	return map[string]interface{}{
+		"change_id": rec.ID,
+		"tool":      rec.Tool,
+		"reverted":  reverted,
+		"created":   created,
+	}, nil
+}
+
+// undoStep is a plannedChange with the state the product is expected to be in
+type undoStep struct {
+	plannedChange
+	expected map[string]interface{}
+}
+
+// planUndo resolves the inverse operations of a recorded change; products that changed
+// since carry an error
+func planUndo(ctx context.Context, params map[string]interface{}) (*auditRecord, []undoStep, error) {
+	rec, effective, err := undoRecord(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	steps, err := planUndoSteps(ctx, effective)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rec, steps, nil
+}
+
+// undoRecord looks up the recorded change to undo and returns the product changes it
+// actually made
+func undoRecord(params map[string]interface{}) (*auditRecord, []auditChange, error) {
+	changeID, ok := params["change_id"].(string)
+	if !ok || changeID == "" {
+		return nil, nil, fmt.Errorf("missing or invalid 'change_id' argument")
+	}
+	sink := auditLog
+	if sink == nil {
+		return nil, nil, errAuditDisabled
+	}
+	recs, err := sink.records()
+	if err != nil {
+		return nil, nil, err
+	}
+	var rec *auditRecord
+	for _, r := range recs {
+		if r.ID == changeID {
+			rec = r
+		}
+		if r.Tool == "undo_change" && r.Outcome == "ok" && r.Arguments["change_id"] == changeID {
+			return nil, nil, fmt.Errorf("change %s was already undone by %s", changeID, r.ID)
+		}
+	}
+	if rec == nil {
+		return nil, nil, fmt.Errorf("no recorded change with id %q (see get_audit_log)", changeID)
+	}
+
+	var effective []auditChange
+	for _, c := range rec.Changes {
+		// failed writes leave the product as it was
+		if c.ProductID == "" || reflect.DeepEqual(c.Before, c.After) {
+			continue
+		}
+		effective = append(effective, c)
+	}
+	if len(effective) == 0 {
+		return nil, nil, fmt.Errorf("change %s (%s) did not modify any product; nothing to undo", rec.ID, rec.Tool)
+	}
+	return rec, effective, nil
+}
+
+// planUndoSteps derives the inverse of each product change from the current state of the
+// products
+func planUndoSteps(ctx context.Context, effective []auditChange) ([]undoStep, error) {
+	ids := make([]string, 0, len(effective))
+	for _, c := range effective {
+		ids = append(ids, c.ProductID)
+	}
+	current, err := snapshotProducts(withFreshReads(ctx), ids)
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]undoStep, 0, len(effective))
+	for _, c := range effective {
+		step := undoStep{plannedChange: plannedChange{ID: c.ProductID, Before: current[c.ProductID]}, expected: c.After}
+		switch {
+		case c.Before == nil:
+			step.Action = "delete"
+		case c.After == nil:
+			step.Action = "create"
+			step.After = c.Before
+		default:
+			step.Action = "update"
+			step.After = c.Before
+			step.Fields = map[string]fieldChange{}
+			for k, v := range c.Before {
+				if !reflect.DeepEqual(c.After[k], v) {
+					step.Fields[k] = fieldChange{From: c.After[k], To: v}
+				}
+			}
+		}
+		if !reflect.DeepEqual(current[c.ProductID], c.After) {
+			step.Error = "product changed since the recorded change"
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// sendWrite sends a write request to the product service, records it in the audit log and
+// returns the decoded response; 4xx and 5xx answers are errors
+func sendWrite(ctx context.Context, method, route, url string, body map[string]interface{}) (map[string]interface{}, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("failed to marshal params: %v", err)
+		}
+	}
+	resp, err := callBackend(ctx, method, route, url, data, nil)
+	recordBackendWrite(ctx, method, route, resp, err)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, &backendStatusError{StatusCode: resp.StatusCode}
+	}
+	var result map[string]interface{}
+	if strings.TrimSpace(string(resp.Body)) != "" {
+		json.Unmarshal(resp.Body, &result)
+	}
+	return result, nil
+}
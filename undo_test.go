@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// lastChangeID returns the id of the most recent audit record
+func lastChangeID(t *testing.T) string {
+	t.Helper()
+	recs, err := auditLog.records()
+	if err != nil || len(recs) == 0 {
+		t.Fatalf("Expected an audit record, got %v (%v)", recs, err)
+	}
+	return recs[len(recs)-1].ID
+}
+
+func TestUndoChangeRevertsUpdateAndDelete(t *testing.T) {
+	fake := newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Laptops", 999),
+		testProduct("2", "Office Chair", "Furniture", "Budget", 149),
+	)
+	swapGlobal[auditSink](t, &auditLog, newMemoryAuditSink(100))
+	ctx := context.Background()
+
+	if _, err := executeToolCall(ctx, "update_product", map[string]interface{}{"id": "2", "price": 99.0, "name": "Chair"}); err != nil {
+		t.Fatal(err)
+	}
+	updateID := lastChangeID(t)
+	if _, err := executeToolCall(ctx, "delete_product", map[string]interface{}{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	deleteID := lastChangeID(t)
+
+	preview, err := executeToolCall(ctx, "undo_change", map[string]interface{}{"change_id": updateID, "dry_run": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan := preview.(mutationPlan); !plan.Valid || plan.Changes[0].Fields["price"].To != 149.0 {
+		t.Errorf("Unexpected undo preview %+v", plan)
+	}
+
+	if _, err := executeToolCall(ctx, "undo_change", map[string]interface{}{"change_id": updateID}); err != nil {
+		t.Fatal(err)
+	}
+	if p := fake.get("2"); p["price"] != 149.0 || p["name"] != "Office Chair" {
+		t.Errorf("Expected the update to be reverted, got %v", p)
+	}
+
+	result, err := executeToolCall(ctx, "undo_change", map[string]interface{}{"change_id": deleteID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created := result.(map[string]interface{})["created"].([]map[string]interface{}); len(created) != 1 || created[0]["name"] != "Laptop5" {
+		t.Errorf("Expected Laptop5 to be recreated, got %v", result)
+	}
+
+	if _, err := executeToolCall(ctx, "undo_change", map[string]interface{}{"change_id": deleteID}); err == nil {
+		t.Error("Expected a second undo of the same change to be refused")
+	}
+
+	// the undo itself is recorded and its recreated product is in the audit log
+	undoRec, _ := executeToolCall(ctx, "get_audit_log", map[string]interface{}{"tool": "undo_change", "outcome": "ok", "limit": 1.0})
+	rec := undoRec.(map[string]interface{})["entries"].([]*auditRecord)[0]
+	if len(rec.Changes) != 1 || rec.Changes[0].Action != "create" || rec.Changes[0].After["name"] != "Laptop5" {
+		t.Errorf("Expected the undo to be audited as a create, got %+v", rec.Changes)
+	}
+}
+
+func TestUndoChangeRefusesOnConflict(t *testing.T) {
+	fake := newFakeProductService(t, testProduct("1", "Laptop5", "Electronics", "Laptops", 999))
+	swapGlobal[auditSink](t, &auditLog, newMemoryAuditSink(100))
+	ctx := context.Background()
+
+	executeToolCall(ctx, "update_product", map[string]interface{}{"id": "1", "price": 899.0})
+	changeID := lastChangeID(t)
+	executeToolCall(ctx, "update_product", map[string]interface{}{"id": "1", "price": 949.0})
+
+	_, err := executeToolCall(ctx, "undo_change", map[string]interface{}{"change_id": changeID})
+	var conflict *conflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected a conflict error, got %v", err)
+	}
+	if len(conflict.Conflicts) != 1 || conflict.Conflicts[0].Current["price"] != 949.0 || conflict.Conflicts[0].Expected["price"] != 899.0 {
+		t.Errorf("Unexpected conflicts %+v", conflict.Conflicts)
+	}
+	if toolErrorKind(err) != "conflict" {
+		t.Errorf("Expected error kind 'conflict', got %s", toolErrorKind(err))
+	}
+	if p := fake.get("1"); p["price"] != 949.0 {
+		t.Errorf("Expected nothing to be reverted, got %v", p)
+	}
+
+	if _, err := executeToolCall(ctx, "undo_change", map[string]interface{}{"change_id": "chg_unknown"}); err == nil {
+		t.Error("Expected an error for an unknown change id")
+	}
+}
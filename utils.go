@@ -58,6 +58,9 @@ func sendJSONRPCError(w http.ResponseWriter, id interface{}, code int, message s
 			Data:    data,
 		},
 	}
+	if rw, ok := w.(*rpcResponseWriter); ok {
+		rw.errorCode = code
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
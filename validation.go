@@ -0,0 +1,348 @@
+// Package main - validation.go
+//
+// This file implements the configurable catalog data-quality rules and the
+// validate_catalog tool.
+//
+// Key Responsibilities:
+//   - Build the rule set from the 'validation' config section
+//   - Check products and product updates against the rules
+//   - Refuse create and update tool calls that would break a rule (validation.enforce)
+//   - Scan the whole catalog and report every violation grouped by rule
+//
+// Rules:
+//   - required_field:      validation.required_fields must be present and not blank
+//     (default name, category, price); updates may not blank them
+//   - category_vocabulary: category must be one of validation.categories (if set)
+//   - segment_vocabulary:  segment must be one of validation.segments (if set)
+//   - min_price/max_price: price bounds (default min_price 0: no negative prices)
+//   - name_pattern:        name must match the validation.name_pattern regular expression
+//   - category_case/segment_case (validate_catalog only, without a vocabulary): values that
+//     differ only in case from the most common spelling, e.g. "electronics" and "Electronics"
+//
+// Enforcement:
+//
+//	create_product, create_multiple_products, update_product, update_products (and so
+//	adjust_prices) and merge_products check the values they write before calling the
+//	product service. A batch with any violation is refused as a whole. Updates are only
+//	checked for the fields they set, so existing products that break a rule can still be
+//	fixed one field at a time.
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// validationFields are the product fields the rules know about
+var validationFields = []string{"name", "category", "segment", "price"}
+
+// ruleOrder is the order of the rule groups in a validate_catalog report
+var ruleOrder = []string{
+	"required_field", "category_vocabulary", "segment_vocabulary", "min_price", "max_price",
+	"name_pattern", "category_case", "segment_case",
+}
+
+const defaultViolationsPerRule = 100
+
+// ruleViolation is a product value that breaks a validation rule
+type ruleViolation struct {
+	Rule      string      `json:"rule"`
+	ProductID interface{} `json:"product_id,omitempty"`
+	Name      interface{} `json:"name,omitempty"`
+	Field     string      `json:"field"`
+	Value     interface{} `json:"value,omitempty"`
+	Message   string      `json:"message"`
+}
+
+// validationError is returned when a write would break the catalog rules
+type validationError struct {
+	Message    string          `json:"message"`
+	Violations []ruleViolation `json:"violations"`
+}
+
+func (e *validationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		switch {
+		case v.ProductID != nil:
+			messages = append(messages, fmt.Sprintf("product %v: %s", v.ProductID, v.Message))
+		case v.Name != nil:
+			messages = append(messages, fmt.Sprintf("product %q: %s", fmt.Sprint(v.Name), v.Message))
+		default:
+			messages = append(messages, v.Message)
+		}
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// catalogRules is the compiled rule set
+type catalogRules struct {
+	enforce     bool
+	required    []string
+	categories  map[string]string // lowercase -> allowed spelling
+	segments    map[string]string
+	minPrice    *float64
+	maxPrice    *float64
+	namePattern *regexp.Regexp
+}
+
+var productRules = mustCatalogRules(defaultConfig().Validation)
+
+// newCatalogRules compiles the validation settings; config.validate reports the same
+// problems at startup
+func newCatalogRules(cfg ValidationConfig) (*catalogRules, error) {
+	rules := &catalogRules{
+		enforce:    cfg.Enforce,
+		required:   cfg.RequiredFields,
+		categories: vocabulary(cfg.Categories),
+		segments:   vocabulary(cfg.Segments),
+		minPrice:   cfg.MinPrice,
+		maxPrice:   cfg.MaxPrice,
+	}
+	if cfg.NamePattern != "" {
+		re, err := regexp.Compile(cfg.NamePattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid name_pattern: %v", err)
+		}
+		rules.namePattern = re
+	}
+	return rules, nil
+}
+
+func mustCatalogRules(cfg ValidationConfig) *catalogRules {
+	rules, err := newCatalogRules(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// configureValidation replaces the rule set
+func configureValidation(cfg ValidationConfig) error {
+	rules, err := newCatalogRules(cfg)
+	if err != nil {
+		return err
+	}
+	productRules = rules
+	return nil
+}
+
+func vocabulary(values []string) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+	allowed := make(map[string]string, len(values))
+	for _, v := range values {
+		allowed[strings.ToLower(strings.TrimSpace(v))] = v
+	}
+	return allowed
+}
+
+// check returns the violations of a product; complete is false for updates, which are only
+// checked for the fields they set
+func (r *catalogRules) check(product map[string]interface{}, complete bool) []ruleViolation {
+	var violations []ruleViolation
+	add := func(rule, field, format string, args ...interface{}) {
+		violations = append(violations, ruleViolation{
+			Rule:      rule,
+			ProductID: product["id"],
+			Name:      product["name"],
+			Field:     field,
+			Value:     product[field],
+			Message:   fmt.Sprintf(format, args...),
+		})
+	}
+
+	for _, field := range r.required {
+		v, present := product[field]
+		switch {
+		case !present && complete:
+			add("required_field", field, "'%s' is required", field)
+		case present && isBlank(v):
+			add("required_field", field, "'%s' must not be blank", field)
+		}
+	}
+	if category, ok := product["category"].(string); ok && category != "" {
+		if msg := checkVocabulary(r.categories, "category", category); msg != "" {
+			add("category_vocabulary", "category", "%s", msg)
+		}
+	}
+	if segment, ok := product["segment"].(string); ok && segment != "" {
+		if msg := checkVocabulary(r.segments, "segment", segment); msg != "" {
+			add("segment_vocabulary", "segment", "%s", msg)
+		}
+	}
+	if price, ok := product["price"].(float64); ok {
+		if r.minPrice != nil && price < *r.minPrice {
+			add("min_price", "price", "price %v is below the minimum of %v", price, *r.minPrice)
+		}
+		if r.maxPrice != nil && price > *r.maxPrice {
+			add("max_price", "price", "price %v is above the maximum of %v", price, *r.maxPrice)
+		}
+	}
+	if name, ok := product["name"].(string); ok && name != "" && r.namePattern != nil && !r.namePattern.MatchString(name) {
+		add("name_pattern", "name", "name %q does not match the pattern %s", name, r.namePattern)
+	}
+	return violations
+}
+
+func isBlank(v interface{}) bool {
+	s, isString := v.(string)
+	return v == nil || (isString && strings.TrimSpace(s) == "")
+}
+
+// checkVocabulary returns why value is not allowed, or "" if it is
+func checkVocabulary(allowed map[string]string, field, value string) string {
+	if allowed == nil {
+		return ""
+	}
+	spelling, known := allowed[strings.ToLower(strings.TrimSpace(value))]
+	switch {
+	case !known:
+		return fmt.Sprintf("%s %q is not allowed (see list_taxonomy and validation.%s)", field, value, map[string]string{"category": "categories", "segment": "segments"}[field])
+	case spelling != value:
+		return fmt.Sprintf("%s %q must be spelled %q", field, value, spelling)
+	}
+	return ""
+}
+
+// checkWrite refuses products (complete) or product updates that break the rules when
+// validation is enforced
+func (r *catalogRules) checkWrite(items []map[string]interface{}, complete bool) error {
+	if !r.enforce {
+		return nil
+	}
+	var violations []ruleViolation
+	for _, item := range items {
+		violations = append(violations, r.check(item, complete)...)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return &validationError{Message: "the catalog rules would be broken; nothing was written", Violations: violations}
+}
+
+// describe explains a rule for the validate_catalog report
+func (r *catalogRules) describe(rule string) string {
+	switch rule {
+	case "required_field":
+		return fmt.Sprintf("fields %s are present and not blank", strings.Join(r.required, ", "))
+	case "category_vocabulary":
+		return "category is one of validation.categories"
+	case "segment_vocabulary":
+		return "segment is one of validation.segments"
+	case "min_price":
+		return fmt.Sprintf("price >= %v", *r.minPrice)
+	case "max_price":
+		return fmt.Sprintf("price <= %v", *r.maxPrice)
+	case "name_pattern":
+		return fmt.Sprintf("name matches %s", r.namePattern)
+	case "category_case":
+		return "categories differing only in case use the most common spelling"
+	case "segment_case":
+		return "segments differing only in case use the most common spelling"
+	}
+	return rule
+}
+
+// ruleGroup is the violations of one rule in a validate_catalog report
+type ruleGroup struct {
+	Rule        string          `json:"rule"`
+	Description string          `json:"description"`
+	Count       int             `json:"count"`
+	Violations  []ruleViolation `json:"violations"`
+}
+
+// validateCatalog scans the catalog and reports every violation grouped by rule
+func validateCatalog(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	perRule := defaultViolationsPerRule
+	if v, present := params["limit_per_rule"]; present {
+		n, ok := v.(float64)
+		if !ok || n < 1 || n != float64(int(n)) {
+			return nil, fmt.Errorf("invalid 'limit_per_rule' argument: must be a positive integer")
+		}
+		perRule = int(n)
+	}
+
+	products, err := fetchProducts(ctx, "/products", productServiceBaseURL+"/products")
+	if err != nil {
+		return nil, err
+	}
+	products = filterProducts(products, params)
+
+	rules := productRules
+	var violations []ruleViolation
+	invalid := map[string]bool{}
+	for _, p := range products {
+		for _, v := range rules.check(p, true) {
+			violations = append(violations, v)
+			invalid[fmt.Sprint(p["id"])] = true
+		}
+	}
+	for _, field := range []string{"category", "segment"} {
+		if (field == "category" && rules.categories != nil) || (field == "segment" && rules.segments != nil) {
+			continue
+		}
+		for _, v := range caseViolations(products, field) {
+			violations = append(violations, v)
+			invalid[fmt.Sprint(v.ProductID)] = true
+		}
+	}
+
+	byRule := map[string]*ruleGroup{}
+	for _, v := range violations {
+		group := byRule[v.Rule]
+		if group == nil {
+			group = &ruleGroup{Rule: v.Rule, Description: rules.describe(v.Rule)}
+			byRule[v.Rule] = group
+		}
+		group.Count++
+		if len(group.Violations) < perRule {
+			group.Violations = append(group.Violations, v)
+		}
+	}
+	groups := []ruleGroup{}
+	for _, rule := range ruleOrder {
+		if group := byRule[rule]; group != nil {
+			groups = append(groups, *group)
+		}
+	}
+	return map[string]interface{}{
+		"products":         len(products),
+		"invalid_products": len(invalid),
+		"violations":       len(violations),
+		"enforced":         rules.enforce,
+		"rules":            groups,
+	}, nil
+}
+
+// caseViolations reports products whose value of field differs only in case from the most
+// common spelling (see distinctValues in taxonomy.go)
+func caseViolations(products []map[string]interface{}, field string) []ruleViolation {
+	entries, _ := distinctValues(products, field)
+	preferred := map[string]string{}
+	for _, entry := range entries {
+		if len(entry.Variants) > 1 {
+			preferred[strings.ToLower(entry.Name)] = entry.Name
+		}
+	}
+	var violations []ruleViolation
+	for _, p := range products {
+		value, _ := p[field].(string)
+		spelling, ok := preferred[strings.ToLower(strings.TrimSpace(value))]
+		if !ok || value == spelling {
+			continue
+		}
+		violations = append(violations, ruleViolation{
+			Rule:      field + "_case",
+			ProductID: p["id"],
+			Name:      p["name"],
+			Field:     field,
+			Value:     value,
+			Message:   fmt.Sprintf("%s %q differs only in case from the more common %q", field, value, spelling),
+		})
+	}
+	return violations
+}
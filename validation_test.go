@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWritesBreakingValidationRulesAreRefused(t *testing.T) {
+	fake := newFakeProductService(t, testProduct("1", "Laptop5", "Electronics", "Laptops", 999))
+	maxPrice := 5000.0
+	cfg := defaultConfig().Validation
+	cfg.Categories = []string{"Electronics", "Furniture"}
+	cfg.MaxPrice = &maxPrice
+	swapGlobal(t, &productRules, mustCatalogRules(cfg))
+	ctx := context.Background()
+
+	_, err := executeToolCall(ctx, "create_product", map[string]interface{}{"name": "Desk", "category": "furniture", "price": -1.0})
+	var invalid *validationError
+	if !errors.As(err, &invalid) || len(invalid.Violations) != 2 {
+		t.Fatalf("Expected the category spelling and the negative price to be reported, got %v", err)
+	}
+	if toolErrorKind(err) != "validation" {
+		t.Errorf("Expected error kind 'validation', got %s", toolErrorKind(err))
+	}
+
+	_, err = executeToolCall(ctx, "update_products", map[string]interface{}{"products": []interface{}{
+		map[string]interface{}{"id": "1", "price": 899.0},
+		map[string]interface{}{"id": "1", "category": "Toys"},
+	}})
+	if !errors.As(err, &invalid) || invalid.Violations[0].Rule != "category_vocabulary" {
+		t.Fatalf("Expected the batch to be refused for the unknown category, got %v", err)
+	}
+	if _, err := executeToolCall(ctx, "adjust_prices", map[string]interface{}{"operation": "percent", "value": 1000.0, "all": true}); !errors.As(err, &invalid) {
+		t.Errorf("Expected adjust_prices above max_price to be refused, got %v", err)
+	}
+	if fake.writeCount() != 0 {
+		t.Fatalf("Expected no write, got %d", fake.writeCount())
+	}
+
+	preview, err := executeToolCall(ctx, "update_product", map[string]interface{}{"id": "1", "price": 9999.0, "dry_run": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan := preview.(mutationPlan); plan.Valid || !strings.Contains(plan.Changes[0].Error, "maximum") {
+		t.Errorf("Expected the preview to report the violation, got %+v", plan)
+	}
+
+	// updates are only checked for the fields they set
+	if _, err := executeToolCall(ctx, "update_product", map[string]interface{}{"id": "1", "price": 899.0}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Enforce = false
+	swapGlobal(t, &productRules, mustCatalogRules(cfg))
+	if _, err := executeToolCall(ctx, "create_product", map[string]interface{}{"name": "Desk", "category": "furniture", "price": 10.0}); err != nil {
+		t.Errorf("Expected writes to pass when validation is not enforced, got %v", err)
+	}
+}
+
+func TestValidateCatalogGroupsViolationsByRule(t *testing.T) {
+	newFakeProductService(t,
+		testProduct("1", "Laptop5", "Electronics", "Laptops", 999),
+		testProduct("2", "Laptop7", "electronics", "Laptops", 1299),
+		testProduct("3", "Mouse", "Electronics", "", -5),
+		map[string]interface{}{"id": "4", "name": " Cable", "price": 9.0},
+	)
+	cfg := defaultConfig().Validation
+	cfg.RequiredFields = []string{"name", "category", "segment", "price"}
+	cfg.NamePattern = `^\S`
+	swapGlobal(t, &productRules, mustCatalogRules(cfg))
+
+	result, err := executeToolCall(context.Background(), "validate_catalog", map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := result.(map[string]interface{})
+	counts := map[string]int{}
+	for _, group := range report["rules"].([]ruleGroup) {
+		counts[group.Rule] = group.Count
+	}
+	want := map[string]int{"required_field": 3, "min_price": 1, "name_pattern": 1, "category_case": 1}
+	for rule, n := range want {
+		if counts[rule] != n {
+			t.Errorf("Expected %d %s violation(s), got %v", n, rule, counts)
+		}
+	}
+	if report["invalid_products"] != 3 {
+		t.Errorf("Expected every product but Laptop5 to break a rule, got %v", report["invalid_products"])
+	}
+}
+
+func TestLoadConfigValidationRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	file := `
+validation:
+  required_fields: [name, colour]
+  categories: [Electronics, electronics]
+  min_price: 10
+  max_price: 5
+  name_pattern: "("
+`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := loadConfig([]string{"--config", path}, envFrom(nil))
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, want := range []string{`unknown field "colour"`, "validation.categories", "validation.min_price", "validation.name_pattern"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s, got: %v", want, err)
+		}
+	}
+}